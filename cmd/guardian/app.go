@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/swissborg/galactica-kyc-guardian/config"
+	"github.com/swissborg/galactica-kyc-guardian/internal/api"
+	"github.com/swissborg/galactica-kyc-guardian/internal/keys"
+	"github.com/swissborg/galactica-kyc-guardian/internal/policy"
+	"github.com/swissborg/galactica-kyc-guardian/internal/ratelimit"
+	"github.com/swissborg/galactica-kyc-guardian/internal/signer"
+	"github.com/swissborg/galactica-kyc-guardian/internal/spend"
+	"github.com/swissborg/galactica-kyc-guardian/internal/sybil"
+	"github.com/swissborg/galactica-kyc-guardian/internal/zkcert"
+)
+
+// configFlags registers the -config and -set flags on fs and returns the loader of the config they select.
+func configFlags(fs *flag.FlagSet) func() (config.Config, error) {
+	path := fs.String("config", os.Getenv("CONFIG_PATH"), "YAML config file")
+	var overrides config.Overrides
+	fs.Var(&overrides, "set", "override a config field, e.g. -set APIConf.Port=8080, can be repeated")
+
+	return func() (config.Config, error) {
+		cfg, err := loadConfig(*path, overrides)
+		if err != nil {
+			return config.Config{}, fmt.Errorf("load config %s: %w", *path, err)
+		}
+		return cfg, nil
+	}
+}
+
+// loadConfig loads the config file with, in increasing precedence, the overrides of the GUARDIAN_
+// environment variables and of the flags.
+func loadConfig(path string, flagOverrides config.Overrides) (config.Config, error) {
+	overrides, ignored := config.EnvOverrides(os.Environ())
+	if len(ignored) > 0 {
+		log.WithField("variables", ignored).Warn("environment variables matching no config field ignored")
+	}
+
+	return config.Load(path, append(overrides, flagOverrides...)...)
+}
+
+// app holds the services built from the config, shared by the commands.
+type app struct {
+	cfg       config.Config
+	secrets   *keys.Secrets
+	issuer    *zkcert.Issuer
+	kyc       *zkcert.Service[zkcertificate.KYCContent]
+	standards []zkcert.JSONService
+	db        *badger.DB
+}
+
+// storeMode is how a command uses the store.
+type storeMode int
+
+const (
+	// storeMemory keeps the store in memory, for the commands that run along the service:
+	// badger allows a single process per data directory
+	storeMemory storeMode = iota
+	// storeConfig opens the store of the config, in memory when it has no path
+	storeConfig
+	// storePersistent opens the store of the config, which must have a path, for the one-off commands
+	// working on the records of the service, which must run while it is stopped
+	storePersistent
+)
+
+// newApp opens the store and connects to the signer, the nodes and the merkle proof services.
+func newApp(ctx context.Context, cfg config.Config, store storeMode) (*app, error) {
+	db, err := openStore(cfg, store)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := newAppWithStore(ctx, cfg, db)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+func newAppWithStore(ctx context.Context, cfg config.Config, db *badger.DB) (*app, error) {
+	secrets := keys.NewSecrets(os.Getenv("SECRETS_DIR"))
+
+	guardianSigner, err := newSigner(ctx, cfg, secrets)
+	if err != nil {
+		return nil, fmt.Errorf("prepare signer: %w", err)
+	}
+
+	pool, err := signer.LoadPool(secrets, cfg.Issuance.ProviderKeys, guardianSigner, cfg.Mode == config.ModeProd)
+	if err != nil {
+		return nil, fmt.Errorf("prepare provider key pool: %w", err)
+	}
+	poolSigners := make([]signer.Signer, len(pool))
+	for i, p := range pool {
+		poolSigners[i] = p
+	}
+
+	var mirror *badger.DB
+	if cfg.MerkleProofService.Local {
+		mirror = db
+	}
+
+	issuer, err := zkcert.NewIssuer(
+		guardianSigner,
+		cfg.RegistryAddress,
+		cfg.Nodes(),
+		cfg.MerkleProofService.URLs(),
+		cfg.MerkleProofService.TLS,
+		mirror,
+		cfg.Issuance,
+		spend.NewLedger(db),
+		poolSigners...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create cert generator: %w", err)
+	}
+
+	standards := make([]zkcert.JSONService, 0, len(cfg.Standards))
+	for _, standard := range cfg.Standards {
+		service, err := zkcert.NewJSONService(issuer, standard.Standard, standard.RegistryAddress)
+		if err != nil {
+			return nil, fmt.Errorf("create %s cert generator: %w", standard.Standard, err)
+		}
+		standards = append(standards, service)
+	}
+
+	return &app{
+		cfg:       cfg,
+		secrets:   secrets,
+		issuer:    issuer,
+		kyc:       zkcert.NewService[zkcertificate.KYCContent](issuer, cfg.RegistryAddress),
+		standards: standards,
+		db:        db,
+	}, nil
+}
+
+// openStore opens the store of the config as required by mode.
+func openStore(cfg config.Config, mode storeMode) (*badger.DB, error) {
+	if mode == storePersistent && cfg.Store.Path == "" {
+		return nil, errors.New("Store.Path is required, the records of the users are lost with an in-memory store")
+	}
+
+	opt := badger.DefaultOptions(cfg.Store.Path)
+	if mode == storeMemory || cfg.Store.Path == "" {
+		opt = badger.DefaultOptions("").WithInMemory(true)
+	}
+
+	db, err := badger.Open(opt)
+	if err != nil {
+		return nil, fmt.Errorf("open store: %w", err)
+	}
+	return db, nil
+}
+
+// handlers returns the certificate handlers of the store, with the policy and duplicate identity
+// detection of the config.
+func (a *app) handlers(ctx context.Context) (*api.Handlers, error) {
+	opts, err := a.handlerOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return api.NewHandlers(a.kyc, a.db, opts...), nil
+}
+
+func (a *app) handlerOptions(ctx context.Context) ([]api.Option, error) {
+	opts := []api.Option{api.WithStandards(a.standards...)}
+
+	if a.cfg.PolicyPath != "" {
+		policies, err := policy.NewEngine(a.cfg.PolicyPath)
+		if err != nil {
+			return nil, fmt.Errorf("load issuance policy: %w", err)
+		}
+		go policies.Watch(ctx, policyReloadInterval)
+		opts = append(opts, api.WithPolicy(policies))
+	}
+
+	if a.cfg.Sybil.Mode != "" {
+		identityIndexSalt, err := a.secrets.Get(keys.SecretIdentityIndexSalt)
+		if err != nil {
+			return nil, fmt.Errorf("read identity index salt: %w", err)
+		}
+
+		detector, err := sybil.NewDetector(a.db, identityIndexSalt, sybil.Mode(a.cfg.Sybil.Mode))
+		if err != nil {
+			return nil, fmt.Errorf("create duplicate identity detector: %w", err)
+		}
+		opts = append(opts, api.WithSybilDetector(detector))
+	}
+
+	if a.cfg.Issuance.MaxQueueDepth > 0 || a.cfg.Issuance.MaxEstimatedWait > 0 {
+		opts = append(opts, api.WithAdmission(api.Admission{
+			MaxQueueDepth:    a.cfg.Issuance.MaxQueueDepth,
+			MaxEstimatedWait: a.cfg.Issuance.MaxEstimatedWait,
+		}))
+	}
+
+	if len(a.cfg.APIConf.RateLimits) > 0 {
+		limits := make([]api.RateLimit, 0, len(a.cfg.APIConf.RateLimits))
+		for _, limit := range a.cfg.APIConf.RateLimits {
+			limits = append(limits, api.RateLimit{
+				Route: limit.Route,
+				Key:   limit.Key,
+				Limit: ratelimit.Limit{Requests: limit.Requests, Period: limit.Period, Burst: limit.Burst},
+			})
+		}
+		opts = append(opts, api.WithRateLimits(ratelimit.NewLimiter(a.db), limits...))
+	}
+
+	return opts, nil
+}
+
+func (a *app) Close() {
+	a.issuer.Close()
+	_ = a.db.Close()
+}
+
+// newSigner connects to the remote signer when configured, otherwise it loads the keys in process.
+// On SIGHUP the keys are reloaded, or the public keys of the remote signer fetched again.
+func newSigner(ctx context.Context, cfg config.Config, secrets *keys.Secrets) (signer.Signer, error) {
+	if cfg.Signer.URL != "" {
+		conn, err := signer.Dial(cfg.Signer.URL, cfg.Signer.TLS)
+		if err != nil {
+			return nil, err
+		}
+		remote, err := signer.NewRemote(ctx, conn)
+		if err != nil {
+			return nil, err
+		}
+		go signer.RefreshOnSignal(ctx, remote, syscall.SIGHUP)
+		return remote, nil
+	}
+
+	production := cfg.Mode == config.ModeProd
+	ring := keys.KeyRing{Active: cfg.SigningKeys.Active, Retired: cfg.SigningKeys.Retired}
+
+	local, err := signer.LoadLocal(secrets, ring, production)
+	if err != nil {
+		return nil, err
+	}
+
+	reloadable := signer.NewReloadable(local)
+	go signer.ReloadOnSignal(ctx, reloadable, secrets, ring, production, syscall.SIGHUP)
+
+	return reloadable, nil
+}
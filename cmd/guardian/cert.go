@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/api"
+	"github.com/swissborg/galactica-kyc-guardian/internal/keys"
+	"github.com/swissborg/galactica-kyc-guardian/internal/zkcert"
+)
+
+// runCert creates and inspects certificates offline, without connecting to the node, the merkle
+// proof service or the store.
+func runCert(args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected \"cert create\" or \"cert inspect\"")
+	}
+
+	switch args[0] {
+	case "create":
+		return runCertCreate(args[1:])
+	case "inspect":
+		return runCertInspect(args[1:])
+	default:
+		return fmt.Errorf("unknown command %q, expected \"cert create\" or \"cert inspect\"", args[0])
+	}
+}
+
+// runCertCreate creates the KYC certificate of a request file, the body of POST /v1/certificates,
+// and prints how it is encoded and signed. Given the same key, salt and expiration date,
+// the same request always gives the same certificate.
+func runCertCreate(args []string) error {
+	fs := flag.NewFlagSet("cert create", flag.ExitOnError)
+	file := fs.String("file", "", "JSON request file, the body of POST /v1/certificates")
+	keyFile := fs.String("key", "", "hex encoded signing key file, by default the signing key of the secrets")
+	salt := fs.Int64("salt", 0, "random salt of the certificate, drawn at random by default")
+	expiration := fs.Int64("expiration", 0, "expiration date as a Unix timestamp, in one year by default")
+	out := fs.String("out", "", "file the certificate JSON is written to")
+	_ = fs.Parse(args)
+
+	if *file == "" {
+		return errors.New("-file is required")
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return fmt.Errorf("read request: %w", err)
+	}
+
+	var req api.GenerateCertRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("decode request %s: %w", *file, err)
+	}
+
+	holderCommitment, inputs, err := req.KYCInputs()
+	if err != nil {
+		return err
+	}
+
+	signingKey, err := loadSigningKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	defer keys.Zero(signingKey[:])
+
+	if *salt == 0 {
+		random, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64)) // [0, MaxInt64)
+		if err != nil {
+			return fmt.Errorf("generate random salt: %w", err)
+		}
+		*salt = random.Int64() + 1 // [1, MaxInt64]
+	}
+
+	expirationDate := time.Now().AddDate(1, 0, 0)
+	if *expiration != 0 {
+		expirationDate = time.Unix(*expiration, 0)
+	}
+
+	cert, err := zkcert.CreateOffline[zkcertificate.KYCContent](
+		holderCommitment,
+		&inputs,
+		signingKey,
+		*salt,
+		expirationDate,
+	)
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		certJSON, err := json.MarshalIndent(cert, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode certificate: %w", err)
+		}
+		if err := os.WriteFile(*out, append(certJSON, '\n'), 0o600); err != nil {
+			return fmt.Errorf("write certificate: %w", err)
+		}
+	}
+
+	return printJSON(zkcert.Inspect(*cert))
+}
+
+// runCertInspect prints how a certificate JSON, as decrypted by its holder, is encoded and signed,
+// and verifies its hashes and signature.
+func runCertInspect(args []string) error {
+	fs := flag.NewFlagSet("cert inspect", flag.ExitOnError)
+	file := fs.String("file", "", "certificate JSON file")
+	var publicKeys publicKeysFlag
+	fs.Var(&publicKeys, "pub", "hex encoded compressed public key of the guardian the certificate must be signed by, can be repeated")
+	_ = fs.Parse(args)
+
+	if *file == "" {
+		return errors.New("-file is required")
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return fmt.Errorf("read certificate: %w", err)
+	}
+
+	cert, err := zkcertificate.DeserializeCertificateJSON(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("decode certificate %s: %w", *file, err)
+	}
+
+	// the details of an invalid certificate are printed too, they help finding what differs
+	if err := printJSON(zkcert.Inspect(cert)); err != nil {
+		return err
+	}
+	return zkcert.Verify(cert, publicKeys...)
+}
+
+// loadSigningKey loads the hex encoded signing key of the file, by default the SIGNING_KEY secret
+// or, without one, the key derived from the provider key.
+func loadSigningKey(path string) (babyjub.PrivateKey, error) {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return babyjub.PrivateKey{}, fmt.Errorf("read signing key: %w", err)
+		}
+		defer keys.Zero(data)
+
+		return keys.LoadSigningKey(bytes.TrimSpace(data), nil)
+	}
+
+	secrets := keys.NewSecrets(os.Getenv("SECRETS_DIR"))
+
+	certSigningKey, err := secrets.Get(keys.SecretSigningKey)
+	if err != nil {
+		return babyjub.PrivateKey{}, fmt.Errorf("read signing key: %w", err)
+	}
+	defer keys.Zero(certSigningKey)
+
+	if len(certSigningKey) > 0 {
+		return keys.LoadSigningKey(certSigningKey, nil)
+	}
+
+	providerKey, err := loadProviderKey(secrets)
+	if err != nil {
+		return babyjub.PrivateKey{}, err
+	}
+	defer keys.ZeroECDSA(providerKey)
+
+	return keys.LoadSigningKey(nil, providerKey)
+}
+
+// publicKeysFlag is a repeatable flag of hex encoded compressed public keys.
+type publicKeysFlag []*babyjub.PublicKey
+
+func (p *publicKeysFlag) String() string {
+	s := make([]string, len(*p))
+	for i, publicKey := range *p {
+		compressed := publicKey.Compress()
+		s[i] = hex.EncodeToString(compressed[:])
+	}
+	return strings.Join(s, ",")
+}
+
+func (p *publicKeysFlag) Set(s string) error {
+	publicKey, err := keys.ParsePublicKey(s)
+	if err != nil {
+		return err
+	}
+
+	*p = append(*p, publicKey)
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// runCheck checks that the guardian can issue certificates: the node answers, the registries are deployed,
+// the provider is whitelisted as a guardian and the merkle proof service knows the trees.
+func runCheck(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	load := configFlags(fs)
+	_ = fs.Parse(args)
+
+	cfg, err := load()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+
+	a, err := newApp(ctx, cfg, storeMemory)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	failed := 0
+	for _, result := range a.issuer.Check(ctx) {
+		if result.Err != nil {
+			failed++
+			fmt.Printf("FAIL %s: %v\n", result.Name, result.Err)
+			continue
+		}
+		fmt.Printf("ok   %s\n", result.Name)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d checks failed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runConfig prints the effective config, with the overrides applied and its secrets redacted.
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return fmt.Errorf("unknown command %q, the only command is \"config print\"", strings.Join(args, " "))
+	}
+
+	fs := flag.NewFlagSet("config print", flag.ExitOnError)
+	load := configFlags(fs)
+	_ = fs.Parse(args[1:])
+
+	cfg, err := load()
+	if err != nil {
+		return err
+	}
+	return cfg.Redacted().WriteYAML(os.Stdout)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/api"
+)
+
+// runIssue issues the certificate of a request file, the body of POST /v1/certificates,
+// waits until it is registered on-chain and prints it.
+func runIssue(args []string) error {
+	fs := flag.NewFlagSet("issue", flag.ExitOnError)
+	load := configFlags(fs)
+	file := fs.String("file", "", "JSON request file, the body of POST /v1/certificates")
+	_ = fs.Parse(args)
+
+	if *file == "" {
+		return errors.New("-file is required")
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return fmt.Errorf("read request: %w", err)
+	}
+
+	var req api.GenerateCertRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("decode request %s: %w", *file, err)
+	}
+
+	cfg, err := load()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+
+	a, err := newApp(ctx, cfg, storeConfig)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	if cfg.Store.Path == "" {
+		fmt.Fprintln(os.Stderr, "warning: no Store.Path configured, the certificate is only printed")
+	}
+
+	handlers, err := a.handlers(ctx)
+	if err != nil {
+		return err
+	}
+
+	resp, err := handlers.Issue(ctx, req)
+	if err != nil {
+		return err
+	}
+	return printJSON(resp)
+}
+
+// printJSON prints v as indented JSON on the standard output.
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
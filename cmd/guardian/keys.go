@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/galactica-corp/guardians-sdk/pkg/keymanagement"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/keys"
+)
+
+// publicKey is the public part of a signing key, as registered in the guardian registry
+type publicKey struct {
+	ProviderAddress string `json:"provider_address,omitempty"`
+	KeyID           string `json:"key_id"`
+	Ax              string `json:"ax"`
+	Ay              string `json:"ay"`
+	Compressed      string `json:"compressed"`
+	// PrivateKey is only printed on request
+	PrivateKey string `json:"private_key,omitempty"`
+}
+
+func newPublicKey(public *babyjub.PublicKey) publicKey {
+	compressed := public.Compress()
+	return publicKey{
+		KeyID:      keys.SigningKeyID(public),
+		Ax:         public.X.String(),
+		Ay:         public.Y.String(),
+		Compressed: hex.EncodeToString(compressed[:]),
+	}
+}
+
+// runKeys derives the signing key of the provider key, or generates a new signing key for a key rotation.
+func runKeys(args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected \"keys derive\" or \"keys generate\"")
+	}
+
+	switch args[0] {
+	case "derive":
+		return runKeysDerive(args[1:])
+	case "generate":
+		return runKeysGenerate(args[1:])
+	default:
+		return fmt.Errorf("unknown command %q, expected \"keys derive\" or \"keys generate\"", args[0])
+	}
+}
+
+// runKeysDerive prints the EdDSA signing key derived from the provider key of the secrets,
+// the key the certificates are signed with when no SIGNING_KEY is configured.
+func runKeysDerive(args []string) error {
+	fs := flag.NewFlagSet("keys derive", flag.ExitOnError)
+	private := fs.Bool("private", false, "also print the hex encoded private signing key")
+	_ = fs.Parse(args)
+
+	providerKey, err := loadProviderKey(keys.NewSecrets(os.Getenv("SECRETS_DIR")))
+	if err != nil {
+		return err
+	}
+	defer keys.ZeroECDSA(providerKey)
+
+	signingKey, err := keymanagement.DeriveEdDSAKeyFromEthereumPrivateKey(providerKey)
+	if err != nil {
+		return fmt.Errorf("derive signing key: %w", err)
+	}
+	defer keys.Zero(signingKey[:])
+
+	out := newPublicKey(signingKey.Public())
+	out.ProviderAddress = crypto.PubkeyToAddress(providerKey.PublicKey).Hex()
+	if *private {
+		out.PrivateKey = hex.EncodeToString(signingKey[:])
+	}
+	return printJSON(out)
+}
+
+// loadProviderKey loads the provider key of the secrets. A raw hex key is accepted whatever the mode,
+// the commands using it do not send transactions.
+func loadProviderKey(secrets *keys.Secrets) (*ecdsa.PrivateKey, error) {
+	src, err := secrets.ProviderKeySource()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Zero()
+
+	return keys.LoadProviderKey(src, false)
+}
+
+// runKeysGenerate generates a new certificate signing key for a key rotation. The private key is written
+// to a new file, only readable by its owner, and the public key to register on-chain is printed.
+func runKeysGenerate(args []string) error {
+	fs := flag.NewFlagSet("keys generate", flag.ExitOnError)
+	out := fs.String("out", "", "file the hex encoded private key is written to, it must not exist")
+	_ = fs.Parse(args)
+
+	if *out == "" {
+		return errors.New("-out is required")
+	}
+
+	signingKey := babyjub.NewRandPrivKey()
+	defer keys.Zero(signingKey[:])
+
+	encoded := []byte(hex.EncodeToString(signingKey[:]))
+	defer keys.Zero(encoded)
+
+	f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("create key file: %w", err)
+	}
+	if _, err := f.Write(encoded); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write key file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close key file: %w", err)
+	}
+
+	return printJSON(newPublicKey(signingKey.Public()))
+}
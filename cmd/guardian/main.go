@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joho/godotenv"
+)
+
+// command is a subcommand of the guardian CLI, run with the arguments following its name
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{name: "serve", usage: "serve the REST and gRPC APIs", run: runServe},
+	{name: "check", usage: "check the node, the registries, the merkle proof service and the guardian whitelist", run: runCheck},
+	{name: "issue", usage: "issue the certificate of a request file and print it: issue -file request.json", run: runIssue},
+	{name: "status", usage: "print the issuance of the certificate of a user: status -user 12345", run: runStatus},
+	{name: "revoke", usage: "revoke the certificate of a user: revoke -user 12345", run: runRevoke},
+	{name: "cert", usage: "cert create -file request.json | cert inspect -file cert.json: create and verify certificates offline", run: runCert},
+	{name: "keys", usage: "keys derive | keys generate -out signing.key", run: runKeys},
+	{name: "config", usage: "config print: print the effective config with its secrets redacted", run: runConfig},
+}
+
+// The guardian CLI serves the API and runs one-off operations with the same config, secrets and store.
+func main() {
+	if err := godotenv.Load(".env"); err != nil {
+		var pathError *fs.PathError
+		if !errors.As(err, &pathError) {
+			fmt.Fprintf(os.Stderr, "parsing .env file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != os.Args[1] {
+			continue
+		}
+
+		if err := cmd.run(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "guardian %s: %v\n", cmd.name, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: guardian <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", cmd.name, cmd.usage)
+	}
+}
+
+// commandContext returns the context of a one-off command, canceled on SIGINT or SIGTERM.
+func commandContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/api"
+)
+
+// runRevoke revokes the certificate of a user and waits until the revocation is registered on-chain.
+func runRevoke(args []string) error {
+	fs := flag.NewFlagSet("revoke", flag.ExitOnError)
+	load := configFlags(fs)
+	userID := fs.String("user", "", "ID of the user")
+	_ = fs.Parse(args)
+
+	if *userID == "" {
+		return errors.New("-user is required")
+	}
+
+	cfg, err := load()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+
+	a, err := newApp(ctx, cfg, storePersistent)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	handlers, err := a.handlers(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := handlers.Revoke(ctx, api.UserID(*userID)); err != nil {
+		return err
+	}
+
+	fmt.Printf("certificate of %s revoked\n", *userID)
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/api"
+)
+
+// policyReloadInterval is how often the issuance policy file is checked for changes
+const policyReloadInterval = 30 * time.Second
+
+// runServe serves the REST and gRPC APIs until SIGINT or SIGTERM.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	load := configFlags(fs)
+	_ = fs.Parse(args)
+
+	log.SetFormatter(&log.JSONFormatter{})
+
+	log.Info("api service init...")
+	defer log.Info("api service stop")
+
+	ctx, cancelCancel := context.WithCancel(context.Background())
+	defer cancelCancel()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+
+	cfg, err := load()
+	if err != nil {
+		return err
+	}
+
+	a, err := newApp(ctx, cfg, storeConfig)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	opts, err := a.handlerOptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	server := api.NewServer(a.kyc, a.db, opts...)
+
+	go func() {
+		if err := server.Start(cfg.APIConf); err != nil && (!errors.Is(err, http.ErrServerClosed)) {
+			log.WithError(err).Fatal("shutting down the server")
+		}
+	}()
+
+	if cfg.APIConf.GRPCPort != "" {
+		go func() {
+			if err := server.StartGRPC(cfg.APIConf); err != nil {
+				log.WithError(err).Fatal("shutting down the gRPC server")
+			}
+		}()
+	}
+
+	<-quit
+	log.Info("Gracefully stopping…")
+	cancelCancel()
+
+	if err := server.Stop(); err != nil {
+		return fmt.Errorf("stop server: %w", err)
+	}
+
+	log.Info("🏁 finished.")
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"flag"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/api"
+)
+
+// status is the issuance of a certificate along with the guardian the registry records for it
+type status struct {
+	api.Issuance
+	// Guardian is the zero address once the certificate is revoked
+	Guardian common.Address `json:"guardian"`
+}
+
+// runStatus prints the issuance of the certificate of a user, as recorded in the store and on-chain.
+func runStatus(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	load := configFlags(fs)
+	userID := fs.String("user", "", "ID of the user")
+	_ = fs.Parse(args)
+
+	if *userID == "" {
+		return errors.New("-user is required")
+	}
+
+	cfg, err := load()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+
+	a, err := newApp(ctx, cfg, storePersistent)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	handlers, err := a.handlers(ctx)
+	if err != nil {
+		return err
+	}
+
+	iss, err := handlers.Issuance(api.UserID(*userID))
+	if err != nil {
+		return err
+	}
+
+	guardian, err := a.issuer.CertificateGuardian(ctx, cfg.RegistryAddress, iss.LeafHash)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(status{Issuance: iss, Guardian: guardian})
+}
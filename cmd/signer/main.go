@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/joho/godotenv"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/keys"
+	"github.com/swissborg/galactica-kyc-guardian/internal/signer"
+)
+
+// defaultListenAddress keeps the signer reachable from the host only unless configured otherwise
+const defaultListenAddress = "127.0.0.1:9091"
+
+// The signer holds the keys of the guardian in an isolated process and signs on behalf of the API,
+// configured with `Signer.URL`. Its gRPC API has no authentication, so it must only be reachable by the API.
+func main() {
+	log.SetFormatter(&log.JSONFormatter{})
+
+	log.Info("signer service init...")
+	defer log.Info("signer service stop")
+
+	if err := godotenv.Load(".env"); err != nil {
+		var pathError *fs.PathError
+		if !errors.As(err, &pathError) {
+			log.Fatalf("parsing .env file: %v", err)
+		}
+	}
+
+	listenAddress := os.Getenv("SIGNER_LISTEN_ADDRESS")
+	if listenAddress == "" {
+		listenAddress = defaultListenAddress
+	}
+
+	secrets := keys.NewSecrets(os.Getenv("SECRETS_DIR"))
+	ring := keys.KeyRing{
+		Active:  os.Getenv("SIGNING_KEYS_ACTIVE"),
+		Retired: splitList(os.Getenv("SIGNING_KEYS_RETIRED")),
+	}
+
+	// the signer is only meant for production deployments, where raw hex keys are refused
+	local, err := signer.LoadLocal(secrets, ring, true)
+	if err != nil {
+		log.Fatalf("prepare keys: %v", err)
+	}
+
+	reloadable := signer.NewReloadable(local)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go signer.ReloadOnSignal(ctx, reloadable, secrets, ring, true, syscall.SIGHUP)
+
+	lis, err := net.Listen("tcp", listenAddress)
+	if err != nil {
+		log.Fatalf("listen on %s: %v", listenAddress, err)
+	}
+
+	server := grpc.NewServer()
+	signer.NewServer(reloadable).Register(server)
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-quit
+		log.Info("Gracefully stopping…")
+		server.GracefulStop()
+	}()
+
+	log.WithField("address", listenAddress).
+		WithField("providerAddress", local.Address()).
+		Info("signer server starting...")
+
+	if err := server.Serve(lis); err != nil {
+		log.WithError(err).Fatal("shutting down the signer server")
+	}
+}
+
+// splitList splits a comma separated list, ignoring empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
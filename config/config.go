@@ -1,20 +1,140 @@
 package config
 
-import "github.com/ethereum/go-ethereum/common"
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+)
+
+// ModeProd is the Mode of production deployments
+const ModeProd = "prod"
 
 type Config struct {
-	APIConf            APIConf            `yaml:"APIConf"`
-	RegistryAddress    common.Address     `yaml:"RegistryAddress"`
-	Node               string             `yaml:"Node"`
+	// Mode is "prod" or "dev", a raw hex provider key is refused in prod unless explicitly allowed
+	Mode            string         `yaml:"Mode" default:"dev"`
+	APIConf         APIConf        `yaml:"APIConf"`
+	RegistryAddress common.Address `yaml:"RegistryAddress"`
+	Node            string         `yaml:"Node"`
+	// FallbackNodes are tried in order when Node is down
+	FallbackNodes      []string           `yaml:"FallbackNodes"`
 	MerkleProofService MerkleProofService `yaml:"MerkleProofService"`
+	Issuance           Issuance           `yaml:"Issuance"`
+	// PolicyPath is the YAML file of the issuance policy, no policy is enforced when empty
+	PolicyPath string `yaml:"PolicyPath"`
+	Store      Store  `yaml:"Store"`
+	Sybil      Sybil  `yaml:"Sybil"`
+	// Standards are the certificate standards issued from JSON inputs in addition to KYC
+	Standards []Standard `yaml:"Standards"`
+	// Signer is the remote signer holding the keys, they are loaded in process when its URL is empty
+	Signer Signer `yaml:"Signer"`
+	// SigningKeys is the ring of certificate signing keys, SIGNING_KEY is used alone when empty
+	SigningKeys SigningKeys `yaml:"SigningKeys"`
+}
+
+// Nodes returns the URLs of the nodes in decreasing priority.
+func (c Config) Nodes() []string {
+	return append([]string{c.Node}, c.FallbackNodes...)
+}
+
+type SigningKeys struct {
+	// Active is the secret of the key new certificates are signed with
+	Active string `yaml:"Active"`
+	// Retired are the hex encoded compressed public keys of the previous keys, kept to audit the certificates they signed
+	Retired []string `yaml:"Retired"`
+}
+
+type Signer struct {
+	URL string `yaml:"URL"`
+	TLS bool   `yaml:"TLS"`
+}
+
+type Standard struct {
+	Standard        zkcertificate.Standard `yaml:"Standard"`
+	RegistryAddress common.Address         `yaml:"RegistryAddress"`
+}
+
+type Issuance struct {
+	// Confirmations is the number of blocks, its own included, the transaction adding a certificate must be
+	// mined in before the certificate is DONE
+	Confirmations int `yaml:"Confirmations" default:"1"`
+	// ReplaceAfter is how long a transaction is pending before it is replaced by the same one paying higher fees
+	ReplaceAfter time.Duration `yaml:"ReplaceAfter" default:"3m"`
+	// StuckAfter is how long a transaction is pending, replacements included, before it is reported stuck
+	StuckAfter time.Duration `yaml:"StuckAfter" default:"15m"`
+	// TipCap is the priority fee per gas in gwei, the suggestion of the node when zero
+	TipCap float64 `yaml:"TipCap"`
+	// BaseFeeMultiplier sets the fee cap per gas to the base fee times the multiplier plus the tip
+	BaseFeeMultiplier int `yaml:"BaseFeeMultiplier" default:"2"`
+	// MaxFeeCap is the ceiling of the fee per gas in gwei, the issuances are deferred while the base fee plus
+	// the tip exceed it. There is no ceiling when zero
+	MaxFeeCap float64 `yaml:"MaxFeeCap"`
+	// ProviderKeys name additional provider keys, whitelisted as guardians too, configured like PRIVATE_KEY. Every
+	// provider key sends its transactions in its own lane, one at a time, the lanes issuing in parallel
+	ProviderKeys []string `yaml:"ProviderKeys"`
+	// MaxQueueDepth is the number of issuances and revocations queued or running above which new certificates
+	// are refused, there is no limit when zero
+	MaxQueueDepth int `yaml:"MaxQueueDepth"`
+	// MaxEstimatedWait is the estimated wait of a new issuance above which new certificates are refused,
+	// there is no limit when zero
+	MaxEstimatedWait time.Duration `yaml:"MaxEstimatedWait"`
+}
+
+type Store struct {
+	// Path is the badger data directory, the store is kept in memory when empty
+	Path string `yaml:"Path"`
+}
+
+type Sybil struct {
+	// Mode is "warn" or "block", duplicate identity detection is disabled when empty
+	Mode string `yaml:"Mode"`
 }
 
 type APIConf struct {
 	Port string `yaml:"Port" default:"8081"`
 	Host string `yaml:"Host" default:"0.0.0.0"`
+	// GRPCPort enables the gRPC API when set
+	GRPCPort string `yaml:"GRPCPort"`
+	// RateLimits limit how often every user or client calls the routes, the routes are not limited when empty.
+	// Their buckets are kept in the store, in memory and per process without Store.Path, which prod mode requires.
+	RateLimits []RateLimit `yaml:"RateLimits"`
+}
+
+// Keys the requests of a RateLimit are counted by
+const (
+	RateLimitKeyUserID = "user_id"
+	RateLimitKeyIP     = "ip"
+)
+
+// RateLimit is a token bucket per user or client of a route, holding Burst requests and refilled with Requests
+// requests every Period.
+type RateLimit struct {
+	// Route is the method and path of a REST route, e.g. "POST /v1/certificates" or
+	// "GET /v1/certificates/:user_id", or the full name of a gRPC method, e.g.
+	// "/guardian.v1.GuardianService/GenerateCertificate"
+	Route string `yaml:"Route"`
+	// Key is what the requests are counted by, "user_id" or "ip" for the client IP
+	Key      string        `yaml:"Key"`
+	Requests int           `yaml:"Requests"`
+	Period   time.Duration `yaml:"Period"`
+	// Burst is the number of requests allowed at once, Requests when zero
+	Burst int `yaml:"Burst"`
 }
 
 type MerkleProofService struct {
 	URL string `yaml:"URL"`
 	TLS bool   `yaml:"TLS"`
+	// FallbackURLs are tried in order when URL is down, with the same TLS setting
+	FallbackURLs []string `yaml:"FallbackURLs"`
+	// Local mirrors the registry trees in the store from the events of the nodes, they are queried when every
+	// service is down, or instead of the services when URL is empty
+	Local bool `yaml:"Local"`
+}
+
+// URLs returns the URLs of the services in decreasing priority.
+func (s MerkleProofService) URLs() []string {
+	if s.URL == "" {
+		return s.FallbackURLs
+	}
+	return append([]string{s.URL}, s.FallbackURLs...)
 }
@@ -0,0 +1,473 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"gopkg.in/yaml.v3"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/keys"
+)
+
+// ErrInvalidConfig is wrapped by the errors of Load listing the problems of a config
+var ErrInvalidConfig = errors.New("invalid config")
+
+// Problem is an invalid field of the config, at its YAML path, e.g. APIConf.Port or Standards[0].RegistryAddress
+type Problem struct {
+	Path    string
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.Path + ": " + p.Message
+}
+
+// ValidationError lists every problem found in a config.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = p.String()
+	}
+	return fmt.Sprintf("%v: %s", ErrInvalidConfig, strings.Join(lines, "; "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidConfig
+}
+
+// Load reads the YAML config file at path, applies the overrides in order, the defaults of the `default` tags
+// and validates it. Without path the config is only made of the overrides and defaults.
+func Load(path string, overrides ...Override) (Config, error) {
+	var data []byte
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return Config{}, fmt.Errorf("read config file: %w", err)
+		}
+	}
+
+	return Parse(data, overrides...)
+}
+
+// Parse decodes the YAML config, applies the overrides in order, the defaults of the `default` tags
+// and validates it. Every problem is reported at once in a *ValidationError.
+func Parse(data []byte, overrides ...Override) (Config, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return Config{}, fmt.Errorf("parse config: %w", err)
+	}
+	if len(root.Content) == 0 {
+		root.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	doc := root.Content[0]
+
+	var v validator
+	v.applyOverrides(doc, overrides)
+
+	// the fields that can't be decoded are reported and dropped, so that the others can still be validated
+	var cfg Config
+	v.inspect(doc, reflect.TypeOf(cfg), "")
+	if err := doc.Decode(&cfg); err != nil {
+		return Config{}, fmt.Errorf("decode config: %w", err)
+	}
+
+	applyDefaults(reflect.ValueOf(&cfg).Elem())
+	v.validate(cfg)
+
+	if len(v.problems) > 0 {
+		return Config{}, &ValidationError{Problems: v.problems}
+	}
+	return cfg, nil
+}
+
+// applyDefaults sets the zero fields of the struct v to the value of their `default` tag.
+func applyDefaults(v reflect.Value) {
+	for i := range v.NumField() {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			applyDefaults(field)
+			continue
+		}
+
+		def, ok := v.Type().Field(i).Tag.Lookup("default")
+		if !ok || !field.IsZero() {
+			continue
+		}
+
+		switch {
+		case field.Type() == durationType:
+			d, err := time.ParseDuration(def)
+			if err != nil {
+				panic(fmt.Sprintf("invalid default %q of %s: %v", def, v.Type().Field(i).Name, err))
+			}
+			field.SetInt(int64(d))
+		case field.Kind() == reflect.String:
+			field.SetString(def)
+		case field.Kind() == reflect.Bool:
+			b, err := strconv.ParseBool(def)
+			if err != nil {
+				panic(fmt.Sprintf("invalid default %q of %s: %v", def, v.Type().Field(i).Name, err))
+			}
+			field.SetBool(b)
+		case field.Kind() == reflect.Int || field.Kind() == reflect.Int64:
+			n, err := strconv.ParseInt(def, 10, 64)
+			if err != nil {
+				panic(fmt.Sprintf("invalid default %q of %s: %v", def, v.Type().Field(i).Name, err))
+			}
+			field.SetInt(n)
+		default:
+			panic(fmt.Sprintf("unsupported default of %s", v.Type().Field(i).Name))
+		}
+	}
+}
+
+type validator struct {
+	problems []Problem
+}
+
+// report records the problem of the field at path, only the first problem of a field is kept
+// since the field is zero once it couldn't be decoded.
+func (v *validator) report(path, format string, args ...any) {
+	for _, p := range v.problems {
+		if p.Path == path {
+			return
+		}
+	}
+	v.problems = append(v.problems, Problem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+var (
+	addressType  = reflect.TypeOf(common.Address{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// inspect reports the keys of the mapping node that are unknown to the struct t or can't be decoded
+// into their field, and removes them from the node. Addresses must be EIP-55 checksummed.
+func (v *validator) inspect(node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind != yaml.MappingNode {
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!null" {
+			v.report(strings.TrimPrefix(path, "."), "expected a mapping")
+			node.Kind, node.Tag, node.Content = yaml.MappingNode, "!!map", nil
+		}
+		return
+	}
+
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := range t.NumField() {
+		fields[yamlName(t.Field(i))] = t.Field(i).Type
+	}
+
+	content := node.Content[:0]
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		fieldPath := strings.TrimPrefix(path+"."+key.Value, ".")
+
+		fieldType, ok := fields[key.Value]
+		if !ok {
+			v.report(fieldPath, "unknown field")
+			continue
+		}
+		if !v.inspectValue(value, fieldType, fieldPath) {
+			continue
+		}
+
+		content = append(content, key, value)
+	}
+	node.Content = content
+}
+
+// inspectValue reports whether the node can be decoded into a value of type t.
+func (v *validator) inspectValue(node *yaml.Node, t reflect.Type, path string) bool {
+	switch {
+	case t.Kind() == reflect.Struct && t != addressType:
+		v.inspect(node, t, path)
+		return true
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct:
+		if node.Kind != yaml.SequenceNode {
+			v.report(path, "expected a list")
+			return false
+		}
+		for i, item := range node.Content {
+			v.inspect(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+		return true
+	}
+
+	if err := node.Decode(reflect.New(t).Interface()); err != nil {
+		v.report(path, "%s", decodeErrorMessage(node, err))
+		return false
+	}
+
+	if t == addressType && node.Tag != "!!null" {
+		if expected := common.HexToAddress(node.Value).Hex(); node.Value != expected {
+			v.report(path, "invalid address checksum, expected %s", expected)
+		}
+	}
+	return true
+}
+
+// decodeErrorMessage strips the line number of the yaml errors, the problems are located by their path.
+func decodeErrorMessage(node *yaml.Node, err error) string {
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		msg := typeErr.Errors[0]
+		if _, rest, ok := strings.Cut(msg, ": "); ok && strings.HasPrefix(msg, "line ") {
+			return rest
+		}
+		return msg
+	}
+	return fmt.Sprintf("invalid value %q: %v", node.Value, err)
+}
+
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func (v *validator) validate(cfg Config) {
+	v.oneOf("Mode", cfg.Mode, "dev", ModeProd)
+
+	v.port("APIConf.Port", cfg.APIConf.Port, true)
+	v.required("APIConf.Host", cfg.APIConf.Host)
+	v.port("APIConf.GRPCPort", cfg.APIConf.GRPCPort, false)
+	if cfg.APIConf.GRPCPort != "" && cfg.APIConf.GRPCPort == cfg.APIConf.Port {
+		v.report("APIConf.GRPCPort", "must differ from APIConf.Port")
+	}
+
+	rateLimits := make(map[[2]string]bool, len(cfg.APIConf.RateLimits))
+	for i, limit := range cfg.APIConf.RateLimits {
+		path := fmt.Sprintf("APIConf.RateLimits[%d]", i)
+		v.rateLimit(path, limit)
+		if rateLimits[[2]string{limit.Route, limit.Key}] {
+			v.report(path, "duplicate %s limit of route %q", limit.Key, limit.Route)
+		}
+		rateLimits[[2]string{limit.Route, limit.Key}] = true
+	}
+	// the buckets of an in-memory store are full again after every restart
+	if len(cfg.APIConf.RateLimits) > 0 && cfg.Mode == ModeProd && cfg.Store.Path == "" {
+		v.report("APIConf.RateLimits", "require Store.Path in %s mode, an in-memory store resets the limits at every restart", ModeProd)
+	}
+
+	v.address("RegistryAddress", cfg.RegistryAddress)
+	v.url("Node", cfg.Node, "http", "https", "ws", "wss")
+	nodes := map[string]bool{cfg.Node: true}
+	for i, node := range cfg.FallbackNodes {
+		path := fmt.Sprintf("FallbackNodes[%d]", i)
+		if nodes[node] {
+			v.report(path, "duplicate node")
+			continue
+		}
+		nodes[node] = true
+		v.url(path, node, "http", "https", "ws", "wss")
+	}
+	v.hostPort("MerkleProofService.URL", cfg.MerkleProofService.URL, !cfg.MerkleProofService.Local)
+	if cfg.MerkleProofService.URL == "" && len(cfg.MerkleProofService.FallbackURLs) > 0 {
+		v.report("MerkleProofService.FallbackURLs", "requires MerkleProofService.URL")
+	}
+	services := map[string]bool{cfg.MerkleProofService.URL: true}
+	for i, service := range cfg.MerkleProofService.FallbackURLs {
+		path := fmt.Sprintf("MerkleProofService.FallbackURLs[%d]", i)
+		if services[service] {
+			v.report(path, "duplicate service")
+			continue
+		}
+		services[service] = true
+		v.hostPort(path, service, true)
+	}
+
+	if cfg.Issuance.Confirmations < 1 {
+		v.report("Issuance.Confirmations", "must be at least 1, got %d", cfg.Issuance.Confirmations)
+	}
+	v.positive("Issuance.ReplaceAfter", cfg.Issuance.ReplaceAfter)
+	v.positive("Issuance.StuckAfter", cfg.Issuance.StuckAfter)
+	if cfg.Issuance.TipCap < 0 {
+		v.report("Issuance.TipCap", "must not be negative, got %v", cfg.Issuance.TipCap)
+	}
+	if cfg.Issuance.BaseFeeMultiplier < 1 {
+		v.report("Issuance.BaseFeeMultiplier", "must be at least 1, got %d", cfg.Issuance.BaseFeeMultiplier)
+	}
+	switch {
+	case cfg.Issuance.MaxFeeCap < 0:
+		v.report("Issuance.MaxFeeCap", "must not be negative, got %v", cfg.Issuance.MaxFeeCap)
+	case cfg.Issuance.MaxFeeCap > 0 && cfg.Issuance.MaxFeeCap < cfg.Issuance.TipCap:
+		v.report("Issuance.MaxFeeCap", "must be at least Issuance.TipCap")
+	}
+
+	if cfg.Issuance.MaxQueueDepth < 0 {
+		v.report("Issuance.MaxQueueDepth", "must not be negative, got %d", cfg.Issuance.MaxQueueDepth)
+	}
+	if cfg.Issuance.MaxEstimatedWait < 0 {
+		v.report("Issuance.MaxEstimatedWait", "must not be negative, got %s", cfg.Issuance.MaxEstimatedWait)
+	}
+
+	providerKeys := make(map[string]bool, len(cfg.Issuance.ProviderKeys))
+	for i, name := range cfg.Issuance.ProviderKeys {
+		path := fmt.Sprintf("Issuance.ProviderKeys[%d]", i)
+		switch {
+		case name == "":
+			v.report(path, "is required")
+		case providerKeys[name]:
+			v.report(path, "duplicate key %s", name)
+		}
+		providerKeys[name] = true
+	}
+
+	v.oneOf("Sybil.Mode", cfg.Sybil.Mode, "", "warn", "block")
+
+	seen := make(map[zkcertificate.Standard]bool, len(cfg.Standards))
+	for i, standard := range cfg.Standards {
+		path := fmt.Sprintf("Standards[%d]", i)
+		switch {
+		case standard.Standard == "":
+			v.report(path+".Standard", "is required")
+		case standard.Standard == zkcertificate.StandardKYC:
+			v.report(path+".Standard", "KYC certificates are issued to RegistryAddress")
+		case seen[standard.Standard]:
+			v.report(path+".Standard", "duplicate standard %s", standard.Standard)
+		}
+		seen[standard.Standard] = true
+		v.address(path+".RegistryAddress", standard.RegistryAddress)
+	}
+
+	v.hostPort("Signer.URL", cfg.Signer.URL, false)
+
+	retired := make(map[babyjub.PublicKeyComp]bool, len(cfg.SigningKeys.Retired))
+	for i, encoded := range cfg.SigningKeys.Retired {
+		path := fmt.Sprintf("SigningKeys.Retired[%d]", i)
+		if encoded == "" {
+			v.report(path, "is required")
+			continue
+		}
+
+		publicKey, err := keys.ParsePublicKey(encoded)
+		if err != nil {
+			v.report(path, "must be a hex encoded compressed public key: %v", err)
+			continue
+		}
+		if retired[publicKey.Compress()] {
+			v.report(path, "duplicate key %s", keys.SigningKeyID(publicKey))
+		}
+		retired[publicKey.Compress()] = true
+	}
+}
+
+func (v *validator) required(path, value string) {
+	if value == "" {
+		v.report(path, "is required")
+	}
+}
+
+func (v *validator) rateLimit(path string, limit RateLimit) {
+	method, route, ok := strings.Cut(limit.Route, " ")
+	switch {
+	case limit.Route == "":
+		v.report(path+".Route", "is required")
+	case strings.HasPrefix(limit.Route, "/"):
+		// gRPC method
+	case !ok || method != strings.ToUpper(method) || !strings.HasPrefix(route, "/"):
+		v.report(path+".Route", "must be a method and a path, e.g. \"POST /v1/certificates\", or a gRPC method, got %q",
+			limit.Route)
+	}
+	v.oneOf(path+".Key", limit.Key, RateLimitKeyUserID, RateLimitKeyIP)
+	if limit.Requests < 1 {
+		v.report(path+".Requests", "must be at least 1, got %d", limit.Requests)
+	}
+	v.positive(path+".Period", limit.Period)
+	if limit.Burst < 0 {
+		v.report(path+".Burst", "must not be negative, got %d", limit.Burst)
+	}
+}
+
+func (v *validator) positive(path string, d time.Duration) {
+	if d <= 0 {
+		v.report(path, "must be positive, got %s", d)
+	}
+}
+
+func (v *validator) oneOf(path, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.report(path, "must be one of %q, got %q", allowed, value)
+}
+
+func (v *validator) port(path, value string, required bool) {
+	if value == "" {
+		if required {
+			v.report(path, "is required")
+		}
+		return
+	}
+
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		v.report(path, "must be a port between 1 and 65535, got %q", value)
+	}
+}
+
+func (v *validator) address(path string, value common.Address) {
+	if value == (common.Address{}) {
+		v.report(path, "is required")
+	}
+}
+
+func (v *validator) url(path, value string, schemes ...string) {
+	if value == "" {
+		v.report(path, "is required")
+		return
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		v.report(path, "invalid URL: %v", err)
+		return
+	}
+	if u.Host == "" {
+		v.report(path, "URL %q has no host", value)
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			return
+		}
+	}
+	v.report(path, "URL scheme must be one of %q, got %q", schemes, u.Scheme)
+}
+
+// hostPort validates a gRPC target, which is a host and a port without scheme.
+func (v *validator) hostPort(path, value string, required bool) {
+	if value == "" {
+		if required {
+			v.report(path, "is required")
+		}
+		return
+	}
+
+	host, port, err := net.SplitHostPort(value)
+	if err != nil {
+		v.report(path, "must be host:port, got %q", value)
+		return
+	}
+	if host == "" {
+		v.report(path, "must be host:port, got %q", value)
+	}
+	v.port(path, port, true)
+}
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadShippedConfigs(t *testing.T) {
+	for _, path := range []string{"dev.yaml", "prod.yaml"} {
+		t.Run(path, func(t *testing.T) {
+			if _, err := Load(path); err != nil {
+				t.Fatalf("load %s: %v", path, err)
+			}
+		})
+	}
+}
+
+func TestParseDefaults(t *testing.T) {
+	cfg, err := Parse([]byte(`
+Node: https://evm-rpc-http-reticulum.galactica.com
+RegistryAddress: 0x68272A56A0e9b095E5606fDD8b6c297702C0dfe5
+MerkleProofService:
+  URL: grpc-merkle-9302.galactica.com:443
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if cfg.Mode != "dev" {
+		t.Errorf("expected default mode dev, got %q", cfg.Mode)
+	}
+	if cfg.APIConf.Port != "8081" || cfg.APIConf.Host != "0.0.0.0" {
+		t.Errorf("expected default listen address 0.0.0.0:8081, got %s:%s", cfg.APIConf.Host, cfg.APIConf.Port)
+	}
+	if cfg.Issuance.Confirmations != 1 {
+		t.Errorf("expected default confirmations 1, got %d", cfg.Issuance.Confirmations)
+	}
+	if cfg.Issuance.ReplaceAfter != 3*time.Minute || cfg.Issuance.StuckAfter != 15*time.Minute {
+		t.Errorf("expected default replacement after 3m and stuck after 15m, got %s and %s",
+			cfg.Issuance.ReplaceAfter, cfg.Issuance.StuckAfter)
+	}
+	if cfg.Issuance.BaseFeeMultiplier != 2 || cfg.Issuance.MaxFeeCap != 0 {
+		t.Errorf("expected default fee cap of twice the base fee without ceiling, got %d and %v",
+			cfg.Issuance.BaseFeeMultiplier, cfg.Issuance.MaxFeeCap)
+	}
+}
+
+func TestParseLocalMerkleTrees(t *testing.T) {
+	cfg, err := Parse([]byte(`
+Node: https://evm-rpc-http-reticulum.galactica.com
+RegistryAddress: 0x68272A56A0e9b095E5606fDD8b6c297702C0dfe5
+MerkleProofService:
+  Local: true
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if urls := cfg.MerkleProofService.URLs(); len(urls) != 0 {
+		t.Errorf("expected no merkle proof service, got %q", urls)
+	}
+}
+
+func TestParseReportsEveryProblem(t *testing.T) {
+	_, err := Parse([]byte(`
+Mode: staging
+APIConf:
+  Port: 70000
+  GRPCPort: grpc
+  Typo: true
+  RateLimits:
+    - Route: post /v1/certificates
+      Key: user_id
+      Requests: 0
+      Period: 1m
+    - Route: /guardian.v1.GuardianService/GenerateCertificate
+      Key: session
+      Requests: 1
+      Period: 1m
+    - Route: post /v1/certificates
+      Key: user_id
+      Requests: 1
+      Period: 1m
+Node: evm-rpc-http-reticulum.galactica.com
+RegistryAddress: 0x68272a56a0e9b095e5606fdd8b6c297702c0dfe5
+MerkleProofService:
+  FallbackURLs: [merkle.example.com:443, merkle.example.com:443]
+Issuance:
+  Confirmations: -1
+  ReplaceAfter: -1m
+  TipCap: 2
+  MaxFeeCap: 1.5
+  ProviderKeys: [PRIVATE_KEY_2, PRIVATE_KEY_2]
+  MaxQueueDepth: -1
+Sybil:
+  Mode: [warn]
+Standards:
+  - Standard: gip1
+    RegistryAddress: 0x68272A56A0e9b095E5606fDD8b6c297702C0dfe5
+  - Standard: gip99
+SigningKeys:
+  Active: SIGNING_KEY_2025
+  Retired: [dfbc19b074d5372ffaf1bec8465896dc047181ecfb95bd9ff41de9848a7a2501, dfbc19b074d5372ffaf1bec8465896dc047181ecfb95bd9ff41de9848a7a2501, SIGNING_KEY]
+`))
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expected the error to wrap %v", ErrInvalidConfig)
+	}
+
+	problems := make(map[string]string, len(validationErr.Problems))
+	for _, p := range validationErr.Problems {
+		problems[p.Path] = p.Message
+	}
+
+	for path, want := range map[string]string{
+		"Mode":                               "must be one of",
+		"APIConf.Port":                       "between 1 and 65535",
+		"APIConf.GRPCPort":                   "between 1 and 65535",
+		"APIConf.Typo":                       "unknown field",
+		"APIConf.RateLimits[0].Route":        "method and a path",
+		"APIConf.RateLimits[0].Requests":     "at least 1",
+		"APIConf.RateLimits[1].Key":          "must be one of",
+		"APIConf.RateLimits[2]":              "duplicate user_id limit",
+		"Node":                               "no host",
+		"RegistryAddress":                    "checksum",
+		"MerkleProofService.URL":             "is required",
+		"MerkleProofService.FallbackURLs":    "requires",
+		"MerkleProofService.FallbackURLs[1]": "duplicate",
+		"Issuance.Confirmations":             "at least 1",
+		"Issuance.ReplaceAfter":              "must be positive",
+		"Issuance.MaxFeeCap":                 "at least Issuance.TipCap",
+		"Issuance.ProviderKeys[1]":           "duplicate",
+		"Issuance.MaxQueueDepth":             "must not be negative",
+		"Sybil.Mode":                         "cannot unmarshal",
+		"Standards[0].Standard":              "KYC",
+		"Standards[1].Standard":              "gip99",
+		"Standards[1].RegistryAddress":       "is required",
+		"SigningKeys.Retired[1]":             "duplicate",
+		"SigningKeys.Retired[2]":             "compressed public key",
+	} {
+		got, ok := problems[path]
+		if !ok {
+			t.Errorf("%s: expected a problem, got none", path)
+			continue
+		}
+		if !strings.Contains(got, want) {
+			t.Errorf("%s: expected a problem containing %q, got %q", path, want, got)
+		}
+	}
+}
+
+func TestRateLimitsRequireStoreInProd(t *testing.T) {
+	const limited = minimalConfig + `  RateLimits:
+    - Route: POST /v1/certificates
+      Key: ip
+      Requests: 60
+      Period: 1h
+`
+	if _, err := Parse([]byte(limited)); err != nil {
+		t.Errorf("expected in-memory limits to be accepted in dev mode, got %v", err)
+	}
+
+	_, err := Parse([]byte("Mode: prod\n" + limited))
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Problems) != 1 ||
+		validationErr.Problems[0].Path != "APIConf.RateLimits" {
+		t.Fatalf("expected the limits to require Store.Path in prod mode, got %v", err)
+	}
+
+	if _, err := Parse([]byte("Mode: prod\n" + limited + "Store:\n  Path: /var/lib/guardian\n")); err != nil {
+		t.Errorf("expected persisted limits to be accepted in prod mode, got %v", err)
+	}
+}
+
+func TestReadmeConfigExample(t *testing.T) {
+	readme, err := os.ReadFile("../readme.md")
+	if err != nil {
+		t.Fatalf("read readme: %v", err)
+	}
+
+	m := regexp.MustCompile("(?s)## Configuration.*?```yaml\\n(.*?)```").FindSubmatch(readme)
+	if m == nil {
+		t.Fatal("readme has no configuration example")
+	}
+
+	if _, err := Parse(m[1]); err != nil {
+		t.Errorf("readme configuration example: %v", err)
+	}
+}
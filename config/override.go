@@ -0,0 +1,250 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding the config fields
+const EnvPrefix = "GUARDIAN_"
+
+// Override sets the field at a YAML path, e.g. APIConf.Port, to a YAML value.
+// Lists are set as a whole, e.g. Standards to [{Standard: gip6, RegistryAddress: 0x...}].
+type Override struct {
+	Path  string
+	Value string
+}
+
+// ParseOverride parses an override written as Path=value.
+func ParseOverride(s string) (Override, error) {
+	path, value, ok := strings.Cut(s, "=")
+	if !ok || path == "" {
+		return Override{}, fmt.Errorf("override %q must be Path=value", s)
+	}
+	return Override{Path: path, Value: value}, nil
+}
+
+// Overrides is a repeatable flag of Path=value overrides.
+type Overrides []Override
+
+func (o *Overrides) String() string {
+	s := make([]string, len(*o))
+	for i, override := range *o {
+		s[i] = override.Path + "=" + override.Value
+	}
+	return strings.Join(s, ",")
+}
+
+func (o *Overrides) Set(s string) error {
+	override, err := ParseOverride(s)
+	if err != nil {
+		return err
+	}
+	*o = append(*o, override)
+	return nil
+}
+
+// EnvName returns the environment variable overriding the field at the YAML path,
+// e.g. GUARDIAN_APICONF_PORT for APIConf.Port.
+func EnvName(path string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// EnvOverrides returns the overrides of the GUARDIAN_ variables of environ, as returned by os.Environ, and the names
+// of the GUARDIAN_ variables matching no config field, which are ignored: other software sets some, e.g. Kubernetes
+// sets GUARDIAN_SERVICE_HOST and GUARDIAN_PORT in the pods of a namespace with a service named guardian.
+func EnvOverrides(environ []string) (overrides []Override, ignored []string) {
+	paths := make(map[string]string)
+	for _, path := range Paths() {
+		paths[EnvName(path)] = path
+	}
+
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+
+		path, ok := paths[name]
+		if !ok {
+			ignored = append(ignored, name)
+			continue
+		}
+		overrides = append(overrides, Override{Path: path, Value: value})
+	}
+
+	// the environment is unordered, the overrides are applied in a stable order
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].Path < overrides[j].Path })
+	sort.Strings(ignored)
+	return overrides, ignored
+}
+
+// Paths returns the YAML paths of every overridable config field.
+func Paths() []string {
+	return fieldPaths(reflect.TypeOf(Config{}), "")
+}
+
+func fieldPaths(t reflect.Type, prefix string) []string {
+	var paths []string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		path := prefix + yamlName(field)
+		if field.Type.Kind() == reflect.Struct && field.Type != addressType {
+			paths = append(paths, fieldPaths(field.Type, path+".")...)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// applyOverrides sets the fields of the YAML document root to the values of the overrides, in order.
+// The paths are case insensitive.
+func (v *validator) applyOverrides(root *yaml.Node, overrides []Override) {
+	known := make(map[string]string)
+	for _, path := range Paths() {
+		known[strings.ToUpper(path)] = path
+	}
+
+	for _, o := range overrides {
+		path, ok := known[strings.ToUpper(o.Path)]
+		if !ok {
+			v.report(o.Path, "unknown field")
+			continue
+		}
+
+		value, err := overrideNode(o.Value)
+		if err != nil {
+			v.report(o.Path, "invalid override: %v", err)
+			continue
+		}
+
+		setNode(root, strings.Split(path, "."), value)
+	}
+}
+
+// overrideNode parses the YAML value of an override, an empty value is an empty string.
+func overrideNode(value string) (*yaml.Node, error) {
+	if value == "" {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str"}, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &doc); err != nil {
+		return nil, err
+	}
+	return doc.Content[0], nil
+}
+
+// setNode sets the value at the path of mapping keys under node, creating the missing mappings.
+func setNode(node *yaml.Node, path []string, value *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		*node = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			node.Content[i+1] = value
+		} else {
+			setNode(node.Content[i+1], path[1:], value)
+		}
+		return
+	}
+
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: path[0]}
+	if len(path) == 1 {
+		node.Content = append(node.Content, key, value)
+		return
+	}
+
+	child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	setNode(child, path[1:], value)
+	node.Content = append(node.Content, key, child)
+}
+
+// WriteYAML writes the config as YAML, with checksummed addresses so that it can be loaded back.
+func (c Config) WriteYAML(w io.Writer) error {
+	var node yaml.Node
+	if err := node.Encode(c); err != nil {
+		return fmt.Errorf("encode config: %w", err)
+	}
+	checksumAddresses(&node, reflect.TypeOf(c))
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	return enc.Close()
+}
+
+// checksumAddresses replaces the addresses of the node encoding a value of type t by their EIP-55 checksum.
+func checksumAddresses(node *yaml.Node, t reflect.Type) {
+	switch {
+	case t == addressType:
+		node.Value = common.HexToAddress(node.Value).Hex()
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := make(map[string]reflect.Type, t.NumField())
+		for i := range t.NumField() {
+			fields[yamlName(t.Field(i))] = t.Field(i).Type
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if fieldType, ok := fields[node.Content[i].Value]; ok {
+				checksumAddresses(node.Content[i+1], fieldType)
+			}
+		}
+	case t.Kind() == reflect.Slice && node.Kind == yaml.SequenceNode:
+		for _, item := range node.Content {
+			checksumAddresses(item, t.Elem())
+		}
+	}
+}
+
+// redacted is the placeholder of the redacted parts of the config
+const redacted = "REDACTED"
+
+// Redacted returns a copy of the config safe to print. The keys are never part of the config,
+// but the node URLs commonly embed API keys in their credentials, path or query.
+func (c Config) Redacted() Config {
+	c.Node = redactURL(c.Node)
+	fallbackNodes := make([]string, len(c.FallbackNodes))
+	for i, node := range c.FallbackNodes {
+		fallbackNodes[i] = redactURL(node)
+	}
+	c.FallbackNodes = fallbackNodes
+	return c
+}
+
+func redactURL(s string) string {
+	if s == "" {
+		return s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+
+	if u.User != nil {
+		u.User = url.User(redacted)
+	}
+	if u.Path != "" && u.Path != "/" {
+		u.Path = "/" + redacted
+		u.RawPath = ""
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = redacted
+	}
+	u.Fragment = ""
+	return u.String()
+}
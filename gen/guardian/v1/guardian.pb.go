@@ -0,0 +1,610 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: guardian/v1/guardian.proto
+
+package guardianv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// CertificateStatus is the issuance status of a user's certificate.
+type CertificateStatus int32
+
+const (
+	CertificateStatus_CERTIFICATE_STATUS_UNSPECIFIED CertificateStatus = 0
+	CertificateStatus_CERTIFICATE_STATUS_PENDING     CertificateStatus = 1
+	CertificateStatus_CERTIFICATE_STATUS_DONE        CertificateStatus = 2
+	CertificateStatus_CERTIFICATE_STATUS_REVOKING    CertificateStatus = 3
+	CertificateStatus_CERTIFICATE_STATUS_REVOKED     CertificateStatus = 4
+)
+
+// Enum value maps for CertificateStatus.
+var (
+	CertificateStatus_name = map[int32]string{
+		0: "CERTIFICATE_STATUS_UNSPECIFIED",
+		1: "CERTIFICATE_STATUS_PENDING",
+		2: "CERTIFICATE_STATUS_DONE",
+		3: "CERTIFICATE_STATUS_REVOKING",
+		4: "CERTIFICATE_STATUS_REVOKED",
+	}
+	CertificateStatus_value = map[string]int32{
+		"CERTIFICATE_STATUS_UNSPECIFIED": 0,
+		"CERTIFICATE_STATUS_PENDING":     1,
+		"CERTIFICATE_STATUS_DONE":        2,
+		"CERTIFICATE_STATUS_REVOKING":    3,
+		"CERTIFICATE_STATUS_REVOKED":     4,
+	}
+)
+
+func (x CertificateStatus) Enum() *CertificateStatus {
+	p := new(CertificateStatus)
+	*p = x
+	return p
+}
+
+func (x CertificateStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CertificateStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_guardian_v1_guardian_proto_enumTypes[0].Descriptor()
+}
+
+func (CertificateStatus) Type() protoreflect.EnumType {
+	return &file_guardian_v1_guardian_proto_enumTypes[0]
+}
+
+func (x CertificateStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CertificateStatus.Descriptor instead.
+func (CertificateStatus) EnumDescriptor() ([]byte, []int) {
+	return file_guardian_v1_guardian_proto_rawDescGZIP(), []int{0}
+}
+
+// Profile is the KYC data of the user.
+type Profile struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Firstname string                 `protobuf:"bytes,1,opt,name=firstname,proto3" json:"firstname,omitempty"`
+	Lastname  string                 `protobuf:"bytes,2,opt,name=lastname,proto3" json:"lastname,omitempty"`
+	// date_of_birth is formatted as YYYY-MM-DD.
+	DateOfBirth   string `protobuf:"bytes,3,opt,name=date_of_birth,json=dateOfBirth,proto3" json:"date_of_birth,omitempty"`
+	Nationality   string `protobuf:"bytes,4,opt,name=nationality,proto3" json:"nationality,omitempty"`
+	Postcode      string `protobuf:"bytes,5,opt,name=postcode,proto3" json:"postcode,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Profile) Reset() {
+	*x = Profile{}
+	mi := &file_guardian_v1_guardian_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Profile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Profile) ProtoMessage() {}
+
+func (x *Profile) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_v1_guardian_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Profile.ProtoReflect.Descriptor instead.
+func (*Profile) Descriptor() ([]byte, []int) {
+	return file_guardian_v1_guardian_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Profile) GetFirstname() string {
+	if x != nil {
+		return x.Firstname
+	}
+	return ""
+}
+
+func (x *Profile) GetLastname() string {
+	if x != nil {
+		return x.Lastname
+	}
+	return ""
+}
+
+func (x *Profile) GetDateOfBirth() string {
+	if x != nil {
+		return x.DateOfBirth
+	}
+	return ""
+}
+
+func (x *Profile) GetNationality() string {
+	if x != nil {
+		return x.Nationality
+	}
+	return ""
+}
+
+func (x *Profile) GetPostcode() string {
+	if x != nil {
+		return x.Postcode
+	}
+	return ""
+}
+
+type GenerateCertificateRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// holder_commitment is the decimal encoded commitment hash of the holder.
+	HolderCommitment string `protobuf:"bytes,1,opt,name=holder_commitment,json=holderCommitment,proto3" json:"holder_commitment,omitempty"`
+	// encryption_pub_key is the base64 encoded encryption key of the holder.
+	EncryptionPubKey string   `protobuf:"bytes,2,opt,name=encryption_pub_key,json=encryptionPubKey,proto3" json:"encryption_pub_key,omitempty"`
+	UserId           string   `protobuf:"bytes,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Profile          *Profile `protobuf:"bytes,4,opt,name=profile,proto3" json:"profile,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *GenerateCertificateRequest) Reset() {
+	*x = GenerateCertificateRequest{}
+	mi := &file_guardian_v1_guardian_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateCertificateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateCertificateRequest) ProtoMessage() {}
+
+func (x *GenerateCertificateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_v1_guardian_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateCertificateRequest.ProtoReflect.Descriptor instead.
+func (*GenerateCertificateRequest) Descriptor() ([]byte, []int) {
+	return file_guardian_v1_guardian_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GenerateCertificateRequest) GetHolderCommitment() string {
+	if x != nil {
+		return x.HolderCommitment
+	}
+	return ""
+}
+
+func (x *GenerateCertificateRequest) GetEncryptionPubKey() string {
+	if x != nil {
+		return x.EncryptionPubKey
+	}
+	return ""
+}
+
+func (x *GenerateCertificateRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *GenerateCertificateRequest) GetProfile() *Profile {
+	if x != nil {
+		return x.Profile
+	}
+	return nil
+}
+
+type GenerateCertificateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        CertificateStatus      `protobuf:"varint,1,opt,name=status,proto3,enum=guardian.v1.CertificateStatus" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateCertificateResponse) Reset() {
+	*x = GenerateCertificateResponse{}
+	mi := &file_guardian_v1_guardian_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateCertificateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateCertificateResponse) ProtoMessage() {}
+
+func (x *GenerateCertificateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_v1_guardian_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateCertificateResponse.ProtoReflect.Descriptor instead.
+func (*GenerateCertificateResponse) Descriptor() ([]byte, []int) {
+	return file_guardian_v1_guardian_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GenerateCertificateResponse) GetStatus() CertificateStatus {
+	if x != nil {
+		return x.Status
+	}
+	return CertificateStatus_CERTIFICATE_STATUS_UNSPECIFIED
+}
+
+type GetCertificateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCertificateRequest) Reset() {
+	*x = GetCertificateRequest{}
+	mi := &file_guardian_v1_guardian_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCertificateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCertificateRequest) ProtoMessage() {}
+
+func (x *GetCertificateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_v1_guardian_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCertificateRequest.ProtoReflect.Descriptor instead.
+func (*GetCertificateRequest) Descriptor() ([]byte, []int) {
+	return file_guardian_v1_guardian_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetCertificateRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type GetCertificateResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Status CertificateStatus      `protobuf:"varint,1,opt,name=status,proto3,enum=guardian.v1.CertificateStatus" json:"status,omitempty"`
+	// certificate is the JSON encoded encrypted certificate, set once the status is DONE.
+	Certificate   string `protobuf:"bytes,2,opt,name=certificate,proto3" json:"certificate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCertificateResponse) Reset() {
+	*x = GetCertificateResponse{}
+	mi := &file_guardian_v1_guardian_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCertificateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCertificateResponse) ProtoMessage() {}
+
+func (x *GetCertificateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_v1_guardian_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCertificateResponse.ProtoReflect.Descriptor instead.
+func (*GetCertificateResponse) Descriptor() ([]byte, []int) {
+	return file_guardian_v1_guardian_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetCertificateResponse) GetStatus() CertificateStatus {
+	if x != nil {
+		return x.Status
+	}
+	return CertificateStatus_CERTIFICATE_STATUS_UNSPECIFIED
+}
+
+func (x *GetCertificateResponse) GetCertificate() string {
+	if x != nil {
+		return x.Certificate
+	}
+	return ""
+}
+
+type WatchCertificateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchCertificateRequest) Reset() {
+	*x = WatchCertificateRequest{}
+	mi := &file_guardian_v1_guardian_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchCertificateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchCertificateRequest) ProtoMessage() {}
+
+func (x *WatchCertificateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_v1_guardian_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchCertificateRequest.ProtoReflect.Descriptor instead.
+func (*WatchCertificateRequest) Descriptor() ([]byte, []int) {
+	return file_guardian_v1_guardian_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *WatchCertificateRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type RevokeCertificateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeCertificateRequest) Reset() {
+	*x = RevokeCertificateRequest{}
+	mi := &file_guardian_v1_guardian_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeCertificateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeCertificateRequest) ProtoMessage() {}
+
+func (x *RevokeCertificateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_v1_guardian_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeCertificateRequest.ProtoReflect.Descriptor instead.
+func (*RevokeCertificateRequest) Descriptor() ([]byte, []int) {
+	return file_guardian_v1_guardian_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RevokeCertificateRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type RevokeCertificateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        CertificateStatus      `protobuf:"varint,1,opt,name=status,proto3,enum=guardian.v1.CertificateStatus" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeCertificateResponse) Reset() {
+	*x = RevokeCertificateResponse{}
+	mi := &file_guardian_v1_guardian_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeCertificateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeCertificateResponse) ProtoMessage() {}
+
+func (x *RevokeCertificateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_guardian_v1_guardian_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeCertificateResponse.ProtoReflect.Descriptor instead.
+func (*RevokeCertificateResponse) Descriptor() ([]byte, []int) {
+	return file_guardian_v1_guardian_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *RevokeCertificateResponse) GetStatus() CertificateStatus {
+	if x != nil {
+		return x.Status
+	}
+	return CertificateStatus_CERTIFICATE_STATUS_UNSPECIFIED
+}
+
+var File_guardian_v1_guardian_proto protoreflect.FileDescriptor
+
+const file_guardian_v1_guardian_proto_rawDesc = "" +
+	"\n" +
+	"\x1aguardian/v1/guardian.proto\x12\vguardian.v1\"\xa5\x01\n" +
+	"\aProfile\x12\x1c\n" +
+	"\tfirstname\x18\x01 \x01(\tR\tfirstname\x12\x1a\n" +
+	"\blastname\x18\x02 \x01(\tR\blastname\x12\"\n" +
+	"\rdate_of_birth\x18\x03 \x01(\tR\vdateOfBirth\x12 \n" +
+	"\vnationality\x18\x04 \x01(\tR\vnationality\x12\x1a\n" +
+	"\bpostcode\x18\x05 \x01(\tR\bpostcode\"\xc0\x01\n" +
+	"\x1aGenerateCertificateRequest\x12+\n" +
+	"\x11holder_commitment\x18\x01 \x01(\tR\x10holderCommitment\x12,\n" +
+	"\x12encryption_pub_key\x18\x02 \x01(\tR\x10encryptionPubKey\x12\x17\n" +
+	"\auser_id\x18\x03 \x01(\tR\x06userId\x12.\n" +
+	"\aprofile\x18\x04 \x01(\v2\x14.guardian.v1.ProfileR\aprofile\"U\n" +
+	"\x1bGenerateCertificateResponse\x126\n" +
+	"\x06status\x18\x01 \x01(\x0e2\x1e.guardian.v1.CertificateStatusR\x06status\"0\n" +
+	"\x15GetCertificateRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"r\n" +
+	"\x16GetCertificateResponse\x126\n" +
+	"\x06status\x18\x01 \x01(\x0e2\x1e.guardian.v1.CertificateStatusR\x06status\x12 \n" +
+	"\vcertificate\x18\x02 \x01(\tR\vcertificate\"2\n" +
+	"\x17WatchCertificateRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"3\n" +
+	"\x18RevokeCertificateRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"S\n" +
+	"\x19RevokeCertificateResponse\x126\n" +
+	"\x06status\x18\x01 \x01(\x0e2\x1e.guardian.v1.CertificateStatusR\x06status*\xb5\x01\n" +
+	"\x11CertificateStatus\x12\"\n" +
+	"\x1eCERTIFICATE_STATUS_UNSPECIFIED\x10\x00\x12\x1e\n" +
+	"\x1aCERTIFICATE_STATUS_PENDING\x10\x01\x12\x1b\n" +
+	"\x17CERTIFICATE_STATUS_DONE\x10\x02\x12\x1f\n" +
+	"\x1bCERTIFICATE_STATUS_REVOKING\x10\x03\x12\x1e\n" +
+	"\x1aCERTIFICATE_STATUS_REVOKED\x10\x042\x9b\x03\n" +
+	"\x0fGuardianService\x12h\n" +
+	"\x13GenerateCertificate\x12'.guardian.v1.GenerateCertificateRequest\x1a(.guardian.v1.GenerateCertificateResponse\x12Y\n" +
+	"\x0eGetCertificate\x12\".guardian.v1.GetCertificateRequest\x1a#.guardian.v1.GetCertificateResponse\x12_\n" +
+	"\x10WatchCertificate\x12$.guardian.v1.WatchCertificateRequest\x1a#.guardian.v1.GetCertificateResponse0\x01\x12b\n" +
+	"\x11RevokeCertificate\x12%.guardian.v1.RevokeCertificateRequest\x1a&.guardian.v1.RevokeCertificateResponseBHZFgithub.com/swissborg/galactica-kyc-guardian/gen/guardian/v1;guardianv1b\x06proto3"
+
+var (
+	file_guardian_v1_guardian_proto_rawDescOnce sync.Once
+	file_guardian_v1_guardian_proto_rawDescData []byte
+)
+
+func file_guardian_v1_guardian_proto_rawDescGZIP() []byte {
+	file_guardian_v1_guardian_proto_rawDescOnce.Do(func() {
+		file_guardian_v1_guardian_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_guardian_v1_guardian_proto_rawDesc), len(file_guardian_v1_guardian_proto_rawDesc)))
+	})
+	return file_guardian_v1_guardian_proto_rawDescData
+}
+
+var file_guardian_v1_guardian_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_guardian_v1_guardian_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_guardian_v1_guardian_proto_goTypes = []any{
+	(CertificateStatus)(0),              // 0: guardian.v1.CertificateStatus
+	(*Profile)(nil),                     // 1: guardian.v1.Profile
+	(*GenerateCertificateRequest)(nil),  // 2: guardian.v1.GenerateCertificateRequest
+	(*GenerateCertificateResponse)(nil), // 3: guardian.v1.GenerateCertificateResponse
+	(*GetCertificateRequest)(nil),       // 4: guardian.v1.GetCertificateRequest
+	(*GetCertificateResponse)(nil),      // 5: guardian.v1.GetCertificateResponse
+	(*WatchCertificateRequest)(nil),     // 6: guardian.v1.WatchCertificateRequest
+	(*RevokeCertificateRequest)(nil),    // 7: guardian.v1.RevokeCertificateRequest
+	(*RevokeCertificateResponse)(nil),   // 8: guardian.v1.RevokeCertificateResponse
+}
+var file_guardian_v1_guardian_proto_depIdxs = []int32{
+	1, // 0: guardian.v1.GenerateCertificateRequest.profile:type_name -> guardian.v1.Profile
+	0, // 1: guardian.v1.GenerateCertificateResponse.status:type_name -> guardian.v1.CertificateStatus
+	0, // 2: guardian.v1.GetCertificateResponse.status:type_name -> guardian.v1.CertificateStatus
+	0, // 3: guardian.v1.RevokeCertificateResponse.status:type_name -> guardian.v1.CertificateStatus
+	2, // 4: guardian.v1.GuardianService.GenerateCertificate:input_type -> guardian.v1.GenerateCertificateRequest
+	4, // 5: guardian.v1.GuardianService.GetCertificate:input_type -> guardian.v1.GetCertificateRequest
+	6, // 6: guardian.v1.GuardianService.WatchCertificate:input_type -> guardian.v1.WatchCertificateRequest
+	7, // 7: guardian.v1.GuardianService.RevokeCertificate:input_type -> guardian.v1.RevokeCertificateRequest
+	3, // 8: guardian.v1.GuardianService.GenerateCertificate:output_type -> guardian.v1.GenerateCertificateResponse
+	5, // 9: guardian.v1.GuardianService.GetCertificate:output_type -> guardian.v1.GetCertificateResponse
+	5, // 10: guardian.v1.GuardianService.WatchCertificate:output_type -> guardian.v1.GetCertificateResponse
+	8, // 11: guardian.v1.GuardianService.RevokeCertificate:output_type -> guardian.v1.RevokeCertificateResponse
+	8, // [8:12] is the sub-list for method output_type
+	4, // [4:8] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_guardian_v1_guardian_proto_init() }
+func file_guardian_v1_guardian_proto_init() {
+	if File_guardian_v1_guardian_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_guardian_v1_guardian_proto_rawDesc), len(file_guardian_v1_guardian_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_guardian_v1_guardian_proto_goTypes,
+		DependencyIndexes: file_guardian_v1_guardian_proto_depIdxs,
+		EnumInfos:         file_guardian_v1_guardian_proto_enumTypes,
+		MessageInfos:      file_guardian_v1_guardian_proto_msgTypes,
+	}.Build()
+	File_guardian_v1_guardian_proto = out.File
+	file_guardian_v1_guardian_proto_goTypes = nil
+	file_guardian_v1_guardian_proto_depIdxs = nil
+}
@@ -0,0 +1,258 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v5.29.3
+// source: guardian/v1/guardian.proto
+
+package guardianv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	GuardianService_GenerateCertificate_FullMethodName = "/guardian.v1.GuardianService/GenerateCertificate"
+	GuardianService_GetCertificate_FullMethodName      = "/guardian.v1.GuardianService/GetCertificate"
+	GuardianService_WatchCertificate_FullMethodName    = "/guardian.v1.GuardianService/WatchCertificate"
+	GuardianService_RevokeCertificate_FullMethodName   = "/guardian.v1.GuardianService/RevokeCertificate"
+)
+
+// GuardianServiceClient is the client API for GuardianService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GuardianServiceClient interface {
+	// GenerateCertificate starts the computation of a new certificate from the user's profile.
+	GenerateCertificate(ctx context.Context, in *GenerateCertificateRequest, opts ...grpc.CallOption) (*GenerateCertificateResponse, error)
+	// GetCertificate returns the status of the certificate and its value when computed.
+	GetCertificate(ctx context.Context, in *GetCertificateRequest, opts ...grpc.CallOption) (*GetCertificateResponse, error)
+	// WatchCertificate streams the certificate every time its status changes,
+	// until it reaches a final status or the user data is removed.
+	WatchCertificate(ctx context.Context, in *WatchCertificateRequest, opts ...grpc.CallOption) (GuardianService_WatchCertificateClient, error)
+	// RevokeCertificate queues the on-chain revocation of an issued certificate.
+	RevokeCertificate(ctx context.Context, in *RevokeCertificateRequest, opts ...grpc.CallOption) (*RevokeCertificateResponse, error)
+}
+
+type guardianServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGuardianServiceClient(cc grpc.ClientConnInterface) GuardianServiceClient {
+	return &guardianServiceClient{cc}
+}
+
+func (c *guardianServiceClient) GenerateCertificate(ctx context.Context, in *GenerateCertificateRequest, opts ...grpc.CallOption) (*GenerateCertificateResponse, error) {
+	out := new(GenerateCertificateResponse)
+	err := c.cc.Invoke(ctx, GuardianService_GenerateCertificate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *guardianServiceClient) GetCertificate(ctx context.Context, in *GetCertificateRequest, opts ...grpc.CallOption) (*GetCertificateResponse, error) {
+	out := new(GetCertificateResponse)
+	err := c.cc.Invoke(ctx, GuardianService_GetCertificate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *guardianServiceClient) WatchCertificate(ctx context.Context, in *WatchCertificateRequest, opts ...grpc.CallOption) (GuardianService_WatchCertificateClient, error) {
+	stream, err := c.cc.NewStream(ctx, &GuardianService_ServiceDesc.Streams[0], GuardianService_WatchCertificate_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &guardianServiceWatchCertificateClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type GuardianService_WatchCertificateClient interface {
+	Recv() (*GetCertificateResponse, error)
+	grpc.ClientStream
+}
+
+type guardianServiceWatchCertificateClient struct {
+	grpc.ClientStream
+}
+
+func (x *guardianServiceWatchCertificateClient) Recv() (*GetCertificateResponse, error) {
+	m := new(GetCertificateResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *guardianServiceClient) RevokeCertificate(ctx context.Context, in *RevokeCertificateRequest, opts ...grpc.CallOption) (*RevokeCertificateResponse, error) {
+	out := new(RevokeCertificateResponse)
+	err := c.cc.Invoke(ctx, GuardianService_RevokeCertificate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GuardianServiceServer is the server API for GuardianService service.
+// All implementations must embed UnimplementedGuardianServiceServer
+// for forward compatibility
+type GuardianServiceServer interface {
+	// GenerateCertificate starts the computation of a new certificate from the user's profile.
+	GenerateCertificate(context.Context, *GenerateCertificateRequest) (*GenerateCertificateResponse, error)
+	// GetCertificate returns the status of the certificate and its value when computed.
+	GetCertificate(context.Context, *GetCertificateRequest) (*GetCertificateResponse, error)
+	// WatchCertificate streams the certificate every time its status changes,
+	// until it reaches a final status or the user data is removed.
+	WatchCertificate(*WatchCertificateRequest, GuardianService_WatchCertificateServer) error
+	// RevokeCertificate queues the on-chain revocation of an issued certificate.
+	RevokeCertificate(context.Context, *RevokeCertificateRequest) (*RevokeCertificateResponse, error)
+	mustEmbedUnimplementedGuardianServiceServer()
+}
+
+// UnimplementedGuardianServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedGuardianServiceServer struct {
+}
+
+func (UnimplementedGuardianServiceServer) GenerateCertificate(context.Context, *GenerateCertificateRequest) (*GenerateCertificateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GenerateCertificate not implemented")
+}
+func (UnimplementedGuardianServiceServer) GetCertificate(context.Context, *GetCertificateRequest) (*GetCertificateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCertificate not implemented")
+}
+func (UnimplementedGuardianServiceServer) WatchCertificate(*WatchCertificateRequest, GuardianService_WatchCertificateServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchCertificate not implemented")
+}
+func (UnimplementedGuardianServiceServer) RevokeCertificate(context.Context, *RevokeCertificateRequest) (*RevokeCertificateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeCertificate not implemented")
+}
+func (UnimplementedGuardianServiceServer) mustEmbedUnimplementedGuardianServiceServer() {}
+
+// UnsafeGuardianServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GuardianServiceServer will
+// result in compilation errors.
+type UnsafeGuardianServiceServer interface {
+	mustEmbedUnimplementedGuardianServiceServer()
+}
+
+func RegisterGuardianServiceServer(s grpc.ServiceRegistrar, srv GuardianServiceServer) {
+	s.RegisterService(&GuardianService_ServiceDesc, srv)
+}
+
+func _GuardianService_GenerateCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateCertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GuardianServiceServer).GenerateCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GuardianService_GenerateCertificate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GuardianServiceServer).GenerateCertificate(ctx, req.(*GenerateCertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GuardianService_GetCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GuardianServiceServer).GetCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GuardianService_GetCertificate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GuardianServiceServer).GetCertificate(ctx, req.(*GetCertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GuardianService_WatchCertificate_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchCertificateRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GuardianServiceServer).WatchCertificate(m, &guardianServiceWatchCertificateServer{stream})
+}
+
+type GuardianService_WatchCertificateServer interface {
+	Send(*GetCertificateResponse) error
+	grpc.ServerStream
+}
+
+type guardianServiceWatchCertificateServer struct {
+	grpc.ServerStream
+}
+
+func (x *guardianServiceWatchCertificateServer) Send(m *GetCertificateResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _GuardianService_RevokeCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeCertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GuardianServiceServer).RevokeCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GuardianService_RevokeCertificate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GuardianServiceServer).RevokeCertificate(ctx, req.(*RevokeCertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// GuardianService_ServiceDesc is the grpc.ServiceDesc for GuardianService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GuardianService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "guardian.v1.GuardianService",
+	HandlerType: (*GuardianServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GenerateCertificate",
+			Handler:    _GuardianService_GenerateCertificate_Handler,
+		},
+		{
+			MethodName: "GetCertificate",
+			Handler:    _GuardianService_GetCertificate_Handler,
+		},
+		{
+			MethodName: "RevokeCertificate",
+			Handler:    _GuardianService_RevokeCertificate_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchCertificate",
+			Handler:       _GuardianService_WatchCertificate_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "guardian/v1/guardian.proto",
+}
@@ -0,0 +1,413 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: signer/v1/signer.proto
+
+package signerv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetPublicKeysRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPublicKeysRequest) Reset() {
+	*x = GetPublicKeysRequest{}
+	mi := &file_signer_v1_signer_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPublicKeysRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPublicKeysRequest) ProtoMessage() {}
+
+func (x *GetPublicKeysRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_signer_v1_signer_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPublicKeysRequest.ProtoReflect.Descriptor instead.
+func (*GetPublicKeysRequest) Descriptor() ([]byte, []int) {
+	return file_signer_v1_signer_proto_rawDescGZIP(), []int{0}
+}
+
+type GetPublicKeysResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// provider_address is the Ethereum address of the provider key
+	ProviderAddress []byte `protobuf:"bytes,1,opt,name=provider_address,json=providerAddress,proto3" json:"provider_address,omitempty"`
+	// signing_public_key is the compressed babyjub public key
+	SigningPublicKey []byte `protobuf:"bytes,2,opt,name=signing_public_key,json=signingPublicKey,proto3" json:"signing_public_key,omitempty"`
+	// retired_signing_public_keys are the compressed babyjub public keys used before the last rotations
+	RetiredSigningPublicKeys [][]byte `protobuf:"bytes,3,rep,name=retired_signing_public_keys,json=retiredSigningPublicKeys,proto3" json:"retired_signing_public_keys,omitempty"`
+	unknownFields            protoimpl.UnknownFields
+	sizeCache                protoimpl.SizeCache
+}
+
+func (x *GetPublicKeysResponse) Reset() {
+	*x = GetPublicKeysResponse{}
+	mi := &file_signer_v1_signer_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPublicKeysResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPublicKeysResponse) ProtoMessage() {}
+
+func (x *GetPublicKeysResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_signer_v1_signer_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPublicKeysResponse.ProtoReflect.Descriptor instead.
+func (*GetPublicKeysResponse) Descriptor() ([]byte, []int) {
+	return file_signer_v1_signer_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetPublicKeysResponse) GetProviderAddress() []byte {
+	if x != nil {
+		return x.ProviderAddress
+	}
+	return nil
+}
+
+func (x *GetPublicKeysResponse) GetSigningPublicKey() []byte {
+	if x != nil {
+		return x.SigningPublicKey
+	}
+	return nil
+}
+
+func (x *GetPublicKeysResponse) GetRetiredSigningPublicKeys() [][]byte {
+	if x != nil {
+		return x.RetiredSigningPublicKeys
+	}
+	return nil
+}
+
+type SignTransactionRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// transaction is the binary encoding of the unsigned transaction
+	Transaction []byte `protobuf:"bytes,1,opt,name=transaction,proto3" json:"transaction,omitempty"`
+	// chain_id is the big-endian chain ID the transaction is signed for
+	ChainId       []byte `protobuf:"bytes,2,opt,name=chain_id,json=chainId,proto3" json:"chain_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SignTransactionRequest) Reset() {
+	*x = SignTransactionRequest{}
+	mi := &file_signer_v1_signer_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SignTransactionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignTransactionRequest) ProtoMessage() {}
+
+func (x *SignTransactionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_signer_v1_signer_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignTransactionRequest.ProtoReflect.Descriptor instead.
+func (*SignTransactionRequest) Descriptor() ([]byte, []int) {
+	return file_signer_v1_signer_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SignTransactionRequest) GetTransaction() []byte {
+	if x != nil {
+		return x.Transaction
+	}
+	return nil
+}
+
+func (x *SignTransactionRequest) GetChainId() []byte {
+	if x != nil {
+		return x.ChainId
+	}
+	return nil
+}
+
+type SignTransactionResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// transaction is the binary encoding of the signed transaction
+	Transaction   []byte `protobuf:"bytes,1,opt,name=transaction,proto3" json:"transaction,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SignTransactionResponse) Reset() {
+	*x = SignTransactionResponse{}
+	mi := &file_signer_v1_signer_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SignTransactionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignTransactionResponse) ProtoMessage() {}
+
+func (x *SignTransactionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_signer_v1_signer_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignTransactionResponse.ProtoReflect.Descriptor instead.
+func (*SignTransactionResponse) Descriptor() ([]byte, []int) {
+	return file_signer_v1_signer_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SignTransactionResponse) GetTransaction() []byte {
+	if x != nil {
+		return x.Transaction
+	}
+	return nil
+}
+
+type SignCertificateRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// content_hash is the 32 bytes hash of the certificate content
+	ContentHash []byte `protobuf:"bytes,1,opt,name=content_hash,json=contentHash,proto3" json:"content_hash,omitempty"`
+	// commitment_hash is the 32 bytes hash of the holder commitment
+	CommitmentHash []byte `protobuf:"bytes,2,opt,name=commitment_hash,json=commitmentHash,proto3" json:"commitment_hash,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *SignCertificateRequest) Reset() {
+	*x = SignCertificateRequest{}
+	mi := &file_signer_v1_signer_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SignCertificateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignCertificateRequest) ProtoMessage() {}
+
+func (x *SignCertificateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_signer_v1_signer_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignCertificateRequest.ProtoReflect.Descriptor instead.
+func (*SignCertificateRequest) Descriptor() ([]byte, []int) {
+	return file_signer_v1_signer_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SignCertificateRequest) GetContentHash() []byte {
+	if x != nil {
+		return x.ContentHash
+	}
+	return nil
+}
+
+func (x *SignCertificateRequest) GetCommitmentHash() []byte {
+	if x != nil {
+		return x.CommitmentHash
+	}
+	return nil
+}
+
+type SignCertificateResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// signature is the compressed babyjub signature
+	Signature []byte `protobuf:"bytes,1,opt,name=signature,proto3" json:"signature,omitempty"`
+	// signing_public_key is the compressed babyjub public key of the signature
+	SigningPublicKey []byte `protobuf:"bytes,2,opt,name=signing_public_key,json=signingPublicKey,proto3" json:"signing_public_key,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *SignCertificateResponse) Reset() {
+	*x = SignCertificateResponse{}
+	mi := &file_signer_v1_signer_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SignCertificateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignCertificateResponse) ProtoMessage() {}
+
+func (x *SignCertificateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_signer_v1_signer_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignCertificateResponse.ProtoReflect.Descriptor instead.
+func (*SignCertificateResponse) Descriptor() ([]byte, []int) {
+	return file_signer_v1_signer_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SignCertificateResponse) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+func (x *SignCertificateResponse) GetSigningPublicKey() []byte {
+	if x != nil {
+		return x.SigningPublicKey
+	}
+	return nil
+}
+
+var File_signer_v1_signer_proto protoreflect.FileDescriptor
+
+const file_signer_v1_signer_proto_rawDesc = "" +
+	"\n" +
+	"\x16signer/v1/signer.proto\x12\tsigner.v1\"\x16\n" +
+	"\x14GetPublicKeysRequest\"\xaf\x01\n" +
+	"\x15GetPublicKeysResponse\x12)\n" +
+	"\x10provider_address\x18\x01 \x01(\fR\x0fproviderAddress\x12,\n" +
+	"\x12signing_public_key\x18\x02 \x01(\fR\x10signingPublicKey\x12=\n" +
+	"\x1bretired_signing_public_keys\x18\x03 \x03(\fR\x18retiredSigningPublicKeys\"U\n" +
+	"\x16SignTransactionRequest\x12 \n" +
+	"\vtransaction\x18\x01 \x01(\fR\vtransaction\x12\x19\n" +
+	"\bchain_id\x18\x02 \x01(\fR\achainId\";\n" +
+	"\x17SignTransactionResponse\x12 \n" +
+	"\vtransaction\x18\x01 \x01(\fR\vtransaction\"d\n" +
+	"\x16SignCertificateRequest\x12!\n" +
+	"\fcontent_hash\x18\x01 \x01(\fR\vcontentHash\x12'\n" +
+	"\x0fcommitment_hash\x18\x02 \x01(\fR\x0ecommitmentHash\"e\n" +
+	"\x17SignCertificateResponse\x12\x1c\n" +
+	"\tsignature\x18\x01 \x01(\fR\tsignature\x12,\n" +
+	"\x12signing_public_key\x18\x02 \x01(\fR\x10signingPublicKey2\x97\x02\n" +
+	"\rSignerService\x12R\n" +
+	"\rGetPublicKeys\x12\x1f.signer.v1.GetPublicKeysRequest\x1a .signer.v1.GetPublicKeysResponse\x12X\n" +
+	"\x0fSignTransaction\x12!.signer.v1.SignTransactionRequest\x1a\".signer.v1.SignTransactionResponse\x12X\n" +
+	"\x0fSignCertificate\x12!.signer.v1.SignCertificateRequest\x1a\".signer.v1.SignCertificateResponseBDZBgithub.com/swissborg/galactica-kyc-guardian/gen/signer/v1;signerv1b\x06proto3"
+
+var (
+	file_signer_v1_signer_proto_rawDescOnce sync.Once
+	file_signer_v1_signer_proto_rawDescData []byte
+)
+
+func file_signer_v1_signer_proto_rawDescGZIP() []byte {
+	file_signer_v1_signer_proto_rawDescOnce.Do(func() {
+		file_signer_v1_signer_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_signer_v1_signer_proto_rawDesc), len(file_signer_v1_signer_proto_rawDesc)))
+	})
+	return file_signer_v1_signer_proto_rawDescData
+}
+
+var file_signer_v1_signer_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_signer_v1_signer_proto_goTypes = []any{
+	(*GetPublicKeysRequest)(nil),    // 0: signer.v1.GetPublicKeysRequest
+	(*GetPublicKeysResponse)(nil),   // 1: signer.v1.GetPublicKeysResponse
+	(*SignTransactionRequest)(nil),  // 2: signer.v1.SignTransactionRequest
+	(*SignTransactionResponse)(nil), // 3: signer.v1.SignTransactionResponse
+	(*SignCertificateRequest)(nil),  // 4: signer.v1.SignCertificateRequest
+	(*SignCertificateResponse)(nil), // 5: signer.v1.SignCertificateResponse
+}
+var file_signer_v1_signer_proto_depIdxs = []int32{
+	0, // 0: signer.v1.SignerService.GetPublicKeys:input_type -> signer.v1.GetPublicKeysRequest
+	2, // 1: signer.v1.SignerService.SignTransaction:input_type -> signer.v1.SignTransactionRequest
+	4, // 2: signer.v1.SignerService.SignCertificate:input_type -> signer.v1.SignCertificateRequest
+	1, // 3: signer.v1.SignerService.GetPublicKeys:output_type -> signer.v1.GetPublicKeysResponse
+	3, // 4: signer.v1.SignerService.SignTransaction:output_type -> signer.v1.SignTransactionResponse
+	5, // 5: signer.v1.SignerService.SignCertificate:output_type -> signer.v1.SignCertificateResponse
+	3, // [3:6] is the sub-list for method output_type
+	0, // [0:3] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_signer_v1_signer_proto_init() }
+func file_signer_v1_signer_proto_init() {
+	if File_signer_v1_signer_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_signer_v1_signer_proto_rawDesc), len(file_signer_v1_signer_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_signer_v1_signer_proto_goTypes,
+		DependencyIndexes: file_signer_v1_signer_proto_depIdxs,
+		MessageInfos:      file_signer_v1_signer_proto_msgTypes,
+	}.Build()
+	File_signer_v1_signer_proto = out.File
+	file_signer_v1_signer_proto_goTypes = nil
+	file_signer_v1_signer_proto_depIdxs = nil
+}
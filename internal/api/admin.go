@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// The methods below run the certificate operations to completion, for one-off use from the command line.
+
+// Issue creates the certificate of the request and waits until it is issued on-chain.
+func (h *Handlers) Issue(ctx context.Context, req GenerateCertRequest) (GetCertResponse, error) {
+	if _, err := h.generateCert(ctx, req); err != nil {
+		return GetCertResponse{}, err
+	}
+
+	resp, err := h.waitCert(ctx, req.UserID)
+	if errors.Is(err, ErrCertNotFound) {
+		// the pending certificate is deleted when its issuance fails
+		return GetCertResponse{}, ErrIssuanceFailed
+	}
+	return resp, err
+}
+
+// Issuance returns the registration of the certificate issued to the user.
+func (h *Handlers) Issuance(userID UserID) (Issuance, error) {
+	return readIssuanceFromDB(h.inMem, userID)
+}
+
+// Revoke revokes the certificate issued to the user and waits until the revocation is done.
+func (h *Handlers) Revoke(ctx context.Context, userID UserID) error {
+	status, err := h.revokeCert(userID)
+	if err != nil {
+		return err
+	}
+	if status != CertificateStatusRevoking {
+		return fmt.Errorf("certificate is %s", status)
+	}
+
+	resp, err := h.waitCert(ctx, userID)
+	if errors.Is(err, ErrCertNotFound) || (err == nil && resp.Status != CertificateStatusRevoked) {
+		// the certificate is back to done, its expired data is not found anymore
+		return ErrRevocationFailed
+	}
+	return err
+}
+
+// waitCert waits until the certificate of the user reaches a final status.
+func (h *Handlers) waitCert(ctx context.Context, userID UserID) (GetCertResponse, error) {
+	var last GetCertResponse
+	err := h.watchCert(ctx, userID, func(resp GetCertResponse) error {
+		last = resp
+		return nil
+	})
+	return last, err
+}
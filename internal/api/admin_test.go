@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+func TestIssueAndRevoke(t *testing.T) {
+	originalInterval := watchPollInterval
+	watchPollInterval = 10 * time.Millisecond
+	defer func() {
+		watchPollInterval = originalInterval
+	}()
+
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("open badger: %v", err)
+	}
+	defer db.Close()
+
+	generator := newFakeGenerator()
+	generator.issue = true
+	handlers := NewHandlers(generator, db)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := handlers.Issue(ctx, GenerateCertRequest{
+		HolderCommitment: "4586425042444163335895417167611444541749813513569901646582116352074512113476",
+		EncryptionPubKey: "OEotdsfEuoiqM7ob2KJEQemhWodn87hZNFv890q4xGw=",
+		UserID:           "12345",
+		Profile: Profile{
+			Firstname:   "Bob",
+			Lastname:    "Norman",
+			DateOfBirth: "2006-01-02",
+			Nationality: "CH",
+			Postcode:    "1006",
+		},
+	})
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	if resp.Status != CertificateStatusDone || resp.Certificate == nil {
+		t.Fatalf("issue: expected the certificate once done, got %+v", resp)
+	}
+
+	iss, err := handlers.Issuance("12345")
+	if err != nil {
+		t.Fatalf("issuance: %v", err)
+	}
+	if iss.Status != CertificateStatusDone || iss.SigningKeyID == "" {
+		t.Errorf("issuance: expected a done issuance with its key ID, got %+v", iss)
+	}
+
+	if err := handlers.Revoke(ctx, "12345"); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if iss, err := handlers.Issuance("12345"); err != nil || iss.Status != CertificateStatusRevoked {
+		t.Errorf("issuance: expected revoked, got %+v, %v", iss, err)
+	}
+
+	if err := handlers.Revoke(ctx, "unknown"); !errors.Is(err, ErrCertNotFound) {
+		t.Errorf("revoke unknown: expected %v, got %v", ErrCertNotFound, err)
+	}
+}
+
+func TestIssueRequeued(t *testing.T) {
+	originalInterval := watchPollInterval
+	watchPollInterval = 10 * time.Millisecond
+	defer func() {
+		watchPollInterval = originalInterval
+	}()
+
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("open badger: %v", err)
+	}
+	defer db.Close()
+
+	generator := newFakeGenerator()
+	generator.issue = true
+	generator.requeue = true
+	handlers := NewHandlers(generator, db)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var statuses []CertificateStatus
+	if _, err := handlers.generateCert(ctx, GenerateCertRequest{
+		HolderCommitment: "4586425042444163335895417167611444541749813513569901646582116352074512113476",
+		EncryptionPubKey: "OEotdsfEuoiqM7ob2KJEQemhWodn87hZNFv890q4xGw=",
+		UserID:           "12345",
+		Profile: Profile{
+			Firstname:   "Bob",
+			Lastname:    "Norman",
+			DateOfBirth: "2006-01-02",
+			Nationality: "CH",
+			Postcode:    "1006",
+		},
+	}); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	err = handlers.watchCert(ctx, "12345", func(resp GetCertResponse) error {
+		statuses = append(statuses, resp.Status)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+
+	// the certificate stays pending while its issuance is requeued
+	if len(statuses) != 2 || statuses[0] != CertificateStatusPending || statuses[1] != CertificateStatusDone {
+		t.Errorf("expected pending then done, got %v", statuses)
+	}
+}
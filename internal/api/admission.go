@@ -0,0 +1,112 @@
+package api
+
+import (
+	"expvar"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+// admissionRejections counts the new certificates refused by the admission control, by reason,
+// served with the other process metrics at GET /debug/vars
+var admissionRejections = expvar.NewMap("admission_rejections")
+
+// Reasons of the admission rejections
+const (
+	rejectedQueueDepth    = "queue_depth"
+	rejectedEstimatedWait = "estimated_wait"
+)
+
+// defaultRetryAfter is suggested to the refused callers until an issuance is timed
+const defaultRetryAfter = time.Minute
+
+// Admission limits the issuances queued, new certificates are refused beyond the limits.
+// A zero limit is unlimited.
+type Admission struct {
+	// MaxQueueDepth is the number of issuances and revocations queued or running a new certificate is refused at
+	MaxQueueDepth int
+	// MaxEstimatedWait is the estimated wait of a new issuance a new certificate is refused above
+	MaxEstimatedWait time.Duration
+}
+
+// WithAdmission refuses the new certificates while the issuance queue exceeds the limits of admission.
+func WithAdmission(admission Admission) Option {
+	return func(h *Handlers) {
+		h.admission = admission
+	}
+}
+
+// RetryableError is returned when a request is refused for now, it can be sent again after RetryAfter.
+type RetryableError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// admit returns a *RetryableError when a new certificate would exceed the limits of the admission control.
+func (h *Handlers) admit() error {
+	if h.admission == (Admission{}) {
+		return nil
+	}
+
+	backlog := h.generator.Backlog()
+
+	var err *RetryableError
+	switch {
+	case h.admission.MaxQueueDepth > 0 && backlog.Pending >= h.admission.MaxQueueDepth:
+		admissionRejections.Add(rejectedQueueDepth, 1)
+
+		retryAfter := defaultRetryAfter
+		if backlog.MeanDuration > 0 {
+			// the lanes process the tasks in parallel
+			excess := backlog.Pending - h.admission.MaxQueueDepth + 1
+			retryAfter = backlog.MeanDuration * time.Duration(excess) / time.Duration(max(backlog.Lanes, 1))
+		}
+		err = &RetryableError{
+			Err:        fmt.Errorf("%w: %d issuances queued", ErrQueueFull, backlog.Pending),
+			RetryAfter: retryAfter,
+		}
+	case h.admission.MaxEstimatedWait > 0 && backlog.EstimatedWait > h.admission.MaxEstimatedWait:
+		admissionRejections.Add(rejectedEstimatedWait, 1)
+
+		err = &RetryableError{
+			Err:        fmt.Errorf("%w: estimated wait %s", ErrWaitTooLong, backlog.EstimatedWait.Round(time.Second)),
+			RetryAfter: backlog.EstimatedWait - h.admission.MaxEstimatedWait,
+		}
+	default:
+		return nil
+	}
+
+	log.WithError(err).
+		WithField("pending", backlog.Pending).
+		WithField("retryAfter", err.RetryAfter).
+		Warn("certificate refused by admission control")
+	return err
+}
+
+// setRetryAfter sets the Retry-After header of the response to the delay of a *RetryableError.
+func setRetryAfter(c echo.Context, err error) {
+	if seconds, ok := retryAfterSeconds(err); ok {
+		c.Response().Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	}
+}
+
+// retryAfterSeconds returns the delay of a *RetryableError in seconds, rounded up to at least one.
+func retryAfterSeconds(err error) (int64, bool) {
+	retryable, ok := asRetryable(err)
+	if !ok {
+		return 0, false
+	}
+	return max(int64(math.Ceil(retryable.RetryAfter.Seconds())), 1), true
+}
@@ -1,17 +1,148 @@
 package api
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/policy"
+	"github.com/swissborg/galactica-kyc-guardian/internal/zkcert"
+)
 
 var (
-	ErrParsReq            = fmt.Errorf("parsing request failed")
-	ErrParsCommitment     = fmt.Errorf("parsing commitment hash failed")
-	ErrValidateCommitment = fmt.Errorf("validating holder commitment failed")
-	ErrParsDate           = fmt.Errorf("parsing profile date failed")
-	ErrParsNationality    = fmt.Errorf("parsing profile nationality failed")
-	ErrCertGenerating     = fmt.Errorf("generating cert failed")
-	ErrCertNotFound       = fmt.Errorf("certificate not found")
-	ErrReadCertStatus     = fmt.Errorf("reading cert status failed")
-	ErrAddCertToQueue     = fmt.Errorf("adding cert to queue failed")
-	ErrAddCertToDB        = fmt.Errorf("adding cert to DB failed")
-	ErrDecodePubKey       = fmt.Errorf("decode pub key string failed")
+	ErrParsReq             = fmt.Errorf("parsing request failed")
+	ErrValidateReq         = fmt.Errorf("validating request failed")
+	ErrParsCommitment      = fmt.Errorf("parsing commitment hash failed")
+	ErrValidateCommitment  = fmt.Errorf("validating holder commitment failed")
+	ErrParsDate            = fmt.Errorf("parsing profile date failed")
+	ErrParsNationality     = fmt.Errorf("parsing profile nationality failed")
+	ErrCertGenerating      = fmt.Errorf("generating cert failed")
+	ErrCertNotFound        = fmt.Errorf("certificate not found")
+	ErrReadCertStatus      = fmt.Errorf("reading cert status failed")
+	ErrAddCertToQueue      = fmt.Errorf("adding cert to queue failed")
+	ErrAddCertToDB         = fmt.Errorf("adding cert to DB failed")
+	ErrDecodePubKey        = fmt.Errorf("decode pub key string failed")
+	ErrDeleteCert          = fmt.Errorf("deleting cert failed")
+	ErrPolicyRejected      = fmt.Errorf("profile rejected by issuance policy")
+	ErrDuplicateIdentity   = fmt.Errorf("identity or holder commitment already used by another user")
+	ErrCheckIdentity       = fmt.Errorf("checking duplicate identity failed")
+	ErrGuardianInfo        = fmt.Errorf("reading guardian info failed")
+	ErrUnsupportedStandard = fmt.Errorf("certificate standard not supported by the guardian")
+	ErrIssuanceFailed      = fmt.Errorf("issuance failed")
+	ErrRevocationFailed    = fmt.Errorf("revocation failed, the certificate is still issued")
+	ErrReadSpend           = fmt.Errorf("reading spend records failed")
+	ErrQueueFull           = fmt.Errorf("issuance queue full, retry later")
+	ErrWaitTooLong         = fmt.Errorf("issuance queue too slow, retry later")
+	ErrRateLimited         = fmt.Errorf("rate limit exceeded")
 )
+
+// badRequestErrs are the errors caused by an invalid request
+var badRequestErrs = []error{
+	ErrParsReq,
+	ErrValidateReq,
+	ErrParsCommitment,
+	ErrValidateCommitment,
+	ErrParsDate,
+	ErrParsNationality,
+	ErrDecodePubKey,
+	zkcert.ErrInvalidInputs,
+}
+
+func isBadRequest(err error) bool {
+	for _, target := range badRequestErrs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// httpStatus maps an error returned by the handlers to an HTTP status code
+func httpStatus(err error) int {
+	switch {
+	case isBadRequest(err):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrPolicyRejected):
+		return http.StatusForbidden
+	case errors.Is(err, ErrDuplicateIdentity):
+		return http.StatusConflict
+	case errors.Is(err, ErrCertNotFound), errors.Is(err, ErrUnsupportedStandard):
+		return http.StatusNotFound
+	case errors.Is(err, ErrWaitTooLong), errors.Is(err, ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrQueueFull):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// grpcCode maps an error returned by the handlers to a gRPC status code
+func grpcCode(err error) codes.Code {
+	switch {
+	case isBadRequest(err):
+		return codes.InvalidArgument
+	case errors.Is(err, ErrPolicyRejected):
+		return codes.FailedPrecondition
+	case errors.Is(err, ErrDuplicateIdentity):
+		return codes.AlreadyExists
+	case errors.Is(err, ErrCertNotFound), errors.Is(err, ErrUnsupportedStandard):
+		return codes.NotFound
+	case errors.Is(err, ErrWaitTooLong), errors.Is(err, ErrRateLimited):
+		return codes.ResourceExhausted
+	case errors.Is(err, ErrQueueFull):
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
+
+// newErrorResp builds the REST error body, including the policy code of a rejection
+func newErrorResp(err error) ErrorResp {
+	resp := ErrorResp{Error: err.Error()}
+
+	var violation *policy.Violation
+	if errors.As(err, &violation) {
+		resp.Code = violation.Code
+	}
+
+	return resp
+}
+
+// asRetryable returns the *RetryableError in the chain of err.
+func asRetryable(err error) (*RetryableError, bool) {
+	var retryable *RetryableError
+	ok := errors.As(err, &retryable)
+	return retryable, ok
+}
+
+// grpcError builds the gRPC status of err, with the policy code of a rejection and the delay of a
+// retryable error as error details
+func grpcError(err error) error {
+	st := status.New(grpcCode(err), err.Error())
+
+	var violation *policy.Violation
+	if errors.As(err, &violation) {
+		if withDetails, detailsErr := st.WithDetails(&errdetails.ErrorInfo{
+			Reason: violation.Code,
+			Domain: "guardian.v1",
+		}); detailsErr == nil {
+			st = withDetails
+		}
+	}
+
+	if retryable, ok := asRetryable(err); ok {
+		if withDetails, detailsErr := st.WithDetails(&errdetails.RetryInfo{
+			RetryDelay: durationpb.New(retryable.RetryAfter),
+		}); detailsErr == nil {
+			st = withDetails
+		}
+	}
+
+	return st.Err()
+}
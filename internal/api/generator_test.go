@@ -0,0 +1,206 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/galactica-corp/guardians-sdk/cmd"
+	"github.com/galactica-corp/guardians-sdk/pkg/merkle"
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+	"github.com/holiman/uint256"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/taskqueue"
+	"github.com/swissborg/galactica-kyc-guardian/internal/zkcert"
+)
+
+// fakeGenerator signs certificates with a random key. Unless issue is set
+// the certificates are never issued, so they stay pending.
+type fakeGenerator struct {
+	signingKey babyjub.PrivateKey
+	retiredKey babyjub.PrivateKey
+	issue      bool
+	// requeue makes the transaction of the first issuance attempt vanish from the chain
+	requeue bool
+	// expire makes the issuance expire in its lane after the first attempt, when requeued
+	expire bool
+	// backlog is the work reported queued for issuance
+	backlog zkcert.Backlog
+	// hold delays the issuances until it is closed, when set
+	hold chan struct{}
+	// issuing tracks the issuances in progress
+	issuing sync.WaitGroup
+}
+
+func newFakeGenerator() *fakeGenerator {
+	return &fakeGenerator{signingKey: babyjub.NewRandPrivKey(), retiredKey: babyjub.NewRandPrivKey()}
+}
+
+func (g *fakeGenerator) CreateZKCert(
+	_ context.Context,
+	holderCommitment zkcertificate.HolderCommitment,
+	inputs zkcert.Inputs[zkcertificate.KYCContent],
+) (*zkcertificate.Certificate[zkcertificate.KYCContent], error) {
+	if err := inputs.Validate(); err != nil {
+		return nil, err
+	}
+
+	content, err := inputs.FFEncode()
+	if err != nil {
+		return nil, err
+	}
+
+	return cmd.CreateZKCert(content, holderCommitment, g.signingKey, time.Now().AddDate(1, 0, 0))
+}
+
+func (g *fakeGenerator) AddZKCertToQueue(
+	_ context.Context,
+	certificate zkcertificate.Certificate[zkcertificate.KYCContent],
+	callback func(zkcertificate.IssuedCertificate[zkcertificate.KYCContent], error),
+) {
+	if !g.issue {
+		return
+	}
+
+	issuedCert := zkcertificate.IssuedCertificate[zkcertificate.KYCContent]{
+		Certificate: certificate,
+		Registration: zkcertificate.RegistrationDetails{
+			ChainID:   big.NewInt(1),
+			Revocable: true,
+			LeafIndex: 1,
+		},
+		MerkleProof: merkle.Proof{
+			Leaf:      merkle.TreeNode{Value: uint256.MustFromBig(certificate.LeafHash.BigInt())},
+			LeafIndex: 1,
+		},
+	}
+
+	g.issuing.Add(1)
+	go func() {
+		defer g.issuing.Done()
+
+		if g.hold != nil {
+			<-g.hold
+		}
+		if g.requeue {
+			callback(zkcertificate.IssuedCertificate[zkcertificate.KYCContent]{}, zkcert.ErrTxVanished)
+			time.Sleep(50 * time.Millisecond)
+		}
+		if g.expire {
+			callback(zkcertificate.IssuedCertificate[zkcertificate.KYCContent]{}, taskqueue.ErrTaskExpired)
+			return
+		}
+		callback(issuedCert, nil)
+	}()
+}
+
+func (g *fakeGenerator) AddRevocationToQueue(
+	_ context.Context,
+	_ zkcertificate.Hash,
+	_ int,
+	callback func(*types.Transaction, error),
+) {
+	if !g.issue {
+		return
+	}
+
+	go callback(types.NewTx(&types.LegacyTx{}), nil)
+}
+
+func (g *fakeGenerator) EncryptZKCert(
+	holderCommitment zkcertificate.HolderCommitment,
+	issuedCert zkcertificate.IssuedCertificate[zkcertificate.KYCContent],
+) (zkcertificate.EncryptedCertificate, error) {
+	return cmd.EncryptZKCert(issuedCert, holderCommitment)
+}
+
+func (g *fakeGenerator) Info(context.Context) (zkcert.Info, error) {
+	return zkcert.Info{
+		ProviderAddress:          common.HexToAddress("0x20682CE367cE2cA50bD255b03fEc2bd08Cc1c8Bd"),
+		SigningPublicKey:         g.signingKey.Public(),
+		RetiredSigningPublicKeys: []*babyjub.PublicKey{g.retiredKey.Public()},
+		RegistryAddress:          common.HexToAddress("0x68272A56A0e9b095E5606fDD8b6c297702C0dfe5"),
+		ChainID:                  big.NewInt(9302),
+		Standards:                []zkcertificate.Standard{zkcertificate.StandardKYC},
+		Registries: map[zkcertificate.Standard]common.Address{
+			zkcertificate.StandardKYC: common.HexToAddress("0x68272A56A0e9b095E5606fDD8b6c297702C0dfe5"),
+		},
+	}, nil
+}
+
+// QueueStatus reports the certificates that are never issued running, second in the registry queue.
+func (g *fakeGenerator) QueueStatus(context.Context, zkcertificate.Hash) (zkcert.QueueStatus, error) {
+	if g.issue {
+		return zkcert.QueueStatus{}, zkcert.ErrNotQueued
+	}
+
+	return zkcert.QueueStatus{
+		Running:             true,
+		Registry:            &zkcert.RegistryQueue{Index: 7, Ahead: 1},
+		EstimatedCompletion: time.Now().Add(time.Minute),
+	}, nil
+}
+
+func (g *fakeGenerator) Backlog() zkcert.Backlog {
+	return g.backlog
+}
+
+// fakeCEXGenerator creates CEX certificates from their JSON inputs. Unless issue is set
+// the certificates are never issued, so they stay pending.
+type fakeCEXGenerator struct {
+	signingKey babyjub.PrivateKey
+	issue      bool
+}
+
+func newFakeCEXGenerator() *fakeCEXGenerator {
+	return &fakeCEXGenerator{signingKey: babyjub.NewRandPrivKey()}
+}
+
+func (g *fakeCEXGenerator) Standard() zkcertificate.Standard {
+	return zkcertificate.StandardCEX
+}
+
+func (g *fakeCEXGenerator) GenerateZKCert(
+	_ context.Context,
+	holderCommitment zkcertificate.HolderCommitment,
+	raw json.RawMessage,
+	callback func(zkcertificate.EncryptedCertificate, error),
+) (zkcertificate.Hash, error) {
+	var inputs zkcertificate.CEXInputs
+	if err := json.Unmarshal(raw, &inputs); err != nil {
+		return zkcertificate.Hash{}, fmt.Errorf("%w: %v", zkcert.ErrInvalidInputs, err)
+	}
+
+	content, err := inputs.FFEncode()
+	if err != nil {
+		return zkcertificate.Hash{}, err
+	}
+
+	cert, err := cmd.CreateZKCert(content, holderCommitment, g.signingKey, time.Now().AddDate(1, 0, 0))
+	if err != nil {
+		return zkcertificate.Hash{}, err
+	}
+
+	if g.issue {
+		go callback(cmd.EncryptZKCert(zkcertificate.IssuedCertificate[zkcertificate.CEXContent]{
+			Certificate: *cert,
+			Registration: zkcertificate.RegistrationDetails{
+				ChainID:   big.NewInt(1),
+				Revocable: true,
+				LeafIndex: 1,
+			},
+			MerkleProof: merkle.Proof{
+				Leaf:      merkle.TreeNode{Value: uint256.MustFromBig(cert.LeafHash.BigInt())},
+				LeafIndex: 1,
+			},
+		}, holderCommitment))
+	}
+
+	return cert.ContentHash, nil
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	guardianv1 "github.com/swissborg/galactica-kyc-guardian/gen/guardian/v1"
+)
+
+// GRPCServer implements guardianv1.GuardianServiceServer on top of the same Handlers as the REST API.
+type GRPCServer struct {
+	guardianv1.UnimplementedGuardianServiceServer
+	handlers *Handlers
+}
+
+func NewGRPCServer(handlers *Handlers) *GRPCServer {
+	return &GRPCServer{handlers: handlers}
+}
+
+// Register registers the guardian service on the gRPC server.
+func (s *GRPCServer) Register(server *grpc.Server) {
+	guardianv1.RegisterGuardianServiceServer(server, s)
+}
+
+func (s *GRPCServer) GenerateCertificate(
+	ctx context.Context,
+	req *guardianv1.GenerateCertificateRequest,
+) (*guardianv1.GenerateCertificateResponse, error) {
+	profile := req.GetProfile()
+	resp, err := s.handlers.generateCert(ctx, GenerateCertRequest{
+		HolderCommitment: req.GetHolderCommitment(),
+		EncryptionPubKey: req.GetEncryptionPubKey(),
+		UserID:           UserID(req.GetUserId()),
+		Profile: Profile{
+			Firstname:   profile.GetFirstname(),
+			Lastname:    profile.GetLastname(),
+			DateOfBirth: profile.GetDateOfBirth(),
+			Nationality: profile.GetNationality(),
+			Postcode:    profile.GetPostcode(),
+		},
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &guardianv1.GenerateCertificateResponse{Status: toProtoStatus(resp.Status)}, nil
+}
+
+func (s *GRPCServer) GetCertificate(
+	_ context.Context,
+	req *guardianv1.GetCertificateRequest,
+) (*guardianv1.GetCertificateResponse, error) {
+	resp, err := s.handlers.getCert(UserID(req.GetUserId()))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return toProtoCertificate(resp), nil
+}
+
+func (s *GRPCServer) WatchCertificate(
+	req *guardianv1.WatchCertificateRequest,
+	stream guardianv1.GuardianService_WatchCertificateServer,
+) error {
+	ctx := stream.Context()
+
+	err := s.handlers.watchCert(ctx, UserID(req.GetUserId()), func(resp GetCertResponse) error {
+		return stream.Send(toProtoCertificate(resp))
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		return grpcError(err)
+	}
+
+	return nil
+}
+
+func (s *GRPCServer) RevokeCertificate(
+	_ context.Context,
+	req *guardianv1.RevokeCertificateRequest,
+) (*guardianv1.RevokeCertificateResponse, error) {
+	certStatus, err := s.handlers.revokeCert(UserID(req.GetUserId()))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return &guardianv1.RevokeCertificateResponse{Status: toProtoStatus(certStatus)}, nil
+}
+
+func toProtoCertificate(resp GetCertResponse) *guardianv1.GetCertificateResponse {
+	return &guardianv1.GetCertificateResponse{
+		Status:      toProtoStatus(resp.Status),
+		Certificate: string(resp.Certificate),
+	}
+}
+
+func toProtoStatus(s CertificateStatus) guardianv1.CertificateStatus {
+	switch s {
+	case CertificateStatusPending:
+		return guardianv1.CertificateStatus_CERTIFICATE_STATUS_PENDING
+	case CertificateStatusDone:
+		return guardianv1.CertificateStatus_CERTIFICATE_STATUS_DONE
+	case CertificateStatusRevoking:
+		return guardianv1.CertificateStatus_CERTIFICATE_STATUS_REVOKING
+	case CertificateStatusRevoked:
+		return guardianv1.CertificateStatus_CERTIFICATE_STATUS_REVOKED
+	default:
+		return guardianv1.CertificateStatus_CERTIFICATE_STATUS_UNSPECIFIED
+	}
+}
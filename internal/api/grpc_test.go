@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	guardianv1 "github.com/swissborg/galactica-kyc-guardian/gen/guardian/v1"
+)
+
+func newGRPCClient(t *testing.T, generator CertGenerator) guardianv1.GuardianServiceClient {
+	t.Helper()
+
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("open badger: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	lis := bufconn.Listen(1024 * 1024)
+	server := NewServer(generator, db).makeGRPC()
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial bufconn: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return guardianv1.NewGuardianServiceClient(conn)
+}
+
+func generateCertificateRequest(userID string) *guardianv1.GenerateCertificateRequest {
+	return &guardianv1.GenerateCertificateRequest{
+		HolderCommitment: "4586425042444163335895417167611444541749813513569901646582116352074512113476",
+		EncryptionPubKey: "OEotdsfEuoiqM7ob2KJEQemhWodn87hZNFv890q4xGw=",
+		UserId:           userID,
+		Profile: &guardianv1.Profile{
+			Firstname:   "Bob",
+			Lastname:    "Norman",
+			DateOfBirth: "2006-01-02",
+			Nationality: "CH",
+			Postcode:    "1006",
+		},
+	}
+}
+
+func TestGRPCGenerateAndGetCertificate(t *testing.T) {
+	client := newGRPCClient(t, newFakeGenerator())
+	ctx := context.Background()
+
+	_, err := client.GetCertificate(ctx, &guardianv1.GetCertificateRequest{UserId: "12345"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("get before generate: expected %s, got %v", codes.NotFound, err)
+	}
+
+	genResp, err := client.GenerateCertificate(ctx, generateCertificateRequest("12345"))
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if genResp.GetStatus() != guardianv1.CertificateStatus_CERTIFICATE_STATUS_PENDING {
+		t.Errorf("generate: expected pending, got %s", genResp.GetStatus())
+	}
+
+	getResp, err := client.GetCertificate(ctx, &guardianv1.GetCertificateRequest{UserId: "12345"})
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if getResp.GetStatus() != guardianv1.CertificateStatus_CERTIFICATE_STATUS_PENDING {
+		t.Errorf("get: expected pending, got %s", getResp.GetStatus())
+	}
+}
+
+func TestGRPCGenerateCertificateInvalidArgument(t *testing.T) {
+	client := newGRPCClient(t, newFakeGenerator())
+
+	req := generateCertificateRequest("12345")
+	req.HolderCommitment = "not a number"
+
+	_, err := client.GenerateCertificate(context.Background(), req)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected %s, got %v", codes.InvalidArgument, err)
+	}
+
+	req = generateCertificateRequest("")
+	_, err = client.GenerateCertificate(context.Background(), req)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("missing user id: expected %s, got %v", codes.InvalidArgument, err)
+	}
+}
+
+func TestGRPCWatchAndRevokeCertificate(t *testing.T) {
+	originalInterval := watchPollInterval
+	watchPollInterval = 10 * time.Millisecond
+	defer func() {
+		watchPollInterval = originalInterval
+	}()
+
+	generator := newFakeGenerator()
+	generator.issue = true
+	client := newGRPCClient(t, generator)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := client.GenerateCertificate(ctx, generateCertificateRequest("12345")); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	stream, err := client.WatchCertificate(ctx, &guardianv1.WatchCertificateRequest{UserId: "12345"})
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+
+	var last *guardianv1.GetCertificateResponse
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		last = resp
+	}
+
+	if last.GetStatus() != guardianv1.CertificateStatus_CERTIFICATE_STATUS_DONE {
+		t.Fatalf("watch: expected last status done, got %s", last.GetStatus())
+	}
+	if last.GetCertificate() == "" {
+		t.Errorf("watch: expected the encrypted certificate once done")
+	}
+
+	revokeResp, err := client.RevokeCertificate(ctx, &guardianv1.RevokeCertificateRequest{UserId: "12345"})
+	if err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if revokeResp.GetStatus() != guardianv1.CertificateStatus_CERTIFICATE_STATUS_REVOKING {
+		t.Errorf("revoke: expected revoking, got %s", revokeResp.GetStatus())
+	}
+
+	stream, err = client.WatchCertificate(ctx, &guardianv1.WatchCertificateRequest{UserId: "12345"})
+	if err != nil {
+		t.Fatalf("watch revocation: %v", err)
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		last = resp
+	}
+
+	if last.GetStatus() != guardianv1.CertificateStatus_CERTIFICATE_STATUS_REVOKED {
+		t.Errorf("watch revocation: expected last status revoked, got %s", last.GetStatus())
+	}
+}
+
+func TestGRPCRevokeUnknownCertificate(t *testing.T) {
+	client := newGRPCClient(t, newFakeGenerator())
+
+	_, err := client.RevokeCertificate(context.Background(), &guardianv1.RevokeCertificateRequest{UserId: "unknown"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected %s, got %v", codes.NotFound, err)
+	}
+}
@@ -3,32 +3,129 @@ package api
 import (
 	"context"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/biter777/countries"
 	"github.com/dgraph-io/badger/v4"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+	"github.com/go-playground/validator/v10"
+	"github.com/iden3/go-iden3-crypto/babyjub"
 	"github.com/labstack/echo/v4"
 	log "github.com/sirupsen/logrus"
 	"github.com/stasundr/decimal"
 
+	"github.com/swissborg/galactica-kyc-guardian/internal/keys"
+	"github.com/swissborg/galactica-kyc-guardian/internal/policy"
+	"github.com/swissborg/galactica-kyc-guardian/internal/ratelimit"
+	"github.com/swissborg/galactica-kyc-guardian/internal/sybil"
+	"github.com/swissborg/galactica-kyc-guardian/internal/version"
 	"github.com/swissborg/galactica-kyc-guardian/internal/zkcert"
 )
 
+// watchPollInterval is how often WatchCertificate checks for a status change
+var watchPollInterval = time.Second
+
+// CertGenerator creates zk certificates, issues them on-chain and encrypts them for the holder.
+// It is implemented by the zkcert.Service of the KYC standard.
+type CertGenerator interface {
+	CreateZKCert(
+		ctx context.Context,
+		holderCommitment zkcertificate.HolderCommitment,
+		inputs zkcert.Inputs[zkcertificate.KYCContent],
+	) (*zkcertificate.Certificate[zkcertificate.KYCContent], error)
+	AddZKCertToQueue(
+		ctx context.Context,
+		certificate zkcertificate.Certificate[zkcertificate.KYCContent],
+		callback func(zkcertificate.IssuedCertificate[zkcertificate.KYCContent], error),
+	)
+	AddRevocationToQueue(
+		ctx context.Context,
+		leafHash zkcertificate.Hash,
+		leafIndex int,
+		callback func(*types.Transaction, error),
+	)
+	EncryptZKCert(
+		holderCommitment zkcertificate.HolderCommitment,
+		issuedCert zkcertificate.IssuedCertificate[zkcertificate.KYCContent],
+	) (zkcertificate.EncryptedCertificate, error)
+	Info(ctx context.Context) (zkcert.Info, error)
+	// QueueStatus returns where the issuance of the certificate of leafHash stands, zkcert.ErrNotQueued
+	// when it is not queued
+	QueueStatus(ctx context.Context, leafHash zkcertificate.Hash) (zkcert.QueueStatus, error)
+	// Backlog returns the work queued for issuance, the admission control refuses new certificates from it
+	Backlog() zkcert.Backlog
+}
+
+var _ CertGenerator = (*zkcert.Service[zkcertificate.KYCContent])(nil)
+
+// Handlers implements the certificate operations shared by the REST and gRPC APIs.
 type Handlers struct {
 	inMem     *badger.DB
-	generator *zkcert.Service
+	generator CertGenerator
+	validator *validator.Validate
+	policy    *policy.Engine
+	sybil     *sybil.Detector
+	standards map[zkcertificate.Standard]zkcert.JSONService
+	admission Admission
+	limiter   *ratelimit.Limiter
+	// rateLimits are the limits of the routes, by route
+	rateLimits map[string][]RateLimit
+
+	// queuedMu guards queued, the leaf hashes of the certificates of the users queued for issuance
+	queuedMu sync.Mutex
+	queued   map[UserID]zkcertificate.Hash
+}
+
+// Option configures an optional dependency of the Handlers
+type Option func(*Handlers)
+
+// WithPolicy evaluates the issuance policy on every profile before creating its certificate.
+func WithPolicy(engine *policy.Engine) Option {
+	return func(h *Handlers) {
+		h.policy = engine
+	}
 }
 
-func NewHandlers(generator *zkcert.Service,
-	mem *badger.DB) *Handlers {
-	return &Handlers{
+// WithSybilDetector checks every profile and holder commitment against the ones of other users
+// before creating its certificate.
+func WithSybilDetector(detector *sybil.Detector) Option {
+	return func(h *Handlers) {
+		h.sybil = detector
+	}
+}
+
+// WithStandards issues certificates of the standards of the services from JSON inputs,
+// in addition to the KYC certificates.
+func WithStandards(services ...zkcert.JSONService) Option {
+	return func(h *Handlers) {
+		for _, service := range services {
+			h.standards[service.Standard()] = service
+		}
+	}
+}
+
+func NewHandlers(generator CertGenerator,
+	mem *badger.DB, opts ...Option) *Handlers {
+	h := &Handlers{
 		inMem:     mem,
 		generator: generator,
+		validator: validator.New(),
+		standards: make(map[zkcertificate.Standard]zkcert.JSONService),
+		queued:    make(map[UserID]zkcertificate.Hash),
+
+		rateLimits: make(map[string][]RateLimit),
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 func (h *Handlers) GenerateCert(c echo.Context) error {
@@ -41,73 +138,86 @@ func (h *Handlers) GenerateCert(c echo.Context) error {
 		})
 	}
 
+	resp, err := h.generateCert(c.Request().Context(), req)
+	if err != nil {
+		setRetryAfter(c, err)
+		return c.JSON(httpStatus(err), newErrorResp(err))
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handlers) generateCert(ctx context.Context, req GenerateCertRequest) (GenerateCertResponse, error) {
 	log.
 		WithField("holderCommitment", req.HolderCommitment).
 		WithField("userID", req.UserID).
 		Info("request")
 
-	if err := c.Validate(req); err != nil {
+	if err := h.validator.Struct(req); err != nil {
 		log.WithError(err).Error("validate gen cert request")
-		return c.JSON(http.StatusBadRequest, ErrorResp{
-			Error: err.Error(),
-		})
+		return GenerateCertResponse{}, fmt.Errorf("%v: %w", err, ErrValidateReq)
 	}
 
-	var holderCommitment zkcertificate.HolderCommitment
-	dec, ok := decimal.NewDecimalFromString(req.HolderCommitment)
-	if !ok {
-		log.Errorf("%s: %s", ErrParsCommitment.Error(), req.HolderCommitment)
-		return c.JSON(http.StatusBadRequest, ErrorResp{
-			Error: ErrParsCommitment.Error(),
-		})
+	if err := h.admit(); err != nil {
+		return GenerateCertResponse{}, err
 	}
 
-	holderCommitment.CommitmentHash = zkcertificate.HashFromBigInt(dec.ToBig())
-
-	decoded, err := base64.StdEncoding.DecodeString(req.EncryptionPubKey)
+	holderCommitment, err := parseHolderCommitment(req.HolderCommitment, req.EncryptionPubKey)
 	if err != nil {
-		log.WithError(err).Errorf("%s: %s", ErrDecodePubKey.Error(), req.EncryptionPubKey)
-		return c.JSON(http.StatusBadRequest, ErrorResp{
-			Error: fmt.Sprintf("%v: %v", err, ErrDecodePubKey),
-		})
+		return GenerateCertResponse{}, err
 	}
-	holderCommitment.EncryptionKey = decoded
 
-	if err := holderCommitment.Validate(); err != nil {
-		log.WithError(err).Errorf("%s: %s", ErrValidateCommitment.Error(), holderCommitment)
-		return c.JSON(http.StatusBadRequest, ErrorResp{
-			Error: fmt.Sprintf("%v: %v", err, ErrValidateCommitment),
-		})
+	d, err := req.Profile.dateOfBirth()
+	if err != nil {
+		log.WithError(err).Error(ErrParsDate)
+		return GenerateCertResponse{}, err
 	}
 
-	log.Info("holder commitment validated")
-
-	d, err := time.Parse(time.DateOnly, req.Profile.DateOfBirth)
+	inputs, err := req.Profile.kycInputs(d)
 	if err != nil {
-		log.WithError(err).Error(ErrParsDate)
-		return c.JSON(http.StatusBadRequest, ErrorResp{
-			Error: fmt.Sprintf("%v: %v", err, ErrParsReq),
-		})
+		log.WithError(err).Error(ErrParsNationality)
+		return GenerateCertResponse{}, err
 	}
+	code := inputs.Citizenship
 
-	code := countries.ByName(req.Profile.Nationality).Alpha3()
-	inputs := zkcertificate.KYCInputs{
-		Surname:      req.Profile.Firstname,
-		Forename:     req.Profile.Lastname,
-		YearOfBirth:  uint16(d.Year()),
-		MonthOfBirth: uint8(d.Month()),
-		DayOfBirth:   uint8(d.Day()),
-		Citizenship:  code,
-		Postcode:     req.Profile.Postcode,
-		Country:      code,
+	subject := policy.Subject{
+		DateOfBirth: d,
+		Citizenship: inputs.Citizenship,
+	}
+	if err := h.policy.Evaluate(subject, time.Now()); err != nil {
+		log.WithError(err).WithField("userID", req.UserID).Warn(ErrPolicyRejected)
+		return GenerateCertResponse{}, fmt.Errorf("%w: %w", ErrPolicyRejected, err)
+	}
+
+	if h.sybil != nil {
+		identity := sybil.Identity{
+			Firstname:   req.Profile.Firstname,
+			Lastname:    req.Profile.Lastname,
+			DateOfBirth: d,
+			Nationality: code,
+		}
+		decision, err := h.sybil.Check(string(req.UserID), identity, holderCommitment.CommitmentHash.String())
+		if errors.Is(err, sybil.ErrDuplicateIdentity) {
+			log.WithField("userID", req.UserID).
+				WithField("matches", decision.Matches).
+				Warn(ErrDuplicateIdentity)
+			return GenerateCertResponse{}, ErrDuplicateIdentity
+		}
+		if err != nil {
+			log.WithError(err).Error(ErrCheckIdentity)
+			return GenerateCertResponse{}, fmt.Errorf("%v: %w", err, ErrCheckIdentity)
+		}
+		if decision.Outcome == sybil.OutcomeWarn {
+			log.WithField("userID", req.UserID).
+				WithField("matches", decision.Matches).
+				Warn("duplicate identity detected, issuing anyway")
+		}
 	}
 
-	cert, err := h.generator.CreateZKCert(holderCommitment, inputs)
+	cert, err := h.generator.CreateZKCert(ctx, holderCommitment, &inputs)
 	if err != nil {
 		log.WithError(err).Error(ErrCertGenerating)
-		return c.JSON(http.StatusInternalServerError, ErrorResp{
-			Error: fmt.Sprintf("%v: %v", err, ErrCertGenerating),
-		})
+		return GenerateCertResponse{}, fmt.Errorf("%v: %w", err, ErrCertGenerating)
 	}
 
 	hc := stripToSix(cert.HolderCommitment)
@@ -119,59 +229,152 @@ func (h *Handlers) GenerateCert(c echo.Context) error {
 		Info("cert created")
 
 	callback := func(issuedCert zkcertificate.IssuedCertificate[zkcertificate.KYCContent], err error) {
+		if errors.Is(err, zkcert.ErrTxVanished) {
+			// the issuance is queued again, the certificate stays pending
+			log.WithError(err).WithField("userID", req.UserID).Warn("cert issuance requeued")
+			return
+		}
 		if err != nil {
 			log.WithError(err).Error("cert issuance")
 
-			if err := deleteUserDataFromDB(h.inMem, req.UserID); err != nil {
-				log.WithError(err).Error("clean up db after cert issuance")
-			}
+			h.completeQueued(req.UserID, cert.LeafHash, func() {
+				if err := deleteUserDataFromDB(h.inMem, req.UserID); err != nil {
+					log.WithError(err).Error("clean up db after cert issuance")
+				}
+			})
 			return
 		}
 
+		signingKeyID := keys.SigningKeyID(&issuedCert.Provider.PublicKey)
+
 		log.WithField("holderCommitment", hc).
 			WithField("userID", req.UserID).
+			WithField("signingKeyID", signingKeyID).
 			Info("certificate issued")
 
-		encryptedCert, err := h.generator.EncryptZKCert(holderCommitment, issuedCert)
-		if err != nil {
-			log.WithError(err).Error("encrypting cert")
-			return
+		if err := addIssuanceToDB(h.inMem, req.UserID, Issuance{
+			LeafHash:     issuedCert.LeafHash,
+			LeafIndex:    issuedCert.Registration.LeafIndex,
+			Status:       CertificateStatusDone,
+			SigningKeyID: signingKeyID,
+		}); err != nil {
+			log.WithError(err).Error("adding issuance to db")
 		}
 
-		log.WithField("holderCommitment", hc).
-			WithField("userID", req.UserID).
-			Info("cert encrypted")
+		h.completeQueued(req.UserID, cert.LeafHash, func() {
+			encryptedCert, err := h.generator.EncryptZKCert(holderCommitment, issuedCert)
+			if err != nil {
+				log.WithError(err).Error("encrypting cert")
+				return
+			}
 
-		b, err := json.Marshal(encryptedCert)
-		if err != nil {
-			log.WithError(err).Error("marshaling cert")
-			return
-		}
-		if err = addCertToDB(h.inMem, req.UserID, b); err != nil {
-			log.WithError(err)
-			return
-		}
+			log.WithField("holderCommitment", hc).
+				WithField("userID", req.UserID).
+				Info("cert encrypted")
 
-		log.WithField("holderCommitment", hc).
-			WithField("userID", req.UserID).
-			Info("certificate added to db")
-	}
+			b, err := json.Marshal(encryptedCert)
+			if err != nil {
+				log.WithError(err).Error("marshaling cert")
+				return
+			}
+			if err = addCertToDB(h.inMem, req.UserID, b); err != nil {
+				log.WithError(err)
+				return
+			}
 
-	h.generator.AddZKCertToQueue(context.Background(), *cert, callback)
+			log.WithField("holderCommitment", hc).
+				WithField("userID", req.UserID).
+				Info("certificate added to db")
+		})
+	}
 
 	// set nil cert to userID key means
 	// that certificate status is pending
 	err = addCertToDB(h.inMem, req.UserID, nil)
 	if err != nil {
 		log.WithError(err).Error(ErrAddCertToDB)
-		return c.JSON(http.StatusInternalServerError, ErrorResp{
-			Error: fmt.Sprintf("%v: %v", err, ErrAddCertToDB),
-		})
+		return GenerateCertResponse{}, fmt.Errorf("%v: %w", err, ErrAddCertToDB)
 	}
 
-	return c.JSON(http.StatusOK, GenerateCertResponse{
+	h.setQueued(req.UserID, cert.LeafHash)
+	h.generator.AddZKCertToQueue(context.Background(), *cert, callback)
+
+	return GenerateCertResponse{
 		Status: CertificateStatusPending,
-	})
+	}, nil
+}
+
+// KYCInputs returns the holder commitment and the KYC inputs the certificate of the request is created from.
+func (req GenerateCertRequest) KYCInputs() (zkcertificate.HolderCommitment, zkcertificate.KYCInputs, error) {
+	holderCommitment, err := parseHolderCommitment(req.HolderCommitment, req.EncryptionPubKey)
+	if err != nil {
+		return holderCommitment, zkcertificate.KYCInputs{}, err
+	}
+
+	d, err := req.Profile.dateOfBirth()
+	if err != nil {
+		return holderCommitment, zkcertificate.KYCInputs{}, err
+	}
+
+	inputs, err := req.Profile.kycInputs(d)
+	return holderCommitment, inputs, err
+}
+
+func (p Profile) dateOfBirth() (time.Time, error) {
+	d, err := time.Parse(time.DateOnly, p.DateOfBirth)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%v: %w", err, ErrParsReq)
+	}
+	return d, nil
+}
+
+// kycInputs maps the profile to the KYC inputs, the nationality is both the citizenship and the country.
+// The nationality must name a country: an unknown one would be encoded as no country at all.
+func (p Profile) kycInputs(dateOfBirth time.Time) (zkcertificate.KYCInputs, error) {
+	country := countries.ByName(p.Nationality)
+	if !country.IsValid() {
+		return zkcertificate.KYCInputs{}, fmt.Errorf("unknown country %q: %w", p.Nationality, ErrParsNationality)
+	}
+
+	code := country.Alpha3()
+	return zkcertificate.KYCInputs{
+		Surname:      p.Firstname,
+		Forename:     p.Lastname,
+		YearOfBirth:  uint16(dateOfBirth.Year()),
+		MonthOfBirth: uint8(dateOfBirth.Month()),
+		DayOfBirth:   uint8(dateOfBirth.Day()),
+		Citizenship:  code,
+		Postcode:     p.Postcode,
+		Country:      code,
+	}, nil
+}
+
+// parseHolderCommitment parses and validates the holder commitment and encryption key of a generate request.
+func parseHolderCommitment(commitment, encryptionPubKey string) (zkcertificate.HolderCommitment, error) {
+	var holderCommitment zkcertificate.HolderCommitment
+	dec, ok := decimal.NewDecimalFromString(commitment)
+	if !ok {
+		log.Errorf("%s: %s", ErrParsCommitment.Error(), commitment)
+		return holderCommitment, ErrParsCommitment
+	}
+
+	holderCommitment.CommitmentHash = zkcertificate.HashFromBigInt(dec.ToBig())
+
+	decoded, err := base64.StdEncoding.DecodeString(encryptionPubKey)
+	if err != nil {
+		log.WithError(err).Errorf("%s: %s", ErrDecodePubKey.Error(), encryptionPubKey)
+		return holderCommitment, fmt.Errorf("%v: %w", err, ErrDecodePubKey)
+	}
+	holderCommitment.EncryptionKey = decoded
+
+	if err := holderCommitment.Validate(); err != nil {
+		log.WithError(err).Errorf("%s: %s", ErrValidateCommitment.Error(), holderCommitment)
+		return holderCommitment, fmt.Errorf("%v: %w", err, ErrValidateCommitment)
+	}
+
+	log.Info("holder commitment validated")
+
+	return holderCommitment, nil
 }
 
 func (h *Handlers) GetCert(c echo.Context) error {
@@ -184,37 +387,291 @@ func (h *Handlers) GetCert(c echo.Context) error {
 		})
 	}
 
+	return h.respondCert(c, req.UserID)
+}
+
+// GetCertificate is the GET /v1/certificates/:user_id variant of GetCert.
+func (h *Handlers) GetCertificate(c echo.Context) error {
+	return h.respondCert(c, UserID(c.Param("user_id")))
+}
+
+func (h *Handlers) respondCert(c echo.Context, userID UserID) error {
 	log.
-		WithField("userID", req.UserID).
+		WithField("userID", userID).
 		Info("request")
 
-	certificate, err := readCertFromDB(h.inMem, req.UserID)
+	resp, err := h.getCert(userID)
+	if err != nil {
+		return c.JSON(httpStatus(err), newErrorResp(err))
+	}
+	if resp.Status == CertificateStatusPending {
+		resp.Queue = h.queueStatus(c.Request().Context(), userID)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// setQueued records the leaf hash of the certificate of the user queued for issuance, until completeQueued or
+// DeleteCertificate forgets it.
+func (h *Handlers) setQueued(userID UserID, leafHash zkcertificate.Hash) {
+	h.queuedMu.Lock()
+	defer h.queuedMu.Unlock()
+
+	h.queued[userID] = leafHash
+}
+
+// completeQueued runs store, which writes the outcome of the issuance of the certificate of leafHash queued for
+// the user, and forgets the issuance. store is skipped when the certificate was deleted or replaced by a newer
+// request in the meantime, so that the outcome does not bring back a deleted certificate.
+func (h *Handlers) completeQueued(userID UserID, leafHash zkcertificate.Hash, store func()) {
+	h.queuedMu.Lock()
+	defer h.queuedMu.Unlock()
+
+	if queued, ok := h.queued[userID]; !ok || queued.Bytes32() != leafHash.Bytes32() {
+		log.WithField("userID", userID).
+			WithField("leafHash", leafHash).
+			Info("certificate deleted while queued, issuance outcome not stored")
+		return
+	}
+	delete(h.queued, userID)
+	store()
+}
+
+// queueStatus returns where the issuance of the certificate of the user stands,
+// nil when it is not queued by this process.
+func (h *Handlers) queueStatus(ctx context.Context, userID UserID) *QueueStatus {
+	h.queuedMu.Lock()
+	leafHash, ok := h.queued[userID]
+	h.queuedMu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	status, err := h.generator.QueueStatus(ctx, leafHash)
+	if errors.Is(err, zkcert.ErrNotQueued) {
+		return nil
+	}
+	if err != nil {
+		// the position in the lane is still reported without the state of the registry queue
+		log.WithError(err).WithField("userID", userID).Warn("read registry queue")
+	}
+
+	return newQueueStatus(status)
+}
+
+func (h *Handlers) getCert(userID UserID) (GetCertResponse, error) {
+	certificate, err := readCertFromDB(h.inMem, userID)
 
 	if err == ErrCertNotFound {
+		// the certificate data expires, but the revocation status is kept
+		if iss, err := readIssuanceFromDB(h.inMem, userID); err == nil && iss.Status != CertificateStatusDone {
+			return GetCertResponse{Status: iss.Status}, nil
+		}
+
 		log.WithError(err).Error(ErrCertNotFound)
-		return c.JSON(http.StatusNotFound, ErrorResp{
-			Error: fmt.Sprintf("%v: %v", ErrCertNotFound, err),
-		})
+		return GetCertResponse{}, fmt.Errorf("%w: %v", ErrCertNotFound, err)
 	}
 
 	if err != nil {
 		log.WithError(err).Error(ErrReadCertStatus)
-		return c.JSON(http.StatusInternalServerError, ErrorResp{
-			Error: fmt.Sprintf("%v: %v", ErrReadCertStatus, err),
-		})
+		return GetCertResponse{}, fmt.Errorf("%w: %v", ErrReadCertStatus, err)
 	}
 
 	// if userID key exists but certificate is empty
 	// means certificate status is pending
 	if certificate == "" {
-		return c.JSON(http.StatusOK, GetCertResponse{
+		return GetCertResponse{
 			Certificate: nil,
 			Status:      CertificateStatusPending,
-		})
+		}, nil
 	}
 
-	return c.JSON(http.StatusOK, GetCertResponse{
+	status := CertificateStatusDone
+	if iss, err := readIssuanceFromDB(h.inMem, userID); err == nil {
+		status = iss.Status
+	}
+
+	return GetCertResponse{
 		Certificate: json.RawMessage(certificate),
-		Status:      CertificateStatusDone,
+		Status:      status,
+	}, nil
+}
+
+// watchCert calls send with the certificate every time its status changes
+// until the certificate reaches a final status or ctx is done.
+func (h *Handlers) watchCert(ctx context.Context, userID UserID, send func(GetCertResponse) error) error {
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+
+	var last CertificateStatus
+	for {
+		resp, err := h.getCert(userID)
+		if err != nil {
+			return err
+		}
+
+		if resp.Status != last {
+			if err := send(resp); err != nil {
+				return err
+			}
+			last = resp.Status
+		}
+
+		if resp.Status == CertificateStatusDone || resp.Status == CertificateStatusRevoked {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// revokeCert queues the on-chain revocation of the certificate issued to the user.
+func (h *Handlers) revokeCert(userID UserID) (CertificateStatus, error) {
+	log.
+		WithField("userID", userID).
+		Info("revoke request")
+
+	iss, err := readIssuanceFromDB(h.inMem, userID)
+	if err != nil {
+		return "", err
+	}
+
+	if iss.Status != CertificateStatusDone {
+		return iss.Status, nil
+	}
+
+	iss.Status = CertificateStatusRevoking
+	if err := addIssuanceToDB(h.inMem, userID, iss); err != nil {
+		return "", fmt.Errorf("%v: %w", err, ErrAddCertToDB)
+	}
+
+	callback := func(tx *types.Transaction, err error) {
+		if err != nil {
+			log.WithError(err).WithField("userID", userID).Error("cert revocation")
+			iss.Status = CertificateStatusDone
+		} else {
+			log.WithField("userID", userID).WithField("tx", tx.Hash()).Info("certificate revoked")
+			iss.Status = CertificateStatusRevoked
+		}
+
+		if err := addIssuanceToDB(h.inMem, userID, iss); err != nil {
+			log.WithError(err).Error("updating issuance after cert revocation")
+		}
+	}
+
+	h.generator.AddRevocationToQueue(context.Background(), iss.LeafHash, iss.LeafIndex, callback)
+
+	return CertificateStatusRevoking, nil
+}
+
+// DeleteCertificate removes the stored certificate or pending status of the user, the issuance of a pending
+// certificate going on without storing it. It does not revoke a certificate issued on-chain.
+func (h *Handlers) DeleteCertificate(c echo.Context) error {
+	userID := UserID(c.Param("user_id"))
+
+	log.
+		WithField("userID", userID).
+		Info("delete request")
+
+	if _, err := readCertFromDB(h.inMem, userID); err != nil {
+		if err == ErrCertNotFound {
+			return c.JSON(http.StatusNotFound, ErrorResp{
+				Error: ErrCertNotFound.Error(),
+			})
+		}
+		log.WithError(err).Error(ErrReadCertStatus)
+		return c.JSON(http.StatusInternalServerError, ErrorResp{
+			Error: fmt.Sprintf("%v: %v", ErrReadCertStatus, err),
+		})
+	}
+
+	// the issuance of a pending certificate is forgotten with it, its outcome is not stored
+	h.queuedMu.Lock()
+	err := deleteUserDataFromDB(h.inMem, userID)
+	if err == nil {
+		delete(h.queued, userID)
+	}
+	h.queuedMu.Unlock()
+	if err != nil {
+		log.WithError(err).Error(ErrDeleteCert)
+		return c.JSON(http.StatusInternalServerError, ErrorResp{
+			Error: fmt.Sprintf("%v: %v", err, ErrDeleteCert),
+		})
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GuardianInfo returns the public identity of the guardian signing the certificates.
+func (h *Handlers) GuardianInfo(c echo.Context) error {
+	info, err := h.generator.Info(c.Request().Context())
+	if err != nil {
+		log.WithError(err).Error(ErrGuardianInfo)
+		return c.JSON(http.StatusInternalServerError, ErrorResp{
+			Error: fmt.Sprintf("%v: %v", err, ErrGuardianInfo),
+		})
+	}
+
+	standards := make([]string, len(info.Standards))
+	for i, standard := range info.Standards {
+		standards[i] = standard.String()
+	}
+
+	registries := make(map[string]string, len(info.Registries))
+	for standard, address := range info.Registries {
+		registries[standard.String()] = address.Hex()
+	}
+
+	var pool []string
+	for _, address := range info.PoolAddresses {
+		pool = append(pool, address.Hex())
+	}
+
+	retired := make([]SigningPublicKey, len(info.RetiredSigningPublicKeys))
+	for i, publicKey := range info.RetiredSigningPublicKeys {
+		retired[i] = newSigningPublicKey(publicKey)
+	}
+
+	return c.JSON(http.StatusOK, GuardianInfoResponse{
+		ProviderAddress:          info.ProviderAddress.Hex(),
+		ProviderPoolAddresses:    pool,
+		SigningPublicKey:         newSigningPublicKey(info.SigningPublicKey),
+		RetiredSigningPublicKeys: retired,
+		RegistryAddress:          info.RegistryAddress.Hex(),
+		ChainID:                  info.ChainID.String(),
+		Standards:                standards,
+		Registries:               registries,
+		Version:                  version.Version,
+		Commit:                   version.Commit,
 	})
 }
+
+func newSigningPublicKey(publicKey *babyjub.PublicKey) SigningPublicKey {
+	compressed := publicKey.Compress()
+
+	return SigningPublicKey{
+		KeyID:      keys.SigningKeyID(publicKey),
+		Ax:         publicKey.X.String(),
+		Ay:         publicKey.Y.String(),
+		Compressed: hex.EncodeToString(compressed[:]),
+	}
+}
+
+func newQueueStatus(status zkcert.QueueStatus) *QueueStatus {
+	resp := &QueueStatus{
+		Position: status.Position,
+		Running:  status.Running,
+	}
+	if status.Registry != nil {
+		resp.Registry = &RegistryQueueStatus{Index: status.Registry.Index, Ahead: status.Registry.Ahead}
+	}
+	if !status.EstimatedCompletion.IsZero() {
+		estimate := status.EstimatedCompletion.UTC().Truncate(time.Second)
+		resp.EstimatedCompletion = &estimate
+	}
+	return resp
+}
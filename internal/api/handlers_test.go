@@ -0,0 +1,83 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/keys"
+	"github.com/swissborg/galactica-kyc-guardian/internal/zkcert"
+)
+
+// TestRequestFixtureCertificate pins the certificate of testdata/request.json signed with the test key
+// testdata/signing.key, as created by "guardian cert create -salt 1 -expiration 1893456000".
+// A change of the hashes means the certificates of the same profiles changed.
+func TestRequestFixtureCertificate(t *testing.T) {
+	data, err := os.ReadFile("testdata/request.json")
+	if err != nil {
+		t.Fatalf("read request: %v", err)
+	}
+
+	var req GenerateCertRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		t.Fatalf("decode request: %v", err)
+	}
+
+	holderCommitment, inputs, err := req.KYCInputs()
+	if err != nil {
+		t.Fatalf("kyc inputs: %v", err)
+	}
+	if inputs.Citizenship != "CHE" || inputs.Country != "CHE" || inputs.YearOfBirth != 2006 {
+		t.Errorf("unexpected inputs %+v", inputs)
+	}
+
+	keyHex, err := os.ReadFile("testdata/signing.key")
+	if err != nil {
+		t.Fatalf("read signing key: %v", err)
+	}
+	signingKey, err := keys.LoadSigningKey(bytes.TrimSpace(keyHex), nil)
+	if err != nil {
+		t.Fatalf("load signing key: %v", err)
+	}
+
+	cert, err := zkcert.CreateOffline[zkcertificate.KYCContent](
+		holderCommitment,
+		&inputs,
+		signingKey,
+		1,
+		time.Unix(1893456000, 0),
+	)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	const (
+		contentHash = "1608899695166753279512372705890320502569094922445842347722298740111750141882"
+		leafHash    = "2968174315043763362707896553387712858543944555019696904560464632669775656035"
+	)
+	if cert.ContentHash.String() != contentHash {
+		t.Errorf("expected content hash %s, got %s", contentHash, cert.ContentHash)
+	}
+	if cert.LeafHash.String() != leafHash {
+		t.Errorf("expected leaf hash %s, got %s", leafHash, cert.LeafHash)
+	}
+}
+
+func TestUnknownNationality(t *testing.T) {
+	for _, nationality := range []string{"", "Narnia"} {
+		profile := Profile{DateOfBirth: "2000-01-01", Nationality: nationality}
+		_, err := profile.kycInputs(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+		if !errors.Is(err, ErrParsNationality) {
+			t.Errorf("expected %v for nationality %q, got %v", ErrParsNationality, nationality, err)
+		}
+		if status := httpStatus(err); status != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, status)
+		}
+	}
+}
@@ -2,15 +2,20 @@ package api
 
 import (
 	"encoding/json"
+	"time"
 )
 
 const (
-	CertificateStatusPending CertificateStatus = "PENDING"
-	CertificateStatusDone    CertificateStatus = "DONE"
+	CertificateStatusPending  CertificateStatus = "PENDING"
+	CertificateStatusDone     CertificateStatus = "DONE"
+	CertificateStatusRevoking CertificateStatus = "REVOKING"
+	CertificateStatusRevoked  CertificateStatus = "REVOKED"
 )
 
 type ErrorResp struct {
 	Error string `json:"error"`
+	// Code is the policy code of a rejected profile
+	Code string `json:"code,omitempty"`
 }
 
 type CertificateStatus string
@@ -23,6 +28,16 @@ type GenerateCertRequest struct {
 	Profile Profile `json:"profile"`
 }
 
+// GenerateStandardCertRequest is the standard-agnostic variant of GenerateCertRequest,
+// Inputs are decoded and validated according to the standard of the route.
+type GenerateStandardCertRequest struct {
+	HolderCommitment string `json:"holder_commitment"`
+	EncryptionPubKey string `json:"encryption_pub_key"`
+
+	UserID UserID          `json:"user_id" validate:"required,lte=64"`
+	Inputs json.RawMessage `json:"inputs" validate:"required"`
+}
+
 type GetCertRequest struct {
 	UserID UserID `json:"user_id"`
 }
@@ -34,6 +49,25 @@ type GenerateCertResponse struct {
 type GetCertResponse struct {
 	Status      CertificateStatus `json:"status"`
 	Certificate json.RawMessage   `json:"certificate"`
+	// Queue tells where the issuance stands while the status is PENDING
+	Queue *QueueStatus `json:"queue,omitempty"`
+}
+
+// QueueStatus is where a pending issuance stands, in the queue of the guardian and in the one of the registry.
+type QueueStatus struct {
+	// Position is the number of tasks ahead of the issuance in the queue of the guardian, 0 once it runs
+	Position int  `json:"position"`
+	Running  bool `json:"running"`
+	// Registry is the state of the certificate in the on-chain queue of the registry, once registered in it
+	Registry *RegistryQueueStatus `json:"registry,omitempty"`
+	// EstimatedCompletion is estimated from the durations of the last issuances, once one is observed
+	EstimatedCompletion *time.Time `json:"estimated_completion,omitempty"`
+}
+
+type RegistryQueueStatus struct {
+	Index uint64 `json:"index"`
+	// Ahead is the number of certificates to add or revoke before the turn of the certificate
+	Ahead uint64 `json:"ahead"`
 }
 
 type UserID string
@@ -45,3 +79,52 @@ type Profile struct {
 	Nationality string `json:"nationality"`
 	Postcode    string `json:"postcode"`
 }
+
+type GuardianInfoResponse struct {
+	// ProviderAddress is the Ethereum address whitelisted in the guardian registry
+	ProviderAddress string `json:"provider_address"`
+	// ProviderPoolAddresses are the additional provider addresses the certificates are issued from
+	ProviderPoolAddresses []string `json:"provider_pool_addresses,omitempty"`
+	// SigningPublicKey is the EdDSA key the certificates are signed with
+	SigningPublicKey SigningPublicKey `json:"signing_public_key"`
+	// RetiredSigningPublicKeys are the keys the certificates were signed with before the last rotations
+	RetiredSigningPublicKeys []SigningPublicKey `json:"retired_signing_public_keys"`
+	RegistryAddress          string             `json:"registry_address"`
+	ChainID                  string             `json:"chain_id"`
+	Standards                []string           `json:"standards"`
+	// Registries maps every supported standard to the registry its certificates are issued to
+	Registries map[string]string `json:"registries"`
+	Version    string            `json:"version"`
+	Commit     string            `json:"commit"`
+}
+
+// SigningPublicKey is a babyjub public key, as found in the providerData of a certificate
+type SigningPublicKey struct {
+	// KeyID identifies the key in the issuance records of the certificates it signed
+	KeyID string `json:"key_id"`
+	Ax    string `json:"ax"`
+	Ay    string `json:"ay"`
+	// Compressed is the hex encoded compressed point
+	Compressed string `json:"compressed"`
+}
+
+type SpendReportResponse struct {
+	From  string     `json:"from"`
+	To    string     `json:"to"`
+	Days  []SpendDay `json:"days"`
+	Total Spend      `json:"total"`
+}
+
+// SpendDay is the spend of a UTC day
+type SpendDay struct {
+	Date string `json:"date"`
+	Spend
+}
+
+type Spend struct {
+	Issuances   int    `json:"issuances"`
+	Revocations int    `json:"revocations"`
+	GasUsed     uint64 `json:"gas_used"`
+	// CostWei is the amount paid for the gas in wei, as a decimal string
+	CostWei string `json:"cost_wei"`
+}
@@ -0,0 +1,22 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// openAPISpec is the OpenAPI 3 document describing every endpoint of the server.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPISpec returns the raw OpenAPI document served at /openapi.json.
+func OpenAPISpec() []byte {
+	return openAPISpec
+}
+
+func getOpenAPISpec(c echo.Context) error {
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJSON, openAPISpec)
+}
@@ -0,0 +1,578 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/labstack/echo/v4"
+
+	"github.com/swissborg/galactica-kyc-guardian/config"
+	"github.com/swissborg/galactica-kyc-guardian/internal/policy"
+	"github.com/swissborg/galactica-kyc-guardian/internal/ratelimit"
+	"github.com/swissborg/galactica-kyc-guardian/internal/zkcert"
+)
+
+func loadOpenAPIRouter(t *testing.T) (*openapi3.T, routers.Router) {
+	t.Helper()
+
+	doc, err := openapi3.NewLoader().LoadFromData(OpenAPISpec())
+	if err != nil {
+		t.Fatalf("load openapi spec: %v", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("validate openapi spec: %v", err)
+	}
+
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		t.Fatalf("create openapi router: %v", err)
+	}
+
+	return doc, router
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// openAPIValidator returns a middleware that reports every request and response
+// that does not conform to the OpenAPI document. Request validation can be disabled
+// to exercise the error responses of deliberately malformed requests.
+func openAPIValidator(t *testing.T, router routers.Router, validateRequest bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			route, pathParams, err := router.FindRoute(req)
+			if err != nil {
+				t.Errorf("%s %s is not described by the openapi spec: %v", req.Method, req.URL.Path, err)
+				return next(c)
+			}
+
+			input := &openapi3filter.RequestValidationInput{
+				Request:    req,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    &openapi3filter.Options{MultiError: true},
+			}
+			if validateRequest {
+				if err := openapi3filter.ValidateRequest(req.Context(), input); err != nil {
+					t.Errorf("%s %s request does not match the openapi spec: %v", req.Method, req.URL.Path, err)
+				}
+			}
+
+			rec := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = rec
+
+			handlerErr := next(c)
+
+			resp := c.Response()
+			err = openapi3filter.ValidateResponse(req.Context(), &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: input,
+				Status:                 resp.Status,
+				Header:                 resp.Header(),
+				Body:                   io.NopCloser(bytes.NewReader(rec.body.Bytes())),
+				Options:                &openapi3filter.Options{MultiError: true, IncludeResponseStatus: true},
+			})
+			if err != nil {
+				t.Errorf("%s %s response %d does not match the openapi spec: %v\n%s",
+					req.Method, req.URL.Path, resp.Status, err, rec.body.String())
+			}
+
+			return handlerErr
+		}
+	}
+}
+
+func newContractServer(t *testing.T, validateRequest bool) *echo.Echo {
+	t.Helper()
+
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("open badger: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, router := loadOpenAPIRouter(t)
+
+	s := NewServer(newFakeGenerator(), db)
+	e := s.makeEcho()
+	e.Use(openAPIValidator(t, router, validateRequest))
+
+	return e
+}
+
+func doJSON(e *echo.Echo, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+// readmeRequestExamples returns the JSON request bodies documented in readme.md keyed by endpoint.
+func readmeRequestExamples(t *testing.T) map[string]string {
+	t.Helper()
+
+	readme, err := os.ReadFile("../../readme.md")
+	if err != nil {
+		t.Fatalf("read readme: %v", err)
+	}
+
+	re := regexp.MustCompile("(?s)```\\s*\\n(POST|GET) (\\S+)\\s*\\n```\\s*\\n\\s*Request body:\\s*\\n\\s*```json\\n(.*?)```")
+	examples := make(map[string]string)
+	for _, m := range re.FindAllStringSubmatch(string(readme), -1) {
+		examples[m[1]+" "+m[2]] = m[3]
+	}
+
+	return examples
+}
+
+func TestOpenAPIContract(t *testing.T) {
+	examples := readmeRequestExamples(t)
+
+	generateExample, ok := examples["POST /cert/generate"]
+	if !ok {
+		t.Fatal("readme has no request example for POST /cert/generate")
+	}
+	getExample, ok := examples["POST /cert/get"]
+	if !ok {
+		t.Fatal("readme has no request example for POST /cert/get")
+	}
+
+	e := newContractServer(t, true)
+
+	rec := doJSON(e, http.MethodPost, "/cert/get", getExample)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("get before generate: expected %d, got %d: %s", http.StatusNotFound, rec.Code, rec.Body)
+	}
+
+	rec = doJSON(e, http.MethodPost, "/cert/generate", generateExample)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("generate: expected %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+
+	rec = doJSON(e, http.MethodPost, "/cert/get", getExample)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get: expected %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+
+	var resp GetCertResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode get response: %v", err)
+	}
+	if resp.Status != CertificateStatusPending {
+		t.Errorf("expected status %s, got %s", CertificateStatusPending, resp.Status)
+	}
+
+	rec = doJSON(e, http.MethodGet, "/openapi.json", "")
+	if rec.Code != http.StatusOK {
+		t.Errorf("openapi.json: expected %d, got %d", http.StatusOK, rec.Code)
+	}
+}
+
+func TestOpenAPIContractErrors(t *testing.T) {
+	e := newContractServer(t, false)
+
+	tests := []struct {
+		name string
+		path string
+		body string
+		code int
+	}{
+		{
+			name: "malformed json",
+			path: "/cert/generate",
+			body: `{"user_id":`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "missing user id",
+			path: "/cert/generate",
+			body: `{"holder_commitment":"1","encryption_pub_key":"","profile":{}}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "invalid holder commitment",
+			path: "/cert/generate",
+			body: `{"holder_commitment":"abc","encryption_pub_key":"","user_id":"1","profile":{}}`,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "unknown user",
+			path: "/cert/get",
+			body: `{"user_id":"unknown"}`,
+			code: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doJSON(e, http.MethodPost, tt.path, tt.body)
+			if rec.Code != tt.code {
+				t.Errorf("expected %d, got %d: %s", tt.code, rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestReadmeExamplesMatchModels(t *testing.T) {
+	examples := readmeRequestExamples(t)
+
+	models := map[string]any{
+		"POST /cert/generate":                  &GenerateCertRequest{},
+		"POST /cert/get":                       &GetCertRequest{},
+		"POST /v1/standards/gip6/certificates": &GenerateStandardCertRequest{},
+	}
+
+	for endpoint, model := range models {
+		example, ok := examples[endpoint]
+		if !ok {
+			t.Errorf("readme has no request example for %s", endpoint)
+			continue
+		}
+
+		dec := json.NewDecoder(strings.NewReader(example))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(model); err != nil {
+			t.Errorf("readme example for %s does not match %T: %v", endpoint, model, err)
+		}
+	}
+}
+
+func TestV1Certificates(t *testing.T) {
+	examples := readmeRequestExamples(t)
+
+	generateExample, ok := examples["POST /v1/certificates"]
+	if !ok {
+		t.Fatal("readme has no request example for POST /v1/certificates")
+	}
+
+	e := newContractServer(t, true)
+
+	rec := doJSON(e, http.MethodPost, "/v1/certificates", generateExample)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create: expected %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Deprecation") != "" {
+		t.Errorf("v1 route must not be deprecated")
+	}
+
+	rec = doJSON(e, http.MethodGet, "/v1/certificates/12345", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get: expected %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+
+	var resp GetCertResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode get response: %v", err)
+	}
+	if resp.Queue == nil || !resp.Queue.Running || resp.Queue.Registry == nil || resp.Queue.Registry.Ahead != 1 {
+		t.Errorf("expected the issuance running, second in the registry queue, got %+v", resp.Queue)
+	}
+	if resp.Queue != nil && (resp.Queue.EstimatedCompletion == nil || resp.Queue.EstimatedCompletion.Before(time.Now())) {
+		t.Errorf("expected a completion estimated in the future, got %v", resp.Queue.EstimatedCompletion)
+	}
+
+	rec = doJSON(e, http.MethodDelete, "/v1/certificates/12345", "")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected %d, got %d: %s", http.StatusNoContent, rec.Code, rec.Body)
+	}
+
+	rec = doJSON(e, http.MethodGet, "/v1/certificates/12345", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("get after delete: expected %d, got %d: %s", http.StatusNotFound, rec.Code, rec.Body)
+	}
+
+	rec = doJSON(e, http.MethodDelete, "/v1/certificates/12345", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("delete after delete: expected %d, got %d: %s", http.StatusNotFound, rec.Code, rec.Body)
+	}
+}
+
+func TestDeleteQueuedCertificate(t *testing.T) {
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("open badger: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	generator := newFakeGenerator()
+	generator.issue = true
+	generator.hold = make(chan struct{})
+	e := NewServer(generator, db).makeEcho()
+
+	rec := doJSON(e, http.MethodPost, "/v1/certificates", readmeRequestExamples(t)["POST /v1/certificates"])
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create: expected %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+	rec = doJSON(e, http.MethodDelete, "/v1/certificates/12345", "")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected %d, got %d: %s", http.StatusNoContent, rec.Code, rec.Body)
+	}
+
+	close(generator.hold)
+	generator.issuing.Wait()
+
+	rec = doJSON(e, http.MethodGet, "/v1/certificates/12345", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected the certificate deleted while queued not to be stored once issued, got %d: %s",
+			rec.Code, rec.Body)
+	}
+	if _, err := readIssuanceFromDB(db, "12345"); err != nil {
+		t.Errorf("expected the issuance recorded for a later revocation: %v", err)
+	}
+}
+
+func TestExpiredIssuance(t *testing.T) {
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("open badger: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	generator := newFakeGenerator()
+	generator.issue = true
+	generator.requeue = true
+	generator.expire = true
+	server := NewServer(generator, db)
+	e := server.makeEcho()
+
+	rec := doJSON(e, http.MethodPost, "/v1/certificates", readmeRequestExamples(t)["POST /v1/certificates"])
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create: expected %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+	generator.issuing.Wait()
+
+	rec = doJSON(e, http.MethodGet, "/v1/certificates/12345", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected the certificate whose issuance expired to be removed, got %d: %s", rec.Code, rec.Body)
+	}
+	if len(server.handlers.queued) != 0 {
+		t.Errorf("expected the expired issuance to be forgotten, got %v", server.handlers.queued)
+	}
+}
+
+func TestLegacyRoutesDeprecated(t *testing.T) {
+	e := newContractServer(t, false)
+
+	rec := doJSON(e, http.MethodPost, "/cert/get", `{"user_id":"unknown"}`)
+	if rec.Header().Get("Deprecation") != "true" {
+		t.Errorf("expected Deprecation header on legacy route, got %q", rec.Header().Get("Deprecation"))
+	}
+	if link := rec.Header().Get("Link"); !strings.Contains(link, "/v1/certificates") {
+		t.Errorf("expected successor Link header, got %q", link)
+	}
+}
+
+func TestPolicyRejection(t *testing.T) {
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("open badger: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	engine, err := policy.NewEngineFromRules(policy.Rules{
+		Citizenship: policy.CountryList{Deny: []string{"CH"}},
+	})
+	if err != nil {
+		t.Fatalf("new policy engine: %v", err)
+	}
+
+	_, router := loadOpenAPIRouter(t)
+	e := NewServer(newFakeGenerator(), db, WithPolicy(engine)).makeEcho()
+	e.Use(openAPIValidator(t, router, true))
+
+	rec := doJSON(e, http.MethodPost, "/v1/certificates", readmeRequestExamples(t)["POST /v1/certificates"])
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected %d, got %d: %s", http.StatusForbidden, rec.Code, rec.Body)
+	}
+
+	var resp ErrorResp
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode error response: %v", err)
+	}
+	if resp.Code != policy.CodeCitizenshipDenied {
+		t.Errorf("expected code %s, got %q", policy.CodeCitizenshipDenied, resp.Code)
+	}
+
+	rec = doJSON(e, http.MethodGet, "/v1/certificates/12345", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("rejected profile must not be pending, got %d", rec.Code)
+	}
+}
+
+func TestAdmission(t *testing.T) {
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("open badger: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, router := loadOpenAPIRouter(t)
+	generator := newFakeGenerator()
+	e := NewServer(generator, db, WithAdmission(Admission{MaxQueueDepth: 10, MaxEstimatedWait: time.Minute})).makeEcho()
+	e.Use(openAPIValidator(t, router, true))
+
+	body := readmeRequestExamples(t)["POST /v1/certificates"]
+
+	tests := []struct {
+		name       string
+		backlog    zkcert.Backlog
+		status     int
+		retryAfter string
+	}{
+		{
+			name:       "queue full",
+			backlog:    zkcert.Backlog{Pending: 12, MeanDuration: 10 * time.Second, Lanes: 2},
+			status:     http.StatusServiceUnavailable,
+			retryAfter: "15",
+		},
+		{
+			name:       "queue full before any issuance",
+			backlog:    zkcert.Backlog{Pending: 10, Lanes: 1},
+			status:     http.StatusServiceUnavailable,
+			retryAfter: "60",
+		},
+		{
+			name:       "wait too long",
+			backlog:    zkcert.Backlog{Pending: 5, EstimatedWait: 90*time.Second + time.Millisecond, Lanes: 1},
+			status:     http.StatusTooManyRequests,
+			retryAfter: "31",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator.backlog = tt.backlog
+
+			rec := doJSON(e, http.MethodPost, "/v1/certificates", body)
+			if rec.Code != tt.status {
+				t.Fatalf("expected %d, got %d: %s", tt.status, rec.Code, rec.Body)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("expected Retry-After %s, got %q", tt.retryAfter, got)
+			}
+		})
+	}
+
+	generator.backlog = zkcert.Backlog{Pending: 9, EstimatedWait: time.Minute, Lanes: 1}
+	rec := doJSON(e, http.MethodPost, "/v1/certificates", body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected %d within the limits, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+
+	rec = doJSON(e, http.MethodGet, "/debug/vars", "")
+	if !strings.Contains(rec.Body.String(), `"admission_rejections": {"estimated_wait": 1, "queue_depth": 2}`) {
+		t.Errorf("expected the rejections in the metrics, got %s", rec.Body)
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("open badger: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, router := loadOpenAPIRouter(t)
+	s := NewServer(newFakeGenerator(), db, WithRateLimits(ratelimit.NewLimiter(db),
+		RateLimit{Route: "GET /v1/certificates/:user_id", Key: config.RateLimitKeyUserID,
+			Limit: ratelimit.Limit{Requests: 1, Period: time.Hour, Burst: 2}},
+		RateLimit{Route: "GET /v1/certificates/:user_id", Key: config.RateLimitKeyIP,
+			Limit: ratelimit.Limit{Requests: 3, Period: time.Hour}},
+		RateLimit{Route: "POST /cert/get", Key: config.RateLimitKeyUserID,
+			Limit: ratelimit.Limit{Requests: 1, Period: time.Minute}},
+	))
+	e := s.makeEcho()
+	e.Use(openAPIValidator(t, router, true))
+	if err := s.handlers.checkRateLimits(e); err != nil {
+		t.Fatalf("check rate limits: %v", err)
+	}
+
+	get := func(path, remaining string, status int) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := doJSON(e, http.MethodGet, path, "")
+		if rec.Code != status {
+			t.Fatalf("GET %s: expected %d, got %d: %s", path, status, rec.Code, rec.Body)
+		}
+		if got := rec.Header().Get("RateLimit-Remaining"); got != remaining {
+			t.Errorf("GET %s: expected RateLimit-Remaining %s, got %q", path, remaining, got)
+		}
+		return rec
+	}
+
+	get("/v1/certificates/1", "1", http.StatusNotFound)
+	get("/v1/certificates/1", "0", http.StatusNotFound)
+	rec := get("/v1/certificates/1", "0", http.StatusTooManyRequests)
+	if rec.Header().Get("RateLimit-Limit") != "2" || rec.Header().Get("Retry-After") != "3600" ||
+		rec.Header().Get("RateLimit-Reset") != "7200" {
+		t.Errorf("unexpected rate limit headers %v", rec.Header())
+	}
+
+	// the user limit refused the request before the IP took a token, the IP has one left
+	get("/v1/certificates/2", "0", http.StatusNotFound)
+	get("/v1/certificates/3", "0", http.StatusTooManyRequests)
+
+	// the user ID of the JSON body, which is still bound by the handler
+	rec = doJSON(e, http.MethodPost, "/cert/get", `{"user_id":"1"}`)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected %d, got %d: %s", http.StatusNotFound, rec.Code, rec.Body)
+	}
+	rec = doJSON(e, http.MethodPost, "/cert/get", `{"user_id":"1"}`)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" {
+		t.Errorf("expected %d with Retry-After 60, got %d %v", http.StatusTooManyRequests, rec.Code, rec.Header())
+	}
+
+	s = NewServer(newFakeGenerator(), db, WithRateLimits(ratelimit.NewLimiter(db),
+		RateLimit{Route: "GET /v1/certificate/:user_id", Key: config.RateLimitKeyIP}))
+	if err := s.handlers.checkRateLimits(s.makeEcho()); err == nil {
+		t.Error("expected an error for the rate limit of an unknown route")
+	}
+}
+
+func TestGuardianInfo(t *testing.T) {
+	e := newContractServer(t, true)
+
+	rec := doJSON(e, http.MethodGet, "/guardian/info", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+
+	var resp GuardianInfoResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode guardian info: %v", err)
+	}
+	if resp.ChainID != "9302" {
+		t.Errorf("expected chain id 9302, got %s", resp.ChainID)
+	}
+	if len(resp.Standards) != 1 || resp.Standards[0] != zkcertificate.StandardKYC.String() {
+		t.Errorf("unexpected standards %v", resp.Standards)
+	}
+	if len(resp.RetiredSigningPublicKeys) != 1 {
+		t.Fatalf("expected one retired signing key, got %d", len(resp.RetiredSigningPublicKeys))
+	}
+	if resp.RetiredSigningPublicKeys[0].KeyID == resp.SigningPublicKey.KeyID {
+		t.Errorf("retired and active signing keys must have distinct key ids")
+	}
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/swissborg/galactica-kyc-guardian/config"
+	guardianv1 "github.com/swissborg/galactica-kyc-guardian/gen/guardian/v1"
+	"github.com/swissborg/galactica-kyc-guardian/internal/ratelimit"
+)
+
+// rateLimitRejections counts the requests refused by the rate limits, by route,
+// served with the other process metrics at GET /debug/vars
+var rateLimitRejections = expvar.NewMap("rate_limit_rejections")
+
+// RateLimit limits how often every user or client calls a route.
+type RateLimit struct {
+	// Route is the method and path of a REST route, e.g. "POST /v1/certificates", or the full name of a unary
+	// gRPC method
+	Route string
+	// Key is what the requests are counted by, config.RateLimitKeyUserID or config.RateLimitKeyIP
+	Key   string
+	Limit ratelimit.Limit
+}
+
+// WithRateLimits limits the routes with the buckets of the limiter, one per route, key and user or client.
+func WithRateLimits(limiter *ratelimit.Limiter, limits ...RateLimit) Option {
+	return func(h *Handlers) {
+		h.limiter = limiter
+		for _, limit := range limits {
+			h.rateLimits[limit.Route] = append(h.rateLimits[limit.Route], limit)
+		}
+	}
+}
+
+// checkRateLimits returns an error when a rate limit is set on a route that is neither one of the REST routes of e
+// nor a unary method of the gRPC service.
+func (h *Handlers) checkRateLimits(e *echo.Echo) error {
+	routes := make(map[string]bool)
+	for _, route := range e.Routes() {
+		routes[route.Method+" "+route.Path] = true
+	}
+	for _, method := range guardianv1.GuardianService_ServiceDesc.Methods {
+		routes["/"+guardianv1.GuardianService_ServiceDesc.ServiceName+"/"+method.MethodName] = true
+	}
+
+	for route := range h.rateLimits {
+		if !routes[route] {
+			return fmt.Errorf("rate limit of unknown route %q", route)
+		}
+	}
+	return nil
+}
+
+// takeRateLimits takes a token from the buckets of the user and of the client IP of the route, the ones without
+// user or IP are skipped. It returns the state of the most restrictive bucket, and a *RetryableError wrapping
+// ErrRateLimited when a bucket is empty. The requests are let through when the store fails.
+func (h *Handlers) takeRateLimits(route string, userID UserID, ip string) (ratelimit.Result, error) {
+	var restrictive ratelimit.Result
+	for _, limit := range h.rateLimits[route] {
+		value := ip
+		if limit.Key == config.RateLimitKeyUserID {
+			value = string(userID)
+		}
+		if value == "" {
+			continue
+		}
+
+		result, err := h.limiter.Take(route+"/"+limit.Key+"/"+value, limit.Limit)
+		if err != nil {
+			log.WithError(err).WithField("route", route).Error("take rate limit token")
+			continue
+		}
+
+		if !result.Allowed {
+			rateLimitRejections.Add(route, 1)
+			log.WithField("route", route).
+				WithField("key", limit.Key).
+				WithField("userID", userID).
+				WithField("ip", ip).
+				Warn(ErrRateLimited)
+			return result, &RetryableError{
+				Err: fmt.Errorf("%w: %d requests per %s by %s",
+					ErrRateLimited, limit.Limit.Requests, limit.Limit.Period, limit.Key),
+				RetryAfter: result.RetryAfter,
+			}
+		}
+		if restrictive.Limit == 0 || result.Remaining < restrictive.Remaining {
+			restrictive = result
+		}
+	}
+	return restrictive, nil
+}
+
+// rateLimitHeaders returns the RateLimit headers of the state of a bucket, nil when no bucket was taken from.
+func rateLimitHeaders(result ratelimit.Result) map[string]string {
+	if result.Limit == 0 {
+		return nil
+	}
+	return map[string]string{
+		"RateLimit-Limit":     strconv.Itoa(result.Limit),
+		"RateLimit-Remaining": strconv.Itoa(result.Remaining),
+		"RateLimit-Reset":     strconv.FormatInt(int64(math.Ceil(result.Reset.Seconds())), 10),
+	}
+}
+
+// rateLimit is the middleware limiting the REST routes, keyed by the user ID of the path or of the JSON body
+// and by the client IP.
+func (h *Handlers) rateLimit(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		route := c.Request().Method + " " + c.Path()
+		if len(h.rateLimits[route]) == 0 {
+			return next(c)
+		}
+
+		result, err := h.takeRateLimits(route, requestUserID(c), c.RealIP())
+		for name, value := range rateLimitHeaders(result) {
+			c.Response().Header().Set(name, value)
+		}
+		if err != nil {
+			setRetryAfter(c, err)
+			return c.JSON(httpStatus(err), newErrorResp(err))
+		}
+
+		return next(c)
+	}
+}
+
+// requestUserID returns the user ID of the path, or else of the JSON body which is left to read, empty when the
+// request has none.
+func requestUserID(c echo.Context) UserID {
+	if userID := c.Param("user_id"); userID != "" {
+		return UserID(userID)
+	}
+
+	req := c.Request()
+	if req.Body == nil || !strings.HasPrefix(req.Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		return ""
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var fields struct {
+		UserID UserID `json:"user_id"`
+	}
+	_ = json.Unmarshal(body, &fields)
+	return fields.UserID
+}
+
+// rateLimitUnary is the interceptor limiting the unary gRPC methods, keyed by the user ID of the request and by the
+// IP of the peer. The RateLimit headers are sent as lowercase response metadata.
+func (h *Handlers) rateLimitUnary(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	if len(h.rateLimits[info.FullMethod]) == 0 {
+		return handler(ctx, req)
+	}
+
+	var userID UserID
+	if withUserID, ok := req.(interface{ GetUserId() string }); ok {
+		userID = UserID(withUserID.GetUserId())
+	}
+	var ip string
+	if p, ok := peer.FromContext(ctx); ok {
+		ip, _, _ = net.SplitHostPort(p.Addr.String())
+	}
+
+	result, err := h.takeRateLimits(info.FullMethod, userID, ip)
+	if headers := rateLimitHeaders(result); headers != nil {
+		md := metadata.MD{}
+		for name, value := range headers {
+			md.Set(name, value)
+		}
+		if err := grpc.SetHeader(ctx, md); err != nil {
+			log.WithError(err).Warn("set rate limit metadata")
+		}
+	}
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	return handler(ctx, req)
+}
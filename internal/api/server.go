@@ -2,7 +2,9 @@ package api
 
 import (
 	"context"
+	"expvar"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/dgraph-io/badger/v4"
@@ -10,25 +12,34 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
 
 	"github.com/swissborg/galactica-kyc-guardian/config"
-	"github.com/swissborg/galactica-kyc-guardian/internal/zkcert"
 )
 
 type Server struct {
 	echo      *echo.Echo
+	grpc      *grpc.Server
 	mem       *badger.DB
-	generator *zkcert.Service
+	generator CertGenerator
+	handlers  *Handlers
 }
 
-func NewServer(generator *zkcert.Service, mem *badger.DB) *Server {
-	return &Server{mem: mem, generator: generator}
+func NewServer(generator CertGenerator, mem *badger.DB, opts ...Option) *Server {
+	return &Server{
+		mem:       mem,
+		generator: generator,
+		handlers:  NewHandlers(generator, mem, opts...),
+	}
 }
 
 func (s *Server) Start(cfg config.APIConf) error {
 	log.Infof("API server starting...")
 
 	s.echo = s.makeEcho()
+	if err := s.handlers.checkRateLimits(s.echo); err != nil {
+		return err
+	}
 
 	err := s.echo.Start(fmt.Sprintf("%s:%s", cfg.Host, cfg.Port))
 	if err != nil {
@@ -37,12 +48,41 @@ func (s *Server) Start(cfg config.APIConf) error {
 	return nil
 }
 
+// StartGRPC serves the gRPC API on cfg.GRPCPort. It blocks until the server is stopped.
+func (s *Server) StartGRPC(cfg config.APIConf) error {
+	log.Infof("gRPC server starting...")
+
+	lis, err := net.Listen("tcp", fmt.Sprintf("%s:%s", cfg.Host, cfg.GRPCPort))
+	if err != nil {
+		return err
+	}
+
+	s.grpc = s.makeGRPC()
+
+	return s.grpc.Serve(lis)
+}
+
 func (s *Server) Stop() error {
 	const shutdownTimeout = time.Second * 10
 
 	ctx, cancelTimeout := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancelTimeout()
 
+	if s.grpc != nil {
+		stopped := make(chan struct{})
+		go func() {
+			s.grpc.GracefulStop()
+			close(stopped)
+		}()
+
+		// watch streams may outlive the timeout, cut them off
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			s.grpc.Stop()
+		}
+	}
+
 	if err := s.echo.Shutdown(ctx); err != nil {
 		return err
 	}
@@ -50,21 +90,55 @@ func (s *Server) Stop() error {
 	return nil
 }
 
+func (s *Server) makeGRPC() *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(s.handlers.rateLimitUnary))
+	NewGRPCServer(s.handlers).Register(server)
+	return server
+}
+
 func (s *Server) makeEcho() *echo.Echo {
 	e := echo.New()
 	e.Use(middleware.Recover())
+	// the client IP is the peer, or the one forwarded by a proxy on a private network
+	e.IPExtractor = echo.ExtractIPFromXFFHeader()
+	e.Use(s.handlers.rateLimit)
 
 	e.Validator = &CustomValidator{validator: validator.New()}
 
-	handlers := NewHandlers(s.generator, s.mem)
+	handlers := s.handlers
 
-	certGroup := e.Group("/cert")
+	e.GET("/openapi.json", getOpenAPISpec)
+	e.GET("/guardian/info", handlers.GuardianInfo)
+	e.GET("/debug/vars", echo.WrapHandler(expvar.Handler()))
+
+	v1 := e.Group("/v1")
+	v1.POST("/certificates", handlers.GenerateCert)
+	v1.GET("/certificates/:user_id", handlers.GetCertificate)
+	v1.DELETE("/certificates/:user_id", handlers.DeleteCertificate)
+	v1.POST("/standards/:standard/certificates", handlers.GenerateStandardCert)
+	v1.GET("/standards/:standard/certificates/:user_id", handlers.GetStandardCertificate)
+	v1.GET("/reports/spend", handlers.SpendReport)
+
+	// legacy routes kept as aliases of the /v1 API
+	certGroup := e.Group("/cert", deprecated("/v1/certificates"))
 	certGroup.POST("/generate", handlers.GenerateCert)
 	certGroup.POST("/get", handlers.GetCert)
 
 	return e
 }
 
+// deprecated marks every response of the group as deprecated in favour of the successor route.
+func deprecated(successor string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			h := c.Response().Header()
+			h.Set("Deprecation", "true")
+			h.Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+			return next(c)
+		}
+	}
+}
+
 type CustomValidator struct {
 	validator *validator.Validate
 }
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/spend"
+)
+
+// spendReportDays is the length of the spend report when its first day is not given
+const spendReportDays = 30
+
+// SpendReport sums the gas spent on the registry transactions of the certificates per UTC day,
+// from the day of the from query parameter to the day of to, both included.
+func (h *Handlers) SpendReport(c echo.Context) error {
+	from, to, err := reportPeriod(c.QueryParam("from"), c.QueryParam("to"), time.Now())
+	if err != nil {
+		return c.JSON(httpStatus(err), newErrorResp(err))
+	}
+
+	days, err := spend.NewLedger(h.inMem).Report(from, to)
+	if err != nil {
+		log.WithError(err).Error(ErrReadSpend)
+		return c.JSON(http.StatusInternalServerError, ErrorResp{
+			Error: fmt.Sprintf("%v: %v", ErrReadSpend, err),
+		})
+	}
+
+	resp := SpendReportResponse{
+		From: from.Format(time.DateOnly),
+		To:   to.Format(time.DateOnly),
+		Days: make([]SpendDay, len(days)),
+	}
+
+	total := spend.Day{Cost: new(big.Int)}
+	for i, day := range days {
+		resp.Days[i] = SpendDay{Date: day.Date, Spend: newSpend(day)}
+
+		total.Issuances += day.Issuances
+		total.Revocations += day.Revocations
+		total.GasUsed += day.GasUsed
+		total.Cost.Add(total.Cost, day.Cost)
+	}
+	resp.Total = newSpend(total)
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func newSpend(day spend.Day) Spend {
+	return Spend{
+		Issuances:   day.Issuances,
+		Revocations: day.Revocations,
+		GasUsed:     day.GasUsed,
+		CostWei:     day.Cost.String(),
+	}
+}
+
+// reportPeriod parses the UTC days of a report, to defaults to the day of now
+// and from to spendReportDays days before to.
+func reportPeriod(fromParam, toParam string, now time.Time) (time.Time, time.Time, error) {
+	to := now.UTC().Truncate(24 * time.Hour)
+	if toParam != "" {
+		var err error
+		if to, err = time.Parse(time.DateOnly, toParam); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("to: %v: %w", err, ErrParsReq)
+		}
+	}
+
+	from := to.AddDate(0, 0, 1-spendReportDays)
+	if fromParam != "" {
+		var err error
+		if from, err = time.Parse(time.DateOnly, fromParam); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("from: %v: %w", err, ErrParsReq)
+		}
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from %s is after to %s: %w",
+			from.Format(time.DateOnly), to.Format(time.DateOnly), ErrValidateReq)
+	}
+	return from, to, nil
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/spend"
+)
+
+func TestSpendReport(t *testing.T) {
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("open badger: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	ledger := spend.NewLedger(db)
+	for _, operation := range []spend.Operation{spend.OperationIssuance, spend.OperationRevocation} {
+		record := spend.NewRecord(zkcertificate.StandardKYC, operation, zkcertificate.Hash{}, []*types.Receipt{
+			{TxHash: common.HexToHash("0x01"), GasUsed: 100_000, EffectiveGasPrice: big.NewInt(2_000_000_000)},
+		}, true)
+		if err := ledger.Add(record); err != nil {
+			t.Fatalf("add record: %v", err)
+		}
+	}
+
+	_, router := loadOpenAPIRouter(t)
+	e := NewServer(newFakeGenerator(), db).makeEcho()
+	e.Use(openAPIValidator(t, router, true))
+
+	rec := doJSON(e, http.MethodGet, "/v1/reports/spend", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+
+	var resp SpendReportResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode spend report: %v", err)
+	}
+	if len(resp.Days) != 1 || resp.Days[0].Date != resp.To {
+		t.Fatalf("expected the spend of today only, got %+v", resp.Days)
+	}
+	want := Spend{Issuances: 1, Revocations: 1, GasUsed: 200_000, CostWei: "400000000000000"}
+	if resp.Total != want || resp.Days[0].Spend != want {
+		t.Errorf("expected %+v, got %+v", want, resp)
+	}
+
+	rec = doJSON(e, http.MethodGet, "/v1/reports/spend?from=2020-01-01&to=2020-01-31", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode spend report: %v", err)
+	}
+	if len(resp.Days) != 0 || resp.Total.CostWei != "0" {
+		t.Errorf("expected no spend in 2020, got %+v", resp)
+	}
+}
+
+func TestReportPeriod(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 4, 5, 0, time.UTC)
+
+	from, to, err := reportPeriod("", "", now)
+	if err != nil {
+		t.Fatalf("default period: %v", err)
+	}
+	if from.Format(time.DateOnly) != "2024-02-10" || to.Format(time.DateOnly) != "2024-03-10" {
+		t.Errorf("expected the last %d days, got %s to %s", spendReportDays, from, to)
+	}
+
+	if _, _, err := reportPeriod("2024-03-11", "2024-03-10", now); !errors.Is(err, ErrValidateReq) {
+		t.Errorf("expected %v, got %v", ErrValidateReq, err)
+	}
+	if _, _, err := reportPeriod("yesterday", "", now); !errors.Is(err, ErrParsReq) {
+		t.Errorf("expected %v, got %v", ErrParsReq, err)
+	}
+}
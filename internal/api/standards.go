@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/zkcert"
+)
+
+// GenerateStandardCert creates a certificate of the standard of the route from its JSON inputs
+// and queues its issuance.
+func (h *Handlers) GenerateStandardCert(c echo.Context) error {
+	var req GenerateStandardCertRequest
+
+	if err := c.Bind(&req); err != nil {
+		log.WithError(err).Error("bind gen standard cert request")
+		return c.JSON(http.StatusBadRequest, ErrorResp{
+			Error: fmt.Sprintf("%v: %v", err, ErrParsReq),
+		})
+	}
+
+	resp, err := h.generateStandardCert(c.Request().Context(), zkcertificate.Standard(c.Param("standard")), req)
+	if err != nil {
+		setRetryAfter(c, err)
+		return c.JSON(httpStatus(err), newErrorResp(err))
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handlers) generateStandardCert(
+	_ context.Context,
+	standard zkcertificate.Standard,
+	req GenerateStandardCertRequest,
+) (GenerateCertResponse, error) {
+	log.
+		WithField("holderCommitment", req.HolderCommitment).
+		WithField("userID", req.UserID).
+		WithField("standard", standard).
+		Info("request")
+
+	service, err := h.standardService(standard)
+	if err != nil {
+		return GenerateCertResponse{}, err
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		log.WithError(err).Error("validate gen standard cert request")
+		return GenerateCertResponse{}, fmt.Errorf("%v: %w", err, ErrValidateReq)
+	}
+
+	if err := h.admit(); err != nil {
+		return GenerateCertResponse{}, err
+	}
+
+	holderCommitment, err := parseHolderCommitment(req.HolderCommitment, req.EncryptionPubKey)
+	if err != nil {
+		return GenerateCertResponse{}, err
+	}
+
+	key := standardCertKey(standard, req.UserID)
+	hc := stripToSix(holderCommitment.CommitmentHash)
+
+	callback := func(encryptedCert zkcertificate.EncryptedCertificate, err error) {
+		if errors.Is(err, zkcert.ErrTxVanished) {
+			// the issuance is queued again, the certificate stays pending
+			log.WithError(err).WithField("standard", standard).Warn("cert issuance requeued")
+			return
+		}
+		if err != nil {
+			log.WithError(err).WithField("standard", standard).Error("cert issuance")
+
+			if err := deleteCertFromDB(h.inMem, key); err != nil {
+				log.WithError(err).Error("clean up db after cert issuance")
+			}
+			return
+		}
+
+		log.WithField("holderCommitment", hc).
+			WithField("userID", req.UserID).
+			WithField("standard", standard).
+			Info("certificate issued")
+
+		b, err := json.Marshal(encryptedCert)
+		if err != nil {
+			log.WithError(err).Error("marshaling cert")
+			return
+		}
+		if err = setCertInDB(h.inMem, key, b); err != nil {
+			log.WithError(err).Error("adding cert to db")
+			return
+		}
+	}
+
+	// the pending status must be stored before the issuance is queued,
+	// otherwise a fast issuance could be overwritten by it
+	if err := setCertInDB(h.inMem, key, nil); err != nil {
+		log.WithError(err).Error(ErrAddCertToDB)
+		return GenerateCertResponse{}, fmt.Errorf("%v: %w", err, ErrAddCertToDB)
+	}
+
+	contentHash, err := service.GenerateZKCert(context.Background(), holderCommitment, req.Inputs, callback)
+	if err != nil {
+		if err := deleteCertFromDB(h.inMem, key); err != nil {
+			log.WithError(err).Error("clean up db after cert generation")
+		}
+
+		log.WithError(err).Error(ErrCertGenerating)
+		return GenerateCertResponse{}, fmt.Errorf("%w: %w", ErrCertGenerating, err)
+	}
+
+	log.
+		WithField("holderCommitment", hc).
+		WithField("userID", req.UserID).
+		WithField("standard", standard).
+		WithField("contentHash", contentHash).
+		Info("cert created")
+
+	return GenerateCertResponse{
+		Status: CertificateStatusPending,
+	}, nil
+}
+
+// GetStandardCertificate is the GET /v1/standards/:standard/certificates/:user_id variant of GetCertificate.
+func (h *Handlers) GetStandardCertificate(c echo.Context) error {
+	standard := zkcertificate.Standard(c.Param("standard"))
+	userID := UserID(c.Param("user_id"))
+
+	log.
+		WithField("userID", userID).
+		WithField("standard", standard).
+		Info("request")
+
+	resp, err := h.getStandardCert(standard, userID)
+	if err != nil {
+		return c.JSON(httpStatus(err), newErrorResp(err))
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handlers) getStandardCert(standard zkcertificate.Standard, userID UserID) (GetCertResponse, error) {
+	if _, err := h.standardService(standard); err != nil {
+		return GetCertResponse{}, err
+	}
+
+	certificate, err := getCertFromDB(h.inMem, standardCertKey(standard, userID))
+	if err == ErrCertNotFound {
+		return GetCertResponse{}, ErrCertNotFound
+	}
+	if err != nil {
+		log.WithError(err).Error(ErrReadCertStatus)
+		return GetCertResponse{}, fmt.Errorf("%w: %v", ErrReadCertStatus, err)
+	}
+
+	if certificate == "" {
+		return GetCertResponse{Status: CertificateStatusPending}, nil
+	}
+
+	return GetCertResponse{
+		Certificate: json.RawMessage(certificate),
+		Status:      CertificateStatusDone,
+	}, nil
+}
+
+func (h *Handlers) standardService(standard zkcertificate.Standard) (zkcert.JSONService, error) {
+	service, ok := h.standards[standard]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedStandard, standard)
+	}
+	return service, nil
+}
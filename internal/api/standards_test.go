@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/labstack/echo/v4"
+)
+
+func newStandardsServer(t *testing.T, generator *fakeCEXGenerator) *echo.Echo {
+	t.Helper()
+
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("open badger: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, router := loadOpenAPIRouter(t)
+	e := NewServer(newFakeGenerator(), db, WithStandards(generator)).makeEcho()
+	e.Use(openAPIValidator(t, router, true))
+
+	return e
+}
+
+func TestStandardCertificates(t *testing.T) {
+	generator := newFakeCEXGenerator()
+	generator.issue = true
+	e := newStandardsServer(t, generator)
+
+	example, ok := readmeRequestExamples(t)["POST /v1/standards/gip6/certificates"]
+	if !ok {
+		t.Fatal("readme example of POST /v1/standards/gip6/certificates not found")
+	}
+
+	rec := doJSON(e, http.MethodPost, "/v1/standards/gip6/certificates", example)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("generate: expected %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rec = doJSON(e, http.MethodGet, "/v1/standards/gip6/certificates/12345", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("get: expected %d, got %d: %s", http.StatusOK, rec.Code, rec.Body)
+		}
+		var resp GetCertResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode get response: %v", err)
+		}
+		if resp.Status == CertificateStatusDone {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("certificate not issued in time: %s", rec.Body)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// the KYC certificate of the user is stored separately
+	rec = doJSON(e, http.MethodGet, "/v1/certificates/12345", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("kyc certificate: expected %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestStandardCertificatesErrors(t *testing.T) {
+	e := newStandardsServer(t, newFakeCEXGenerator())
+
+	tests := []struct {
+		name string
+		path string
+		body string
+		want int
+	}{
+		{
+			name: "invalid inputs",
+			path: "/v1/standards/gip6/certificates",
+			body: `{"holder_commitment":"4586425042444163335895417167611444541749813513569901646582116352074512113476","encryption_pub_key":"OEotdsfEuoiqM7ob2KJEQemhWodn87hZNFv890q4xGw=","user_id":"12345","inputs":{"swapVolumeYear":"10"}}`,
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "standard not configured",
+			path: "/v1/standards/gip5/certificates",
+			body: `{"holder_commitment":"4586425042444163335895417167611444541749813513569901646582116352074512113476","encryption_pub_key":"OEotdsfEuoiqM7ob2KJEQemhWodn87hZNFv890q4xGw=","user_id":"12345","inputs":{}}`,
+			want: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doJSON(e, http.MethodPost, tt.path, tt.body)
+			if rec.Code != tt.want {
+				t.Fatalf("expected %d, got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	rec := doJSON(e, http.MethodGet, "/v1/standards/gip6/certificates/12345", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("rejected inputs must not be pending, got %d", rec.Code)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -10,9 +11,52 @@ import (
 
 const userDataStoringTime = 30 * time.Minute
 
+// DB key prefixes, one per kind of record stored for a user
+const (
+	certKeyPrefix         = "cert/"
+	issuanceKeyPrefix     = "issuance/"
+	standardCertKeyPrefix = "standard-cert/"
+)
+
+func certKey(userID UserID) []byte {
+	return []byte(certKeyPrefix + string(userID))
+}
+
+// standardCertKey is the key of the certificate of a standard other than KYC,
+// a user may hold one certificate per standard.
+func standardCertKey(standard zkcertificate.Standard, userID UserID) []byte {
+	return []byte(standardCertKeyPrefix + standard.String() + "/" + string(userID))
+}
+
+func issuanceKey(userID UserID) []byte {
+	return []byte(issuanceKeyPrefix + string(userID))
+}
+
+// Issuance is the public registration data of a certificate issued on-chain.
+// Unlike the encrypted certificate it does not expire, so that the certificate can be revoked later.
+type Issuance struct {
+	LeafHash  zkcertificate.Hash `json:"leafHash"`
+	LeafIndex int                `json:"leafIndex"`
+	Status    CertificateStatus  `json:"status"`
+	// SigningKeyID identifies the key the certificate was signed with
+	SigningKeyID string `json:"signingKeyId,omitempty"`
+}
+
 func addCertToDB(db *badger.DB, userID UserID, cert []byte) error {
+	return setCertInDB(db, certKey(userID), cert)
+}
+
+func readCertFromDB(db *badger.DB, userID UserID) (string, error) {
+	return getCertFromDB(db, certKey(userID))
+}
+
+func deleteUserDataFromDB(db *badger.DB, userID UserID) error {
+	return deleteCertFromDB(db, certKey(userID))
+}
+
+func setCertInDB(db *badger.DB, key []byte, cert []byte) error {
 	return db.Update(func(txn *badger.Txn) error {
-		e := badger.NewEntry([]byte(userID), cert).WithTTL(userDataStoringTime)
+		e := badger.NewEntry(key, cert).WithTTL(userDataStoringTime)
 		if err := txn.SetEntry(e); err != nil {
 			return fmt.Errorf("failed to set certificate to db: %w", err)
 		}
@@ -20,10 +64,10 @@ func addCertToDB(db *badger.DB, userID UserID, cert []byte) error {
 	})
 }
 
-func readCertFromDB(db *badger.DB, userID UserID) (string, error) {
+func getCertFromDB(db *badger.DB, key []byte) (string, error) {
 	var certData []byte
 	err := db.View(func(txn *badger.Txn) error {
-		item, err := txn.Get([]byte(userID))
+		item, err := txn.Get(key)
 		if err == badger.ErrKeyNotFound {
 			return ErrCertNotFound
 		}
@@ -41,10 +85,41 @@ func readCertFromDB(db *badger.DB, userID UserID) (string, error) {
 	return string(certData), nil
 }
 
-func deleteUserDataFromDB(db *badger.DB, userID UserID) error {
+func deleteCertFromDB(db *badger.DB, key []byte) error {
 	return db.Update(func(txn *badger.Txn) error {
-		return txn.Delete([]byte(userID))
+		return txn.Delete(key)
+	})
+}
+
+func addIssuanceToDB(db *badger.DB, userID UserID, iss Issuance) error {
+	b, err := json.Marshal(iss)
+	if err != nil {
+		return fmt.Errorf("failed to marshal issuance: %w", err)
+	}
+
+	return db.Update(func(txn *badger.Txn) error {
+		if err := txn.Set(issuanceKey(userID), b); err != nil {
+			return fmt.Errorf("failed to set issuance to db: %w", err)
+		}
+		return nil
+	})
+}
+
+func readIssuanceFromDB(db *badger.DB, userID UserID) (Issuance, error) {
+	var iss Issuance
+	err := db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(issuanceKey(userID))
+		if err == badger.ErrKeyNotFound {
+			return ErrCertNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("error retrieving issuance: %w", err)
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &iss)
+		})
 	})
+	return iss, err
 }
 
 func stripToSix(hash zkcertificate.Hash) string {
@@ -0,0 +1,145 @@
+// Package keys loads the private keys of the guardian.
+package keys
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	ErrNoProviderKey         = errors.New("no provider key configured")
+	ErrAmbiguousProviderKey  = errors.New("both a raw provider key and a keystore are configured")
+	ErrRawKeyInProduction    = errors.New("raw hex provider key refused in production, use a keystore")
+	ErrMissingPassphrase     = errors.New("no keystore passphrase configured")
+	ErrUnexpectedKeystoreKey = errors.New("keystore address does not match its key")
+	// ErrInvalidHexKey does not wrap the decoding error, which could quote the key
+	ErrInvalidHexKey = errors.New("invalid hex encoded key")
+)
+
+// ProviderKeySource tells where the provider key is read from,
+// either Hex or KeystorePath must be set.
+type ProviderKeySource struct {
+	// Hex is the raw hex encoded private key
+	Hex []byte
+	// KeystorePath is an encrypted go-ethereum V3 keystore JSON file
+	KeystorePath string
+	// Passphrase decrypts the keystore
+	Passphrase []byte
+	// AllowHex permits a raw hex key in production
+	AllowHex bool
+}
+
+// ProviderKeySource returns the source of the provider key configured by the secrets
+// and the PRIVATE_KEY_KEYSTORE and ALLOW_RAW_PRIVATE_KEY environment variables.
+func (s *Secrets) ProviderKeySource() (ProviderKeySource, error) {
+	return s.keySource(SecretPrivateKey)
+}
+
+// PoolKeySource returns the source of the additional provider key name, configured like PRIVATE_KEY: either the
+// raw hex secret name, accepted in production when the ALLOW_RAW_<name> environment variable is true, or the
+// keystore at the path of the <name>_KEYSTORE environment variable and the secret <name>_PASSPHRASE.
+func (s *Secrets) PoolKeySource(name string) (ProviderKeySource, error) {
+	return s.keySource(name)
+}
+
+// keySource returns the source of the provider key name, from the secrets name and <name>_PASSPHRASE and the
+// <name>_KEYSTORE and ALLOW_RAW_<name> environment variables.
+func (s *Secrets) keySource(name string) (ProviderKeySource, error) {
+	hexKey, err := s.Get(name)
+	if err != nil {
+		return ProviderKeySource{}, err
+	}
+
+	passphrase, err := s.Get(name + passphraseSuffix)
+	if err != nil {
+		Zero(hexKey)
+		return ProviderKeySource{}, err
+	}
+
+	keystorePath, _ := s.lookupEnv(name + "_KEYSTORE")
+	allowHex, _ := s.lookupEnv("ALLOW_RAW_" + name)
+
+	return ProviderKeySource{
+		Hex:          hexKey,
+		KeystorePath: keystorePath,
+		Passphrase:   passphrase,
+		AllowHex:     allowHex == "true",
+	}, nil
+}
+
+// Zero overwrites the key material of the source.
+func (src ProviderKeySource) Zero() {
+	Zero(src.Hex)
+	Zero(src.Passphrase)
+}
+
+// LoadProviderKey returns the Ethereum key the guardian sends its transactions with.
+// In production a raw hex key is refused unless src.AllowHex is set.
+func LoadProviderKey(src ProviderKeySource, production bool) (*ecdsa.PrivateKey, error) {
+	switch {
+	case len(src.Hex) > 0 && src.KeystorePath != "":
+		return nil, ErrAmbiguousProviderKey
+	case src.KeystorePath != "":
+		return loadKeystore(src)
+	case len(src.Hex) > 0:
+		if production && !src.AllowHex {
+			return nil, ErrRawKeyInProduction
+		}
+
+		raw, err := decodeHexKey(src.Hex)
+		if err != nil {
+			return nil, err
+		}
+		defer Zero(raw)
+
+		key, err := crypto.ToECDSA(raw)
+		if err != nil {
+			return nil, fmt.Errorf("decode provider key: %w", err)
+		}
+		return key, nil
+	default:
+		return nil, ErrNoProviderKey
+	}
+}
+
+func loadKeystore(src ProviderKeySource) (*ecdsa.PrivateKey, error) {
+	if len(src.Passphrase) == 0 {
+		return nil, ErrMissingPassphrase
+	}
+
+	keyJSON, err := os.ReadFile(src.KeystorePath)
+	if err != nil {
+		return nil, fmt.Errorf("read keystore: %w", err)
+	}
+
+	key, err := keystore.DecryptKey(keyJSON, string(src.Passphrase))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt keystore %s: %w", src.KeystorePath, err)
+	}
+
+	if crypto.PubkeyToAddress(key.PrivateKey.PublicKey) != key.Address {
+		return nil, ErrUnexpectedKeystoreKey
+	}
+
+	return key.PrivateKey, nil
+}
+
+// decodeHexKey decodes the hex key, with or without 0x prefix. The caller should Zero the result.
+func decodeHexKey(hexKey []byte) ([]byte, error) {
+	hexKey = bytes.TrimPrefix(hexKey, []byte("0x"))
+
+	raw := make([]byte, hex.DecodedLen(len(hexKey)))
+	if _, err := hex.Decode(raw, hexKey); err != nil {
+		Zero(raw)
+		return nil, ErrInvalidHexKey
+	}
+
+	return raw, nil
+}
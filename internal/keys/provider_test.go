@@ -0,0 +1,122 @@
+package keys
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const testHexKey = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+func writeKeystore(t *testing.T, passphrase string) string {
+	t.Helper()
+
+	key, err := crypto.HexToECDSA(testHexKey)
+	if err != nil {
+		t.Fatalf("decode test key: %v", err)
+	}
+
+	ks := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.ImportECDSA(key, passphrase)
+	if err != nil {
+		t.Fatalf("import key: %v", err)
+	}
+
+	return account.URL.Path
+}
+
+func TestLoadProviderKey(t *testing.T) {
+	keystorePath := writeKeystore(t, "correct horse")
+	want, _ := crypto.HexToECDSA(testHexKey)
+
+	tests := []struct {
+		name       string
+		src        ProviderKeySource
+		production bool
+		wantErr    error
+	}{
+		{
+			name: "hex in dev",
+			src:  ProviderKeySource{Hex: []byte(testHexKey)},
+		},
+		{
+			name:       "hex refused in production",
+			src:        ProviderKeySource{Hex: []byte(testHexKey)},
+			production: true,
+			wantErr:    ErrRawKeyInProduction,
+		},
+		{
+			name:       "hex explicitly allowed in production",
+			src:        ProviderKeySource{Hex: []byte("0x" + testHexKey), AllowHex: true},
+			production: true,
+		},
+		{
+			name:    "invalid hex",
+			src:     ProviderKeySource{Hex: []byte("not a key")},
+			wantErr: ErrInvalidHexKey,
+		},
+		{
+			name:       "keystore",
+			src:        ProviderKeySource{KeystorePath: keystorePath, Passphrase: []byte("correct horse")},
+			production: true,
+		},
+		{
+			name:    "keystore with wrong passphrase",
+			src:     ProviderKeySource{KeystorePath: keystorePath, Passphrase: []byte("wrong")},
+			wantErr: keystore.ErrDecrypt,
+		},
+		{
+			name:    "keystore without passphrase",
+			src:     ProviderKeySource{KeystorePath: keystorePath},
+			wantErr: ErrMissingPassphrase,
+		},
+		{
+			name:    "both hex and keystore",
+			src:     ProviderKeySource{Hex: []byte(testHexKey), KeystorePath: keystorePath},
+			wantErr: ErrAmbiguousProviderKey,
+		},
+		{
+			name:    "nothing configured",
+			wantErr: ErrNoProviderKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := LoadProviderKey(tt.src, tt.production)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load provider key: %v", err)
+			}
+			if !key.Equal(want) {
+				t.Errorf("loaded key does not match")
+			}
+		})
+	}
+}
+
+func TestInvalidHexKeyErrorDoesNotLeakKey(t *testing.T) {
+	_, err := LoadProviderKey(ProviderKeySource{Hex: []byte(testHexKey[:63] + "z")}, false)
+	if !errors.Is(err, ErrInvalidHexKey) {
+		t.Fatalf("expected %v, got %v", ErrInvalidHexKey, err)
+	}
+	if strings.Contains(err.Error(), "z") || strings.Contains(err.Error(), testHexKey[:8]) {
+		t.Errorf("error quotes the key: %v", err)
+	}
+}
+
+func TestZeroECDSA(t *testing.T) {
+	key, _ := crypto.HexToECDSA(testHexKey)
+	ZeroECDSA(key)
+	if key.D.Sign() != 0 {
+		t.Errorf("expected the secret scalar to be zeroed, got %s", key.D)
+	}
+}
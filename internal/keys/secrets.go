@@ -0,0 +1,80 @@
+package keys
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Names of the secrets of the guardian
+const (
+	SecretPrivateKey           = "PRIVATE_KEY"
+	SecretPrivateKeyPassphrase = "PRIVATE_KEY_PASSPHRASE"
+	SecretSigningKey           = "SIGNING_KEY"
+	SecretIdentityIndexSalt    = "IDENTITY_INDEX_SALT"
+)
+
+// fileSuffix is appended to the name of a secret to give the file it is read from instead
+const fileSuffix = "_FILE"
+
+// passphraseSuffix is appended to the name of a provider key to give the secret decrypting its keystore
+const passphraseSuffix = "_PASSPHRASE"
+
+var ErrAmbiguousSecret = errors.New("secret configured both as a value and as a file")
+
+// Secrets reads the secrets of the guardian. A secret NAME is read, by order of precedence, from
+// the NAME environment variable, from the file named by NAME_FILE or from the file NAME of the
+// secrets directory, as mounted by Docker or Kubernetes.
+type Secrets struct {
+	dir       string
+	lookupEnv func(string) (string, bool)
+}
+
+// NewSecrets reads the secrets from the environment, and from dir when it is not empty.
+func NewSecrets(dir string) *Secrets {
+	return &Secrets{dir: dir, lookupEnv: os.LookupEnv}
+}
+
+// Get returns the secret, or nil when it is not configured. The caller should Zero it after use.
+// A trailing newline of the secret files is ignored.
+func (s *Secrets) Get(name string) ([]byte, error) {
+	value, hasValue := s.lookupEnv(name)
+	path, hasFile := s.lookupEnv(name + fileSuffix)
+
+	switch {
+	case hasValue && value != "" && hasFile && path != "":
+		return nil, fmt.Errorf("%w: %s", ErrAmbiguousSecret, name)
+	case hasValue && value != "":
+		return []byte(value), nil
+	case hasFile && path != "":
+		return readSecretFile(path)
+	case s.dir != "":
+		secret, err := readSecretFile(filepath.Join(s.dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return secret, err
+	default:
+		return nil, nil
+	}
+}
+
+func readSecretFile(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read secret file: %w", err)
+	}
+
+	secret := bytes.TrimRight(b, "\r\n")
+	// the trimmed newline is left behind in b, zero it with the rest of the secret
+	Zero(b[len(secret):])
+
+	return secret, nil
+}
+
+// Zero overwrites the key material in b.
+func Zero(b []byte) {
+	clear(b)
+}
@@ -0,0 +1,160 @@
+package keys
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iden3/go-iden3-crypto/babyjub"
+)
+
+func writeSecretFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write secret file: %v", err)
+	}
+	return path
+}
+
+func TestSecretsGet(t *testing.T) {
+	dir := t.TempDir()
+	writeSecretFile(t, dir, "FROM_DIR", "dir-secret\n")
+	writeSecretFile(t, dir, "FROM_ENV", "dir-secret\n")
+	file := writeSecretFile(t, t.TempDir(), "secret", "file-secret\r\n")
+
+	t.Setenv("FROM_ENV", "env-secret")
+	t.Setenv("FROM_FILE_FILE", file)
+	t.Setenv("AMBIGUOUS", "env-secret")
+	t.Setenv("AMBIGUOUS_FILE", file)
+
+	secrets := NewSecrets(dir)
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr error
+	}{
+		{name: "FROM_ENV", want: "env-secret"},
+		{name: "FROM_FILE", want: "file-secret"},
+		{name: "FROM_DIR", want: "dir-secret"},
+		{name: "MISSING", want: ""},
+		{name: "AMBIGUOUS", wantErr: ErrAmbiguousSecret},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret, err := secrets.Get(tt.name)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("get secret: %v", err)
+			}
+			if string(secret) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, secret)
+			}
+		})
+	}
+}
+
+func TestSecretsProviderKeySource(t *testing.T) {
+	keystorePath := writeKeystore(t, "correct horse")
+	passphraseFile := writeSecretFile(t, t.TempDir(), "passphrase", "correct horse\n")
+
+	t.Setenv("PRIVATE_KEY_KEYSTORE", keystorePath)
+	t.Setenv("PRIVATE_KEY_PASSPHRASE_FILE", passphraseFile)
+
+	src, err := NewSecrets("").ProviderKeySource()
+	if err != nil {
+		t.Fatalf("provider key source: %v", err)
+	}
+	defer src.Zero()
+
+	if _, err := LoadProviderKey(src, true); err != nil {
+		t.Errorf("load provider key from keystore and passphrase file: %v", err)
+	}
+}
+
+func TestSecretsPoolKeySource(t *testing.T) {
+	keystorePath := writeKeystore(t, "correct horse")
+
+	t.Setenv("PRIVATE_KEY", testHexKey)
+	t.Setenv("ALLOW_RAW_PRIVATE_KEY", "true")
+	t.Setenv("PRIVATE_KEY_2_KEYSTORE", keystorePath)
+	t.Setenv("PRIVATE_KEY_2_PASSPHRASE", "correct horse")
+	t.Setenv("PRIVATE_KEY_3", testHexKey)
+
+	secrets := NewSecrets("")
+	load := func(name string) error {
+		src, err := secrets.PoolKeySource(name)
+		if err != nil {
+			t.Fatalf("pool key source %s: %v", name, err)
+		}
+		defer src.Zero()
+
+		_, err = LoadProviderKey(src, true)
+		return err
+	}
+
+	if err := load("PRIVATE_KEY_2"); err != nil {
+		t.Errorf("load pool key from keystore: %v", err)
+	}
+	// allowing the raw main key does not allow the raw pool keys
+	if err := load("PRIVATE_KEY_3"); !errors.Is(err, ErrRawKeyInProduction) {
+		t.Errorf("expected %v, got %v", ErrRawKeyInProduction, err)
+	}
+
+	t.Setenv("ALLOW_RAW_PRIVATE_KEY_3", "true")
+	if err := load("PRIVATE_KEY_3"); err != nil {
+		t.Errorf("load explicitly allowed raw pool key: %v", err)
+	}
+}
+
+func TestSigningKeyID(t *testing.T) {
+	key := babyjub.NewRandPrivKey()
+
+	id := SigningKeyID(key.Public())
+	if len(id) != 2*signingKeyIDLength {
+		t.Errorf("expected a %d hex characters id, got %q", 2*signingKeyIDLength, id)
+	}
+	if SigningKeyID(key.Public()) != id {
+		t.Errorf("key id must be deterministic")
+	}
+	other := babyjub.NewRandPrivKey()
+	if SigningKeyID(other.Public()) == id {
+		t.Errorf("distinct keys must have distinct ids")
+	}
+}
+
+func TestRetiredPublicKeys(t *testing.T) {
+	retired := babyjub.NewRandPrivKey()
+	compressed := retired.Public().Compress()
+	encoded := hex.EncodeToString(compressed[:])
+
+	publicKeys, err := KeyRing{Retired: []string{encoded}}.RetiredPublicKeys()
+	if err != nil {
+		t.Fatalf("parse retired keys: %v", err)
+	}
+	if len(publicKeys) != 1 || publicKeys[0].Compress() != compressed {
+		t.Errorf("unexpected retired public keys %v", publicKeys)
+	}
+
+	for name, ring := range map[string]KeyRing{
+		"secret name":  {Retired: []string{SecretSigningKey}},
+		"wrong length": {Retired: []string{encoded + "00"}},
+		"listed twice": {Retired: []string{encoded, "0x" + encoded}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ring.RetiredPublicKeys(); !errors.Is(err, ErrInvalidKeyRing) {
+				t.Errorf("expected %v, got %v", ErrInvalidKeyRing, err)
+			}
+		})
+	}
+}
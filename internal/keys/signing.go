@@ -0,0 +1,119 @@
+package keys
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/galactica-corp/guardians-sdk/pkg/keymanagement"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+)
+
+// ErrInvalidKeyRing is returned when the signing key ring is misconfigured
+var ErrInvalidKeyRing = errors.New("invalid signing key ring")
+
+// LoadSigningKey returns the EdDSA key the certificates are signed with. When certSigningKey is empty,
+// the key is derived from the provider key like the guardians SDK does.
+func LoadSigningKey(certSigningKey []byte, providerKey *ecdsa.PrivateKey) (babyjub.PrivateKey, error) {
+	var signingKey babyjub.PrivateKey
+	if len(certSigningKey) > 0 {
+		keyBytes, err := decodeHexKey(certSigningKey)
+		if err != nil {
+			return signingKey, err
+		}
+		defer Zero(keyBytes)
+
+		if len(keyBytes) != len(signingKey) {
+			return signingKey, fmt.Errorf("invalid key length: expected %d bytes, got %d", len(signingKey), len(keyBytes))
+		}
+		copy(signingKey[:], keyBytes)
+	} else {
+		var err error
+		signingKey, err = keymanagement.DeriveEdDSAKeyFromEthereumPrivateKey(providerKey)
+		if err != nil {
+			return signingKey, fmt.Errorf("inferring signing key: %w", err)
+		}
+	}
+	return signingKey, nil
+}
+
+// ZeroECDSA overwrites the secret scalar of the key, it must not be used afterwards.
+func ZeroECDSA(key *ecdsa.PrivateKey) {
+	if key == nil || key.D == nil {
+		return
+	}
+	clear(key.D.Bits())
+	key.D.SetInt64(0)
+}
+
+// signingKeyIDLength is the number of bytes of the public key hash identifying a signing key
+const signingKeyIDLength = 8
+
+// SigningKeyID identifies a signing key by the hash of its compressed public key,
+// so that the key a certificate was signed with can be told from the certificate alone.
+func SigningKeyID(publicKey *babyjub.PublicKey) string {
+	compressed := publicKey.Compress()
+	sum := sha256.Sum256(compressed[:])
+	return hex.EncodeToString(sum[:signingKeyIDLength])
+}
+
+// KeyRing names the signing keys. New certificates are signed with the Active key, the Retired keys are only
+// kept to tell which key signed the certificates issued before a rotation, by their public keys alone.
+type KeyRing struct {
+	// Active is the secret of the signing key, SIGNING_KEY when empty
+	Active string
+	// Retired are the hex encoded compressed public keys of the previous signing keys
+	Retired []string
+}
+
+// ActiveSecret returns the name of the secret of the active signing key.
+func (r KeyRing) ActiveSecret() string {
+	if r.Active == "" {
+		return SecretSigningKey
+	}
+	return r.Active
+}
+
+// RetiredPublicKeys parses the public keys of the retired signing keys, whose private keys are never loaded.
+func (r KeyRing) RetiredPublicKeys() ([]*babyjub.PublicKey, error) {
+	publicKeys := make([]*babyjub.PublicKey, 0, len(r.Retired))
+	seen := make(map[babyjub.PublicKeyComp]bool, len(r.Retired))
+	for _, encoded := range r.Retired {
+		publicKey, err := ParsePublicKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: retired signing key %q: %w", ErrInvalidKeyRing, encoded, err)
+		}
+
+		compressed := publicKey.Compress()
+		if seen[compressed] {
+			return nil, fmt.Errorf("%w: retired signing key %s is listed twice", ErrInvalidKeyRing, SigningKeyID(publicKey))
+		}
+		seen[compressed] = true
+
+		publicKeys = append(publicKeys, publicKey)
+	}
+	return publicKeys, nil
+}
+
+// ParsePublicKey parses a hex encoded compressed signing public key, with or without 0x prefix.
+func ParsePublicKey(encoded string) (*babyjub.PublicKey, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(encoded, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("decode public key: %w", err)
+	}
+
+	var compressed babyjub.PublicKeyComp
+	if len(b) != len(compressed) {
+		return nil, fmt.Errorf("invalid public key length %d", len(b))
+	}
+	copy(compressed[:], b)
+
+	publicKey, err := compressed.Decompress()
+	if err != nil {
+		return nil, fmt.Errorf("decompress public key: %w", err)
+	}
+	return publicKey, nil
+}
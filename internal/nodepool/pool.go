@@ -0,0 +1,382 @@
+// Package nodepool spreads the JSON-RPC calls of the guardian over several nodes in priority order,
+// failing over to the next healthy node when one is down.
+package nodepool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrNoNode is returned when every node failed to answer a call
+var ErrNoNode = errors.New("no node available")
+
+const (
+	// probeTimeout bounds the health probe of a node
+	probeTimeout = 5 * time.Second
+	// maxBlockLag is how many blocks a node can be behind the most advanced one and stay healthy
+	maxBlockLag = 20
+	// limitExceededCode is the JSON-RPC error code of the rate limited requests
+	limitExceededCode = -32005
+)
+
+type node struct {
+	// name identifies the node in the logs, its URL commonly holds an API key
+	name    string
+	client  *ethclient.Client
+	healthy atomic.Bool
+}
+
+// Pool calls the first healthy node in priority order and fails over to the next one when a node
+// does not answer. The answers of a node, including errors such as reverted calls, are never retried,
+// but for the transactions and receipts not found by a node, which are looked up on the other nodes.
+type Pool struct {
+	nodes []*node
+
+	chainIDMu sync.Mutex
+	chainID   *big.Int
+
+	// sendMu serializes the transactions, see SendTransaction
+	sendMu sync.Mutex
+}
+
+// Dial connects to the nodes of urls, in decreasing priority. The nodes are healthy until probed.
+func Dial(ctx context.Context, urls []string) (*Pool, error) {
+	if len(urls) == 0 {
+		return nil, ErrNoNode
+	}
+
+	p := &Pool{}
+	for i, rawURL := range urls {
+		client, err := ethclient.DialContext(ctx, rawURL)
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("connect to node %s: %w", nodeName(i, rawURL), err)
+		}
+
+		n := &node{name: nodeName(i, rawURL), client: client}
+		n.healthy.Store(true)
+		p.nodes = append(p.nodes, n)
+	}
+	return p, nil
+}
+
+// nodeName names the node by its priority and host.
+func nodeName(i int, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return fmt.Sprintf("#%d", i)
+	}
+	return fmt.Sprintf("#%d %s", i, u.Host)
+}
+
+func (p *Pool) Close() {
+	for _, n := range p.nodes {
+		n.client.Close()
+	}
+}
+
+// Watch probes the nodes every interval until ctx is done.
+func (p *Pool) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = p.Probe(ctx)
+		}
+	}
+}
+
+// Health is the outcome of the probe of a node.
+type Health struct {
+	Node string
+	Err  error
+}
+
+// Probe checks that every node answers, is on the chain of the pool and is at most maxBlockLag blocks
+// behind the most advanced node. It returns the health of the nodes in priority order.
+func (p *Pool) Probe(ctx context.Context) []Health {
+	heads := make([]uint64, len(p.nodes))
+	errs := make([]error, len(p.nodes))
+
+	var wg sync.WaitGroup
+	for i, n := range p.nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			heads[i], errs[i] = p.probe(ctx, n)
+		}()
+	}
+	wg.Wait()
+
+	var best uint64
+	for i := range p.nodes {
+		if errs[i] == nil && heads[i] > best {
+			best = heads[i]
+		}
+	}
+
+	health := make([]Health, len(p.nodes))
+	for i, n := range p.nodes {
+		err := errs[i]
+		if err == nil && best-heads[i] > maxBlockLag {
+			err = fmt.Errorf("%d blocks behind", best-heads[i])
+		}
+		p.setHealthy(n, err)
+		health[i] = Health{Node: n.name, Err: err}
+	}
+	return health
+}
+
+func (p *Pool) probe(ctx context.Context, n *node) (uint64, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	chainID, err := n.client.ChainID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	p.chainIDMu.Lock()
+	if p.chainID == nil {
+		p.chainID = chainID
+	}
+	expected := p.chainID
+	p.chainIDMu.Unlock()
+
+	if chainID.Cmp(expected) != 0 {
+		return 0, fmt.Errorf("on chain %s instead of %s", chainID, expected)
+	}
+
+	return n.client.BlockNumber(ctx)
+}
+
+// setHealthy records the health of the node, logging its changes.
+func (p *Pool) setHealthy(n *node, err error) {
+	healthy := err == nil
+	if n.healthy.Swap(healthy) == healthy {
+		return
+	}
+
+	if healthy {
+		log.WithField("node", n.name).Info("node is back up")
+	} else {
+		log.WithError(err).WithField("node", n.name).Warn("node is down")
+	}
+}
+
+// ordered returns the healthy nodes in priority order, followed by the other nodes as a last resort.
+func (p *Pool) ordered() []*node {
+	nodes := make([]*node, 0, len(p.nodes))
+	for _, n := range p.nodes {
+		if n.healthy.Load() {
+			nodes = append(nodes, n)
+		}
+	}
+	for _, n := range p.nodes {
+		if !n.healthy.Load() {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// do runs f on the nodes in order until one answers.
+func (p *Pool) do(ctx context.Context, f func(*ethclient.Client) error) error {
+	var err error
+	for _, n := range p.ordered() {
+		err = f(n.client)
+		if !isNodeFailure(ctx, err) {
+			return err
+		}
+		p.setHealthy(n, err)
+	}
+	return fmt.Errorf("%w: %w", ErrNoNode, err)
+}
+
+func call[T any](ctx context.Context, p *Pool, f func(*ethclient.Client) (T, error)) (T, error) {
+	var result T
+	err := p.do(ctx, func(c *ethclient.Client) error {
+		var err error
+		result, err = f(c)
+		return err
+	})
+	return result, err
+}
+
+// lookup runs f on the nodes in order like call, but takes a NotFound answer for the answer of the pool only
+// once every node answering gave it: a node lagging behind the others does not know the transactions and
+// receipts the others already have.
+func lookup[T any](ctx context.Context, p *Pool, f func(*ethclient.Client) (T, error)) (T, error) {
+	var result T
+	var err error
+	notFound := false
+	for _, n := range p.ordered() {
+		result, err = f(n.client)
+		if errors.Is(err, ethereum.NotFound) {
+			notFound = true
+			continue
+		}
+		if !isNodeFailure(ctx, err) {
+			return result, err
+		}
+		p.setHealthy(n, err)
+	}
+	if notFound {
+		var zero T
+		return zero, ethereum.NotFound
+	}
+	return result, fmt.Errorf("%w: %w", ErrNoNode, err)
+}
+
+// isNodeFailure tells whether err means that the node did not answer, rather than an answer of the node.
+func isNodeFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil || errors.Is(err, ethereum.NotFound) {
+		return false
+	}
+
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr.ErrorCode() == limitExceededCode
+	}
+	return true
+}
+
+// SendTransaction sends the signed transaction through the first node accepting it.
+//
+// A node failing to answer may still have received the transaction and broadcast it. The transaction is
+// then sent to the next node only if that node does not already know it, and only the same signed
+// transaction is ever sent: having a single hash and nonce, it is included at most once whatever the
+// number of nodes it reached. The transactions are sent one at a time so that a failover of one
+// transaction is over before the next one is sent.
+func (p *Pool) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	p.sendMu.Lock()
+	defer p.sendMu.Unlock()
+
+	var err error
+	for i, n := range p.ordered() {
+		if i > 0 && knows(ctx, n.client, tx.Hash()) {
+			log.WithField("node", n.name).WithField("tx", tx.Hash()).Info("transaction already known after a node failure")
+			return nil
+		}
+
+		err = n.client.SendTransaction(ctx, tx)
+		if err == nil || isAlreadyKnown(err) {
+			return nil
+		}
+		if !isNodeFailure(ctx, err) {
+			if i > 0 && knows(ctx, n.client, tx.Hash()) {
+				// e.g. a nonce too low because the transaction sent through a failed node is already mined
+				return nil
+			}
+			return err
+		}
+
+		p.setHealthy(n, err)
+		log.WithError(err).WithField("node", n.name).WithField("tx", tx.Hash()).Warn("send transaction failed over")
+	}
+	return fmt.Errorf("%w: %w", ErrNoNode, err)
+}
+
+// knows tells whether the node has the transaction, pending or mined.
+func knows(ctx context.Context, client *ethclient.Client, hash common.Hash) bool {
+	_, _, err := client.TransactionByHash(ctx, hash)
+	return err == nil
+}
+
+// isAlreadyKnown tells whether the node refused the transaction because it already has it.
+func isAlreadyKnown(err error) bool {
+	var rpcErr rpc.Error
+	return errors.As(err, &rpcErr) && strings.Contains(strings.ToLower(err.Error()), "already known")
+}
+
+func (p *Pool) ChainID(ctx context.Context) (*big.Int, error) {
+	return call(ctx, p, func(c *ethclient.Client) (*big.Int, error) { return c.ChainID(ctx) })
+}
+
+func (p *Pool) BlockNumber(ctx context.Context) (uint64, error) {
+	return call(ctx, p, func(c *ethclient.Client) (uint64, error) { return c.BlockNumber(ctx) })
+}
+
+func (p *Pool) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return call(ctx, p, func(c *ethclient.Client) ([]byte, error) { return c.CodeAt(ctx, account, blockNumber) })
+}
+
+func (p *Pool) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return call(ctx, p, func(c *ethclient.Client) ([]byte, error) { return c.CallContract(ctx, msg, blockNumber) })
+}
+
+func (p *Pool) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return call(ctx, p, func(c *ethclient.Client) (*types.Header, error) { return c.HeaderByNumber(ctx, number) })
+}
+
+func (p *Pool) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return call(ctx, p, func(c *ethclient.Client) ([]byte, error) { return c.PendingCodeAt(ctx, account) })
+}
+
+func (p *Pool) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return call(ctx, p, func(c *ethclient.Client) (uint64, error) { return c.PendingNonceAt(ctx, account) })
+}
+
+func (p *Pool) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return call(ctx, p, func(c *ethclient.Client) (uint64, error) { return c.NonceAt(ctx, account, blockNumber) })
+}
+
+func (p *Pool) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return call(ctx, p, func(c *ethclient.Client) (*big.Int, error) { return c.SuggestGasPrice(ctx) })
+}
+
+func (p *Pool) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return call(ctx, p, func(c *ethclient.Client) (*big.Int, error) { return c.SuggestGasTipCap(ctx) })
+}
+
+func (p *Pool) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	return call(ctx, p, func(c *ethclient.Client) (uint64, error) { return c.EstimateGas(ctx, msg) })
+}
+
+func (p *Pool) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	return call(ctx, p, func(c *ethclient.Client) ([]types.Log, error) { return c.FilterLogs(ctx, q) })
+}
+
+func (p *Pool) SubscribeFilterLogs(
+	ctx context.Context,
+	q ethereum.FilterQuery,
+	ch chan<- types.Log,
+) (ethereum.Subscription, error) {
+	return call(ctx, p, func(c *ethclient.Client) (ethereum.Subscription, error) {
+		return c.SubscribeFilterLogs(ctx, q, ch)
+	})
+}
+
+func (p *Pool) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return lookup(ctx, p, func(c *ethclient.Client) (*types.Receipt, error) { return c.TransactionReceipt(ctx, txHash) })
+}
+
+func (p *Pool) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	type result struct {
+		tx      *types.Transaction
+		pending bool
+	}
+	r, err := lookup(ctx, p, func(c *ethclient.Client) (result, error) {
+		tx, pending, err := c.TransactionByHash(ctx, hash)
+		return result{tx: tx, pending: pending}, err
+	})
+	return r.tx, r.pending, err
+}
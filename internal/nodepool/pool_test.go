@@ -0,0 +1,370 @@
+package nodepool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// fakeNode is a JSON-RPC stand-in of a node, answering the few methods used by the tests.
+type fakeNode struct {
+	mu      sync.Mutex
+	chainID int64
+	head    uint64
+	// down answers every request with a 503
+	down bool
+	// dropSendAnswer receives the transactions but answers with a 502, like a proxy timing out
+	dropSendAnswer bool
+	// revert answers the eth_call requests with a reverted execution
+	revert bool
+	// limited answers every request with a rate limit error
+	limited bool
+	// refusal refuses the transactions with this message when set
+	refusal string
+	txs     map[common.Hash]*types.Transaction
+	calls   map[string]int
+	// peers receive the transactions the node receives, like the gossip of the network
+	peers []*fakeNode
+}
+
+func newFakeNode(t *testing.T) (*fakeNode, string) {
+	t.Helper()
+
+	n := &fakeNode{chainID: 9302, head: 1000, txs: make(map[common.Hash]*types.Transaction), calls: make(map[string]int)}
+	server := httptest.NewServer(n)
+	t.Cleanup(server.Close)
+	return n, server.URL
+}
+
+type jsonRPCRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (n *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req jsonRPCRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	n.mu.Lock()
+	n.calls[req.Method]++
+	down, dropSendAnswer := n.down, n.dropSendAnswer
+	n.mu.Unlock()
+
+	if down {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	result, rpcErr := n.answer(req)
+	if req.Method == "eth_sendRawTransaction" && dropSendAnswer {
+		http.Error(w, "gateway timeout", http.StatusBadGateway)
+		return
+	}
+
+	resp := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+	if rpcErr != nil {
+		resp["error"] = rpcErr
+	} else {
+		resp["result"] = result
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (n *fakeNode) answer(req jsonRPCRequest) (any, *jsonRPCError) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.limited {
+		return nil, &jsonRPCError{Code: limitExceededCode, Message: "limit exceeded"}
+	}
+
+	switch req.Method {
+	case "eth_chainId":
+		return hexutil.EncodeBig(big.NewInt(n.chainID)), nil
+	case "eth_blockNumber":
+		return hexutil.EncodeUint64(n.head), nil
+	case "eth_call":
+		if n.revert {
+			return nil, &jsonRPCError{Code: 3, Message: "execution reverted"}
+		}
+		return "0x01", nil
+	case "eth_sendRawTransaction":
+		var raw hexutil.Bytes
+		_ = json.Unmarshal(req.Params[0], &raw)
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(raw); err != nil {
+			return nil, &jsonRPCError{Code: -32602, Message: err.Error()}
+		}
+		if _, ok := n.txs[tx.Hash()]; ok {
+			return nil, &jsonRPCError{Code: -32000, Message: "already known"}
+		}
+		if n.refusal != "" {
+			return nil, &jsonRPCError{Code: -32000, Message: n.refusal}
+		}
+		n.txs[tx.Hash()] = tx
+		for _, peer := range n.peers {
+			peer.mu.Lock()
+			peer.txs[tx.Hash()] = tx
+			peer.mu.Unlock()
+		}
+		return tx.Hash(), nil
+	case "eth_getTransactionByHash":
+		var hash common.Hash
+		_ = json.Unmarshal(req.Params[0], &hash)
+		if tx, ok := n.txs[hash]; ok {
+			return tx, nil
+		}
+		return nil, nil
+	default:
+		return nil, &jsonRPCError{Code: -32601, Message: "method not found"}
+	}
+}
+
+func (n *fakeNode) set(f func(n *fakeNode)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f(n)
+}
+
+func (n *fakeNode) count(method string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.calls[method]
+}
+
+func dialPool(t *testing.T, urls ...string) *Pool {
+	t.Helper()
+
+	p, err := Dial(context.Background(), urls)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(p.Close)
+	return p
+}
+
+func signedTx(t *testing.T) *types.Transaction {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	to := common.HexToAddress("0x68272A56A0e9b095E5606fDD8b6c297702C0dfe5")
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(9302)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(9302),
+		Nonce:     7,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(10),
+		Gas:       21000,
+		To:        &to,
+	})
+	if err != nil {
+		t.Fatalf("sign tx: %v", err)
+	}
+	return tx
+}
+
+func TestFailoverReads(t *testing.T) {
+	primary, primaryURL := newFakeNode(t)
+	secondary, secondaryURL := newFakeNode(t)
+	p := dialPool(t, primaryURL, secondaryURL)
+	ctx := context.Background()
+
+	primary.set(func(n *fakeNode) { n.down = true })
+
+	if _, err := p.BlockNumber(ctx); err != nil {
+		t.Fatalf("block number: %v", err)
+	}
+	if _, err := p.BlockNumber(ctx); err != nil {
+		t.Fatalf("block number: %v", err)
+	}
+	if got := primary.count("eth_blockNumber"); got != 1 {
+		t.Errorf("expected the failed primary to be skipped until probed, got %d calls", got)
+	}
+	if got := secondary.count("eth_blockNumber"); got != 2 {
+		t.Errorf("expected the secondary to answer, got %d calls", got)
+	}
+
+	primary.set(func(n *fakeNode) { n.down = false })
+	for _, health := range p.Probe(ctx) {
+		if health.Err != nil {
+			t.Errorf("expected %s to be healthy, got %v", health.Node, health.Err)
+		}
+	}
+
+	if _, err := p.BlockNumber(ctx); err != nil {
+		t.Fatalf("block number: %v", err)
+	}
+	if got := secondary.count("eth_blockNumber"); got != 3 { // 2 calls and the probe
+		t.Errorf("expected the primary to be preferred again, got %d secondary calls", got)
+	}
+
+	primary.set(func(n *fakeNode) { n.down = true })
+	secondary.set(func(n *fakeNode) { n.down = true })
+	if _, err := p.BlockNumber(ctx); !errors.Is(err, ErrNoNode) {
+		t.Errorf("expected %v, got %v", ErrNoNode, err)
+	}
+}
+
+func TestNodeAnswersAreNotRetried(t *testing.T) {
+	primary, primaryURL := newFakeNode(t)
+	secondary, secondaryURL := newFakeNode(t)
+	p := dialPool(t, primaryURL, secondaryURL)
+	ctx := context.Background()
+
+	primary.set(func(n *fakeNode) { n.revert = true })
+	if _, err := p.CallContract(ctx, ethereum.CallMsg{}, nil); err == nil {
+		t.Fatalf("expected the revert to be returned")
+	}
+	if got := secondary.count("eth_call"); got != 0 {
+		t.Errorf("expected a reverted call not to be retried, got %d calls", got)
+	}
+
+	if _, _, err := p.nodes[0].client.TransactionByHash(ctx, common.Hash{1}); !errors.Is(err, ethereum.NotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if isNodeFailure(ctx, ethereum.NotFound) {
+		t.Errorf("expected not found to be an answer")
+	}
+
+	primary.set(func(n *fakeNode) { n.revert, n.limited = false, true })
+	if _, err := p.CallContract(ctx, ethereum.CallMsg{}, nil); err != nil {
+		t.Fatalf("expected a rate limited call to fail over: %v", err)
+	}
+	if got := secondary.count("eth_call"); got != 1 {
+		t.Errorf("expected the secondary to answer, got %d calls", got)
+	}
+}
+
+func TestNotFoundLookedUpOnEveryNode(t *testing.T) {
+	lagging, laggingURL := newFakeNode(t)
+	secondary, secondaryURL := newFakeNode(t)
+	p := dialPool(t, laggingURL, secondaryURL)
+	ctx := context.Background()
+
+	tx := signedTx(t)
+	secondary.set(func(n *fakeNode) { n.txs[tx.Hash()] = tx })
+
+	if _, _, err := p.TransactionByHash(ctx, tx.Hash()); err != nil {
+		t.Fatalf("expected the transaction unknown to the lagging node to be found, got %v", err)
+	}
+	if got := lagging.count("eth_getTransactionByHash"); got != 1 {
+		t.Errorf("expected the lagging node to be asked first, got %d calls", got)
+	}
+
+	secondary.set(func(n *fakeNode) { delete(n.txs, tx.Hash()) })
+	if _, _, err := p.TransactionByHash(ctx, tx.Hash()); !errors.Is(err, ethereum.NotFound) {
+		t.Errorf("expected not found once no node has the transaction, got %v", err)
+	}
+
+	secondary.set(func(n *fakeNode) { n.down = true })
+	if _, _, err := p.TransactionByHash(ctx, tx.Hash()); !errors.Is(err, ethereum.NotFound) {
+		t.Errorf("expected not found when the node answering has no transaction, got %v", err)
+	}
+	if !p.nodes[0].healthy.Load() {
+		t.Errorf("expected a node answering not found to stay healthy")
+	}
+}
+
+func TestProbe(t *testing.T) {
+	_, primaryURL := newFakeNode(t)
+	lagging, laggingURL := newFakeNode(t)
+	otherChain, otherChainURL := newFakeNode(t)
+	p := dialPool(t, primaryURL, laggingURL, otherChainURL)
+
+	lagging.set(func(n *fakeNode) { n.head -= maxBlockLag + 1 })
+	otherChain.set(func(n *fakeNode) { n.chainID = 1 })
+
+	health := p.Probe(context.Background())
+	if health[0].Err != nil {
+		t.Errorf("expected the primary to be healthy, got %v", health[0].Err)
+	}
+	if health[1].Err == nil || health[2].Err == nil {
+		t.Errorf("expected the lagging node and the node of another chain to be down, got %v", health)
+	}
+
+	if ordered := p.ordered(); ordered[0] != p.nodes[0] || len(ordered) != 3 {
+		t.Errorf("expected the healthy node first and the others as a last resort")
+	}
+}
+
+func TestSendTransactionNeverDoubleSent(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("received by a failed node", func(t *testing.T) {
+		primary, primaryURL := newFakeNode(t)
+		secondary, secondaryURL := newFakeNode(t)
+		p := dialPool(t, primaryURL, secondaryURL)
+
+		// the primary broadcasts the transaction but its answer is lost
+		primary.set(func(n *fakeNode) { n.dropSendAnswer, n.peers = true, []*fakeNode{secondary} })
+
+		if err := p.SendTransaction(ctx, signedTx(t)); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+		if got := secondary.count("eth_sendRawTransaction"); got != 0 {
+			t.Errorf("expected the known transaction not to be sent again, got %d sends", got)
+		}
+	})
+
+	t.Run("not received by a failed node", func(t *testing.T) {
+		primary, primaryURL := newFakeNode(t)
+		secondary, secondaryURL := newFakeNode(t)
+		p := dialPool(t, primaryURL, secondaryURL)
+
+		primary.set(func(n *fakeNode) { n.down = true })
+
+		tx := signedTx(t)
+		if err := p.SendTransaction(ctx, tx); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+		if got := secondary.count("eth_sendRawTransaction"); got != 1 {
+			t.Errorf("expected a single send, got %d", got)
+		}
+
+		// sending it again is a no-op for the node that has it
+		if err := p.SendTransaction(ctx, tx); err != nil {
+			t.Errorf("expected an already known transaction to be accepted, got %v", err)
+		}
+		if len(secondary.txs) != 1 {
+			t.Errorf("expected a single transaction, got %d", len(secondary.txs))
+		}
+	})
+
+	t.Run("refused", func(t *testing.T) {
+		primary, primaryURL := newFakeNode(t)
+		secondary, secondaryURL := newFakeNode(t)
+		p := dialPool(t, primaryURL, secondaryURL)
+
+		primary.set(func(n *fakeNode) { n.refusal = "nonce too low" })
+		if err := p.SendTransaction(ctx, signedTx(t)); err == nil {
+			t.Fatalf("expected the refusal of the node to be returned")
+		}
+		if got := secondary.count("eth_sendRawTransaction"); got != 0 {
+			t.Errorf("expected a refused transaction not to be sent elsewhere, got %d sends", got)
+		}
+	})
+}
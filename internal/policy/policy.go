@@ -0,0 +1,257 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/biter777/countries"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Rejection codes returned when a profile violates the policy
+const (
+	CodeUnderage           = "UNDERAGE"
+	CodeCitizenshipDenied  = "CITIZENSHIP_DENIED"
+	CodeInvalidDateOfBirth = "INVALID_DATE_OF_BIRTH"
+)
+
+// Violation is returned when a profile is not eligible for a certificate
+type Violation struct {
+	Code   string
+	Reason string
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("policy violation %s: %s", v.Code, v.Reason)
+}
+
+// CountryList restricts a country either by an allow list or by a deny list.
+// When Allow is not empty, only the listed countries are accepted.
+// Countries can be given by name, alpha-2 or alpha-3 code.
+type CountryList struct {
+	Allow []string `yaml:"Allow"`
+	Deny  []string `yaml:"Deny"`
+}
+
+// Rules is the YAML representation of the issuance policy
+type Rules struct {
+	// MinAge is the minimum age in years of the user at issuance time, 0 disables the check
+	MinAge      int         `yaml:"MinAge"`
+	Citizenship CountryList `yaml:"Citizenship"`
+}
+
+// Subject is the part of the KYC profile the policy is evaluated on
+type Subject struct {
+	DateOfBirth time.Time
+	// Citizenship is an alpha-3 country code
+	Citizenship string
+}
+
+type countrySet struct {
+	allow map[string]struct{}
+	deny  map[string]struct{}
+}
+
+func (s countrySet) permits(alpha3 string) bool {
+	if _, ok := s.deny[alpha3]; ok {
+		return false
+	}
+	if len(s.allow) > 0 {
+		_, ok := s.allow[alpha3]
+		return ok
+	}
+	return true
+}
+
+type compiledRules struct {
+	minAge      int
+	citizenship countrySet
+}
+
+// Engine evaluates the issuance policy. The zero value and a nil *Engine accept every subject.
+type Engine struct {
+	path    string
+	mu      sync.RWMutex
+	rules   compiledRules
+	modTime time.Time
+}
+
+// NewEngine creates an engine from the rules in the YAML file at path.
+func NewEngine(path string) (*Engine, error) {
+	e := &Engine{path: path}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// NewEngineFromRules creates an engine from rules that are never reloaded.
+func NewEngineFromRules(rules Rules) (*Engine, error) {
+	compiled, err := compile(rules)
+	if err != nil {
+		return nil, err
+	}
+	return &Engine{rules: compiled}, nil
+}
+
+// Reload reads the rules file again. The current rules are kept when the file is invalid.
+func (e *Engine) Reload() error {
+	info, err := os.Stat(e.path)
+	if err != nil {
+		return fmt.Errorf("stat policy file: %w", err)
+	}
+
+	b, err := os.ReadFile(e.path)
+	if err != nil {
+		return fmt.Errorf("read policy file: %w", err)
+	}
+
+	var rules Rules
+	if err := yaml.Unmarshal(b, &rules); err != nil {
+		return fmt.Errorf("unmarshal policy file: %w", err)
+	}
+
+	compiled, err := compile(rules)
+	if err != nil {
+		return fmt.Errorf("compile policy: %w", err)
+	}
+
+	e.mu.Lock()
+	e.rules = compiled
+	e.modTime = info.ModTime()
+	e.mu.Unlock()
+
+	return nil
+}
+
+// ReloadIfChanged reloads the rules when the file was modified since the last load.
+func (e *Engine) ReloadIfChanged() (bool, error) {
+	info, err := os.Stat(e.path)
+	if err != nil {
+		return false, fmt.Errorf("stat policy file: %w", err)
+	}
+
+	e.mu.RLock()
+	changed := !info.ModTime().Equal(e.modTime)
+	e.mu.RUnlock()
+
+	if !changed {
+		return false, nil
+	}
+
+	return true, e.Reload()
+}
+
+// Watch reloads the rules every interval when the file changed, until ctx is done.
+// An invalid file is logged and the previous rules stay in effect.
+func (e *Engine) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			reloaded, err := e.ReloadIfChanged()
+			if err != nil {
+				log.WithError(err).Error("reload policy")
+				continue
+			}
+			if reloaded {
+				log.WithField("path", e.path).Info("policy reloaded")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Evaluate returns a *Violation when the subject is not eligible at the given time.
+func (e *Engine) Evaluate(subject Subject, now time.Time) error {
+	if e == nil {
+		return nil
+	}
+
+	e.mu.RLock()
+	rules := e.rules
+	e.mu.RUnlock()
+
+	if rules.minAge > 0 {
+		if subject.DateOfBirth.IsZero() || subject.DateOfBirth.After(now) {
+			return &Violation{Code: CodeInvalidDateOfBirth, Reason: "date of birth is missing or in the future"}
+		}
+		if age := Age(subject.DateOfBirth, now); age < rules.minAge {
+			return &Violation{Code: CodeUnderage, Reason: fmt.Sprintf("minimum age is %d", rules.minAge)}
+		}
+	}
+
+	if !rules.citizenship.permits(subject.Citizenship) {
+		return &Violation{Code: CodeCitizenshipDenied, Reason: fmt.Sprintf("citizenship %q is not served", subject.Citizenship)}
+	}
+
+	return nil
+}
+
+// Age returns the number of full years between dateOfBirth and now.
+func Age(dateOfBirth, now time.Time) int {
+	age := now.Year() - dateOfBirth.Year()
+	if now.Month() < dateOfBirth.Month() || (now.Month() == dateOfBirth.Month() && now.Day() < dateOfBirth.Day()) {
+		age--
+	}
+	return age
+}
+
+func compile(rules Rules) (compiledRules, error) {
+	if rules.MinAge < 0 {
+		return compiledRules{}, fmt.Errorf("MinAge must not be negative")
+	}
+
+	citizenship, err := compileCountries(rules.Citizenship)
+	if err != nil {
+		return compiledRules{}, fmt.Errorf("Citizenship: %w", err)
+	}
+
+	return compiledRules{
+		minAge:      rules.MinAge,
+		citizenship: citizenship,
+	}, nil
+}
+
+func compileCountries(list CountryList) (countrySet, error) {
+	allow, err := countryCodes(list.Allow)
+	if err != nil {
+		return countrySet{}, fmt.Errorf("Allow: %w", err)
+	}
+
+	deny, err := countryCodes(list.Deny)
+	if err != nil {
+		return countrySet{}, fmt.Errorf("Deny: %w", err)
+	}
+
+	return countrySet{allow: allow, deny: deny}, nil
+}
+
+func countryCodes(names []string) (map[string]struct{}, error) {
+	codes := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		alpha3, err := CountryCode(name)
+		if err != nil {
+			return nil, err
+		}
+		codes[alpha3] = struct{}{}
+	}
+	return codes, nil
+}
+
+// CountryCode normalizes a country name, alpha-2 or alpha-3 code to an alpha-3 code.
+func CountryCode(name string) (string, error) {
+	code := countries.ByName(strings.TrimSpace(name))
+	if code == countries.Unknown || !code.IsValid() {
+		return "", fmt.Errorf("unknown country %q", name)
+	}
+	return code.Alpha3(), nil
+}
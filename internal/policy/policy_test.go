@@ -0,0 +1,163 @@
+package policy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func date(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		t.Fatalf("parse date %q: %v", s, err)
+	}
+	return d
+}
+
+func TestAge(t *testing.T) {
+	now := date(t, "2024-06-15")
+
+	tests := []struct {
+		dateOfBirth string
+		age         int
+	}{
+		{"2006-06-15", 18},
+		{"2006-06-16", 17},
+		{"2006-07-01", 17},
+		{"2006-05-31", 18},
+		{"2004-02-29", 20},
+	}
+
+	for _, tt := range tests {
+		if age := Age(date(t, tt.dateOfBirth), now); age != tt.age {
+			t.Errorf("Age(%s) = %d, expected %d", tt.dateOfBirth, age, tt.age)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	engine, err := NewEngineFromRules(Rules{
+		MinAge:      18,
+		Citizenship: CountryList{Allow: []string{"CHE", "FR", "US"}, Deny: []string{"US", "North Korea"}},
+	})
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+
+	now := date(t, "2024-06-15")
+
+	tests := []struct {
+		name    string
+		subject Subject
+		code    string
+	}{
+		{
+			name:    "eligible",
+			subject: Subject{DateOfBirth: date(t, "2000-01-01"), Citizenship: "CHE"},
+		},
+		{
+			name:    "underage",
+			subject: Subject{DateOfBirth: date(t, "2006-06-16"), Citizenship: "CHE"},
+			code:    CodeUnderage,
+		},
+		{
+			name:    "born in the future",
+			subject: Subject{DateOfBirth: date(t, "2030-01-01"), Citizenship: "CHE"},
+			code:    CodeInvalidDateOfBirth,
+		},
+		{
+			name:    "denied citizenship",
+			subject: Subject{DateOfBirth: date(t, "2000-01-01"), Citizenship: "USA"},
+			code:    CodeCitizenshipDenied,
+		},
+		{
+			name:    "citizenship not allowed",
+			subject: Subject{DateOfBirth: date(t, "2000-01-01"), Citizenship: "DEU"},
+			code:    CodeCitizenshipDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := engine.Evaluate(tt.subject, now)
+			if tt.code == "" {
+				if err != nil {
+					t.Errorf("expected no violation, got %v", err)
+				}
+				return
+			}
+
+			var violation *Violation
+			if !errors.As(err, &violation) {
+				t.Fatalf("expected violation %s, got %v", tt.code, err)
+			}
+			if violation.Code != tt.code {
+				t.Errorf("expected code %s, got %s", tt.code, violation.Code)
+			}
+		})
+	}
+}
+
+func TestNilEngineAcceptsEverything(t *testing.T) {
+	var engine *Engine
+	if err := engine.Evaluate(Subject{}, time.Now()); err != nil {
+		t.Errorf("expected no violation, got %v", err)
+	}
+}
+
+func TestUnknownCountry(t *testing.T) {
+	_, err := NewEngineFromRules(Rules{Citizenship: CountryList{Deny: []string{"Atlantis"}}})
+	if err == nil {
+		t.Error("expected an error for an unknown country")
+	}
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte("MinAge: 18\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	engine, err := NewEngine(path)
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+
+	now := date(t, "2024-06-15")
+	subject := Subject{DateOfBirth: date(t, "2004-01-01"), Citizenship: "CHE"}
+
+	if err := engine.Evaluate(subject, now); err != nil {
+		t.Fatalf("expected no violation, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("MinAge: 21\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// make sure the modification time differs on coarse grained file systems
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := engine.ReloadIfChanged()
+	if err != nil || !reloaded {
+		t.Fatalf("expected reload, got %v, %v", reloaded, err)
+	}
+
+	if err := engine.Evaluate(subject, now); err == nil {
+		t.Error("expected a violation after reload")
+	}
+
+	if err := os.WriteFile(path, []byte("MinAge: [\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Reload(); err == nil {
+		t.Error("expected an error for an invalid file")
+	}
+	if err := engine.Evaluate(subject, now); err == nil {
+		t.Error("expected the previous rules to stay in effect")
+	}
+}
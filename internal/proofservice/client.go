@@ -0,0 +1,162 @@
+// Package proofservice queries the merkle proof services in priority order, failing over to the next one
+// when a service does not answer, and to a local tree as a last resort.
+package proofservice
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	merkleproof "github.com/Galactica-corp/merkle-proof-service/gen/galactica/merkle"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// ErrNoService is returned when every merkle proof service failed to answer a query
+var ErrNoService = errors.New("no merkle proof service available")
+
+// callTimeout bounds a query to a service, so that a hanging service leaves time to the next ones
+const callTimeout = 30 * time.Second
+
+// localName names the local fallback in the health of the services
+const localName = "local tree"
+
+type service struct {
+	name   string
+	conn   *grpc.ClientConn
+	client merkleproof.QueryClient
+}
+
+// Client implements the QueryClient of the merkle proof service over several services.
+// The answers of a service, such as an unknown leaf, are never retried.
+type Client struct {
+	services []service
+	// local is queried when every service failed, it is nil when there is no local fallback
+	local merkleproof.QueryClient
+}
+
+var _ merkleproof.QueryClient = (*Client)(nil)
+
+// Dial connects to the services of urls, in decreasing priority, with TLS when useTLS is set.
+// local is the optional fallback queried when every service failed.
+func Dial(urls []string, useTLS bool, local merkleproof.QueryClient) (*Client, error) {
+	creds := insecure.NewCredentials()
+	if useTLS {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+
+	c := &Client{local: local}
+	for _, url := range urls {
+		conn, err := grpc.NewClient(url, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("connect to merkle proof service %s: %w", url, err)
+		}
+		c.services = append(c.services, service{name: url, conn: conn, client: merkleproof.NewQueryClient(conn)})
+	}
+	return c, nil
+}
+
+func (c *Client) Close() {
+	for _, s := range c.services {
+		if s.conn != nil {
+			_ = s.conn.Close()
+		}
+	}
+}
+
+func (c *Client) Proof(
+	ctx context.Context,
+	in *merkleproof.QueryProofRequest,
+	opts ...grpc.CallOption,
+) (*merkleproof.QueryProofResponse, error) {
+	return query(ctx, c, func(ctx context.Context, client merkleproof.QueryClient) (*merkleproof.QueryProofResponse, error) {
+		return client.Proof(ctx, in, opts...)
+	})
+}
+
+func (c *Client) GetEmptyLeafProof(
+	ctx context.Context,
+	in *merkleproof.GetEmptyLeafProofRequest,
+	opts ...grpc.CallOption,
+) (*merkleproof.GetEmptyLeafProofResponse, error) {
+	return query(ctx, c, func(ctx context.Context, client merkleproof.QueryClient) (*merkleproof.GetEmptyLeafProofResponse, error) {
+		return client.GetEmptyLeafProof(ctx, in, opts...)
+	})
+}
+
+// query runs f on the services in order until one answers, then on the local fallback.
+func query[T any](
+	ctx context.Context,
+	c *Client,
+	f func(context.Context, merkleproof.QueryClient) (T, error),
+) (T, error) {
+	var (
+		resp T
+		err  error
+	)
+	for _, s := range c.services {
+		callCtx, cancel := context.WithTimeout(ctx, callTimeout)
+		resp, err = f(callCtx, s.client)
+		cancel()
+		if !isServiceFailure(ctx, err) {
+			return resp, err
+		}
+		log.WithError(err).WithField("service", s.name).Warn("merkle proof service failed over")
+	}
+
+	if c.local != nil {
+		if len(c.services) > 0 {
+			log.WithError(err).Warn("merkle proof services down, using the local tree")
+		}
+		return f(ctx, c.local)
+	}
+	return resp, fmt.Errorf("%w: %w", ErrNoService, err)
+}
+
+// isServiceFailure tells whether err means that the service could not answer, rather than an answer
+// of the service. A service that is not in sync with the chain yet fails with FailedPrecondition.
+func isServiceFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.NotFound:
+		return false
+	default:
+		return true
+	}
+}
+
+// Health is the outcome of the probe of a service.
+type Health struct {
+	Service string
+	Err     error
+}
+
+// Probe asks every service, and the local fallback, for an empty leaf of the registry.
+// It returns their health in priority order.
+func (c *Client) Probe(ctx context.Context, registry string) []Health {
+	req := &merkleproof.GetEmptyLeafProofRequest{Registry: registry}
+
+	var health []Health
+	for _, s := range c.services {
+		callCtx, cancel := context.WithTimeout(ctx, callTimeout)
+		_, err := s.client.GetEmptyLeafProof(callCtx, req)
+		cancel()
+		health = append(health, Health{Service: s.name, Err: err})
+	}
+
+	if c.local != nil {
+		_, err := c.local.GetEmptyLeafProof(ctx, req)
+		health = append(health, Health{Service: localName, Err: err})
+	}
+	return health
+}
@@ -0,0 +1,124 @@
+package proofservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	merkleproof "github.com/Galactica-corp/merkle-proof-service/gen/galactica/merkle"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeService answers every query with its index, or with err when set.
+type fakeService struct {
+	index uint32
+	err   error
+	calls int
+}
+
+func (s *fakeService) Proof(
+	context.Context,
+	*merkleproof.QueryProofRequest,
+	...grpc.CallOption,
+) (*merkleproof.QueryProofResponse, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &merkleproof.QueryProofResponse{Proof: &merkleproof.Proof{Index: s.index}}, nil
+}
+
+func (s *fakeService) GetEmptyLeafProof(
+	context.Context,
+	*merkleproof.GetEmptyLeafProofRequest,
+	...grpc.CallOption,
+) (*merkleproof.GetEmptyLeafProofResponse, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &merkleproof.GetEmptyLeafProofResponse{Proof: &merkleproof.Proof{Index: s.index}}, nil
+}
+
+func newClient(local merkleproof.QueryClient, services ...*fakeService) *Client {
+	c := &Client{local: local}
+	for _, s := range services {
+		c.services = append(c.services, service{name: "fake", client: s})
+	}
+	return c
+}
+
+func TestFailover(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary, local := &fakeService{index: 1}, &fakeService{index: 2}, &fakeService{index: 3}
+	c := newClient(local, primary, secondary)
+
+	for _, tc := range []struct {
+		name      string
+		primary   error
+		secondary error
+		index     uint32
+		err       codes.Code
+	}{
+		{name: "primary up", index: 1},
+		{name: "primary down", primary: status.Error(codes.Unavailable, "down"), index: 2},
+		{name: "primary behind the chain", primary: status.Error(codes.FailedPrecondition, "not on head"), index: 2},
+		{
+			name:      "every service down",
+			primary:   status.Error(codes.Unavailable, "down"),
+			secondary: status.Error(codes.DeadlineExceeded, "timeout"),
+			index:     3,
+		},
+		{name: "unknown leaf", primary: status.Error(codes.NotFound, "leaf not found"), err: codes.NotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			primary.err, secondary.err = tc.primary, tc.secondary
+
+			resp, err := c.Proof(ctx, &merkleproof.QueryProofRequest{})
+			if status.Code(err) != tc.err {
+				t.Fatalf("expected %v, got %v", tc.err, err)
+			}
+			if err == nil && resp.Proof.Index != tc.index {
+				t.Errorf("expected the answer of service %d, got %d", tc.index, resp.Proof.Index)
+			}
+		})
+	}
+
+	if local.calls != 1 {
+		t.Errorf("expected the local tree to be queried once, got %d", local.calls)
+	}
+}
+
+func TestNoService(t *testing.T) {
+	down := status.Error(codes.Unavailable, "down")
+	c := newClient(nil, &fakeService{err: down})
+
+	_, err := c.GetEmptyLeafProof(context.Background(), &merkleproof.GetEmptyLeafProofRequest{})
+	if !errors.Is(err, ErrNoService) {
+		t.Errorf("expected %v, got %v", ErrNoService, err)
+	}
+}
+
+func TestDialUnreachable(t *testing.T) {
+	local := &fakeService{index: 3}
+	c, err := Dial([]string{"127.0.0.1:1"}, false, local)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+
+	resp, err := c.GetEmptyLeafProof(context.Background(), &merkleproof.GetEmptyLeafProofRequest{})
+	if err != nil {
+		t.Fatalf("empty leaf proof: %v", err)
+	}
+	if resp.Proof.Index != 3 {
+		t.Errorf("expected the answer of the local tree, got %d", resp.Proof.Index)
+	}
+
+	health := c.Probe(context.Background(), "")
+	if len(health) != 2 || health[0].Err == nil || health[1].Err != nil || health[1].Service != localName {
+		t.Errorf("unexpected health %+v", health)
+	}
+}
@@ -0,0 +1,131 @@
+// Package ratelimit limits how often a key may do something with token buckets kept in the guardian store,
+// so that the limits hold across restarts.
+package ratelimit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// DB key prefix of the buckets, followed by their name
+const keyPrefix = "ratelimit/"
+
+// Limit is a token bucket of Burst tokens refilled with Requests tokens every Period, a request taking a token.
+type Limit struct {
+	// Requests is the number of requests allowed per Period in the long run
+	Requests int
+	Period   time.Duration
+	// Burst is the number of requests allowed at once, Requests when zero
+	Burst int
+}
+
+func (l Limit) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// interval is the time to refill one token.
+func (l Limit) interval() time.Duration {
+	return l.Period / time.Duration(l.Requests)
+}
+
+// Result is the state of a bucket after a request took a token from it.
+type Result struct {
+	// Allowed is false when the bucket was empty, no token was taken then
+	Allowed bool
+	// Limit is the capacity of the bucket
+	Limit int
+	// Remaining is the number of tokens left
+	Remaining int
+	// Reset is how long before the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long before a token is available, zero when allowed
+	RetryAfter time.Duration
+}
+
+// bucket is the state of a bucket stored under its name.
+type bucket struct {
+	Tokens  float64   `json:"tokens"`
+	Updated time.Time `json:"updated"`
+}
+
+// Limiter takes the tokens from the buckets in the store.
+type Limiter struct {
+	db *badger.DB
+	// mu serializes the updates of the buckets, which would otherwise conflict
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+func NewLimiter(db *badger.DB) *Limiter {
+	return &Limiter{db: db, now: time.Now}
+}
+
+// Take takes a token from the bucket of name, created full under limit. The bucket is dropped from the store once
+// it would be full again.
+func (l *Limiter) Take(name string, limit Limit) (Result, error) {
+	if limit.Requests <= 0 || limit.Period <= 0 {
+		return Result{}, fmt.Errorf("invalid limit of %d requests per %s", limit.Requests, limit.Period)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := []byte(keyPrefix + name)
+	now := l.now()
+	capacity := limit.burst()
+
+	var result Result
+	err := l.db.Update(func(txn *badger.Txn) error {
+		b := bucket{Tokens: capacity, Updated: now}
+
+		item, err := txn.Get(key)
+		switch {
+		case errors.Is(err, badger.ErrKeyNotFound):
+		case err != nil:
+			return err
+		default:
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &b)
+			}); err != nil {
+				return fmt.Errorf("decode rate limit bucket %s: %w", name, err)
+			}
+			b.refill(now, limit, capacity)
+		}
+
+		result.Allowed = b.Tokens >= 1
+		if result.Allowed {
+			b.Tokens--
+		} else {
+			result.RetryAfter = time.Duration((1 - b.Tokens) * float64(limit.interval()))
+		}
+		result.Limit = int(capacity)
+		result.Remaining = int(math.Floor(b.Tokens))
+		result.Reset = time.Duration((capacity - b.Tokens) * float64(limit.interval()))
+
+		val, err := json.Marshal(b)
+		if err != nil {
+			return fmt.Errorf("marshal rate limit bucket: %w", err)
+		}
+		// badger expires the entries to the second
+		ttl := result.Reset.Truncate(time.Second) + time.Second
+		return txn.SetEntry(badger.NewEntry(key, val).WithTTL(ttl))
+	})
+	return result, err
+}
+
+// refill adds the tokens refilled since the last update, up to the capacity.
+func (b *bucket) refill(now time.Time, limit Limit, capacity float64) {
+	if elapsed := now.Sub(b.Updated); elapsed > 0 {
+		b.Tokens = min(b.Tokens+float64(elapsed)/float64(limit.interval()), capacity)
+	}
+	b.Updated = now
+}
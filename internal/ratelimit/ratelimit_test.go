@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+func openDB(t *testing.T, dir string) *badger.DB {
+	t.Helper()
+
+	db, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("open badger: %v", err)
+	}
+	return db
+}
+
+func TestTake(t *testing.T) {
+	dir := t.TempDir()
+	db := openDB(t, dir)
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(db)
+	limiter.now = func() time.Time { return now }
+
+	limit := Limit{Requests: 2, Period: time.Minute, Burst: 3}
+	take := func(l *Limiter, name string) Result {
+		t.Helper()
+		result, err := l.Take(name, limit)
+		if err != nil {
+			t.Fatalf("take: %v", err)
+		}
+		return result
+	}
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		if r := take(limiter, "user/1"); !r.Allowed || r.Limit != 3 || r.Remaining != remaining {
+			t.Fatalf("expected allowed with %d remaining, got %+v", remaining, r)
+		}
+	}
+
+	r := take(limiter, "user/1")
+	if r.Allowed || r.Remaining != 0 || r.RetryAfter != 30*time.Second || r.Reset != 90*time.Second {
+		t.Errorf("expected refused for 30s, got %+v", r)
+	}
+	if r := take(limiter, "user/2"); !r.Allowed {
+		t.Errorf("expected the buckets to be separate, got %+v", r)
+	}
+
+	// the buckets are kept across restarts
+	if err := db.Close(); err != nil {
+		t.Fatalf("close badger: %v", err)
+	}
+	db = openDB(t, dir)
+	t.Cleanup(func() { _ = db.Close() })
+	limiter = NewLimiter(db)
+	limiter.now = func() time.Time { return now }
+
+	now = now.Add(20 * time.Second)
+	if r := take(limiter, "user/1"); r.Allowed || r.RetryAfter != 10*time.Second {
+		t.Errorf("expected refused for 10s after the restart, got %+v", r)
+	}
+
+	now = now.Add(10 * time.Second)
+	if r := take(limiter, "user/1"); !r.Allowed || r.Remaining != 0 {
+		t.Errorf("expected the refilled token, got %+v", r)
+	}
+
+	now = now.Add(time.Hour)
+	if r := take(limiter, "user/1"); !r.Allowed || r.Remaining != 2 {
+		t.Errorf("expected the bucket full again, got %+v", r)
+	}
+
+	if _, err := limiter.Take("user/1", Limit{Period: time.Minute}); err == nil {
+		t.Error("expected an error for a limit without requests")
+	}
+}
@@ -0,0 +1,156 @@
+package registrytree
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/holiman/uint256"
+)
+
+// DB key prefix of the trees, followed by the registry address
+const keyPrefix = "registrytree/"
+
+// meta describes the tree of a registry, it is read from the registry once
+type meta struct {
+	Depth     int    `json:"depth"`
+	ZeroValue string `json:"zeroValue"`
+	InitBlock uint64 `json:"initBlock"`
+}
+
+// checkpoint is the progress of the indexing of a registry
+type checkpoint struct {
+	// Next is the first block whose events are not in the tree
+	Next uint64 `json:"next"`
+	// Hash is the hash of the block before Next, zero when no block was indexed
+	Hash common.Hash `json:"hash"`
+}
+
+// journal records the previous values of the keys written while indexing a block, to undo them on a reorg
+type journal struct {
+	Block uint64      `json:"block"`
+	Hash  common.Hash `json:"hash"`
+	Undo  []undo      `json:"undo,omitempty"`
+}
+
+type undo struct {
+	Key []byte `json:"key"`
+	// Value is the previous value of the key, nil when it was absent
+	Value []byte `json:"value,omitempty"`
+}
+
+type keys struct {
+	prefix []byte
+}
+
+func registryKeys(address common.Address) keys {
+	return keys{prefix: []byte(keyPrefix + address.Hex() + "/")}
+}
+
+func (k keys) key(parts ...[]byte) []byte {
+	key := append([]byte{}, k.prefix...)
+	for _, part := range parts {
+		key = append(key, part...)
+	}
+	return key
+}
+
+func (k keys) meta() []byte       { return k.key([]byte("meta")) }
+func (k keys) checkpoint() []byte { return k.key([]byte("checkpoint")) }
+func (k keys) journals() []byte   { return k.key([]byte("journal/")) }
+
+func (k keys) node(level int, index uint32) []byte {
+	return k.key([]byte("node/"), []byte{byte(level)}, binary.BigEndian.AppendUint32(nil, index))
+}
+
+func (k keys) leaf(value *uint256.Int) []byte {
+	b := value.Bytes32()
+	return k.key([]byte("leaf/"), b[:])
+}
+
+func (k keys) journal(block uint64) []byte {
+	return binary.BigEndian.AppendUint64(k.journals(), block)
+}
+
+// reader is a badger transaction, read-only or not.
+type reader interface {
+	Get(key []byte) (*badger.Item, error)
+}
+
+// get returns the value of key, nil when it is absent.
+func get(r reader, key []byte) ([]byte, error) {
+	item, err := r.Get(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item.ValueCopy(nil)
+}
+
+func getJSON(r reader, key []byte, v any) (bool, error) {
+	data, err := get(r, key)
+	if err != nil || data == nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("decode %s: %w", key, err)
+	}
+	return true, nil
+}
+
+func setJSON(txn *badger.Txn, key []byte, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return txn.Set(key, data)
+}
+
+// writer writes the keys of a block in a transaction, journaling their previous values.
+type writer struct {
+	txn     *badger.Txn
+	journal *journal
+	saved   map[string]bool
+}
+
+func newWriter(txn *badger.Txn, j *journal) *writer {
+	return &writer{txn: txn, journal: j, saved: make(map[string]bool)}
+}
+
+func (w *writer) Get(key []byte) (*badger.Item, error) {
+	return w.txn.Get(key)
+}
+
+func (w *writer) set(key, value []byte) error {
+	if err := w.save(key); err != nil {
+		return err
+	}
+	return w.txn.Set(key, value)
+}
+
+func (w *writer) delete(key []byte) error {
+	if err := w.save(key); err != nil {
+		return err
+	}
+	return w.txn.Delete(key)
+}
+
+// save journals the value of key before its first write.
+func (w *writer) save(key []byte) error {
+	if w.saved[string(key)] {
+		return nil
+	}
+	w.saved[string(key)] = true
+
+	value, err := get(w.txn, key)
+	if err != nil {
+		return err
+	}
+	w.journal.Undo = append(w.journal.Undo, undo{Key: key, Value: value})
+	return nil
+}
@@ -0,0 +1,663 @@
+// Package registrytree mirrors the Merkle trees of the certificate registries in the store, indexing the leaf
+// additions and revocations of the registries from the nodes. It answers the same queries as the merkle proof
+// service, so that certificates can be issued and revoked without it.
+package registrytree
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	merkleproof "github.com/Galactica-corp/merkle-proof-service/gen/galactica/merkle"
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/galactica-corp/guardians-sdk/pkg/contracts"
+	"github.com/holiman/uint256"
+	"github.com/iden3/go-iden3-crypto/poseidon"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// ErrRootMismatch is returned when the tree built from the events does not have the root of the registry
+	ErrRootMismatch = errors.New("merkle root differs from the registry")
+	// errReorgTooDeep means that no journaled block is still on the chain, the tree must be rebuilt
+	errReorgTooDeep = errors.New("reorg deeper than the journal")
+)
+
+const (
+	// logsRange is the number of blocks whose logs are queried at once
+	logsRange = 10_000
+	// emptyLeafDraws bounds the random draws of an empty leaf, as done by the merkle proof service
+	emptyLeafDraws = 10
+	// reorgWindow is how many blocks behind the checkpoint are journaled, a deeper reorg rebuilds the tree
+	reorgWindow = 128
+)
+
+// Backend is the access to the chain the trees are built from.
+type Backend interface {
+	bind.ContractCaller
+	BlockNumber(ctx context.Context) (uint64, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+}
+
+// Trees keeps the trees of the registries in the store. A tree is built the first time its registry is queried
+// or tracked, and brought up to date with the events of the new blocks at every query and by Watch.
+// Trees implements the QueryClient of the merkle proof service.
+//
+// Every indexed block is recorded in a checkpoint, from which the indexing resumes after a restart. The changes
+// of the last reorgWindow blocks are journaled: when the last indexed block is no longer on the chain, the blocks
+// are undone down to the last one still on it. As a last guard, the tree is rebuilt from scratch when its root
+// differs from the root of the registry.
+type Trees struct {
+	db      *badger.DB
+	backend Backend
+
+	mu    sync.Mutex
+	trees map[common.Address]*tree
+}
+
+func New(db *badger.DB, backend Backend) *Trees {
+	return &Trees{db: db, backend: backend, trees: make(map[common.Address]*tree)}
+}
+
+var _ merkleproof.QueryClient = (*Trees)(nil)
+
+// Track adds the registry to the ones indexed by Watch.
+func (t *Trees) Track(address common.Address) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.trees[address]; !ok {
+		t.trees[address] = nil
+	}
+}
+
+// Watch brings the trees up to date every interval until ctx is done.
+func (t *Trees) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		t.mu.Lock()
+		addresses := make([]common.Address, 0, len(t.trees))
+		for address := range t.trees {
+			addresses = append(addresses, address)
+		}
+		t.mu.Unlock()
+
+		for _, address := range addresses {
+			if _, err := t.sync(ctx, address.Hex()); err != nil && ctx.Err() == nil {
+				log.WithError(err).WithField("registry", address).Warn("index registry tree")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Proof returns the proof of the leaf of the request.
+func (t *Trees) Proof(
+	ctx context.Context,
+	req *merkleproof.QueryProofRequest,
+	_ ...grpc.CallOption,
+) (*merkleproof.QueryProofResponse, error) {
+	leaf, err := uint256.FromDecimal(req.Leaf)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid leaf value, must be a decimal number: %s", req.Leaf)
+	}
+
+	tr, err := t.sync(ctx, req.Registry)
+	if err != nil {
+		return nil, err
+	}
+
+	var proof *merkleproof.Proof
+	err = t.db.View(func(txn *badger.Txn) error {
+		value, err := get(txn, tr.keys.leaf(leaf))
+		if err != nil {
+			return err
+		}
+		if len(value) != 4 {
+			return status.Errorf(codes.NotFound, "leaf not found: %s", req.Leaf)
+		}
+
+		proof, err = tr.proof(txn, binary.BigEndian.Uint32(value))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &merkleproof.QueryProofResponse{Proof: proof}, nil
+}
+
+// GetEmptyLeafProof returns the proof of a random leaf that was never used.
+func (t *Trees) GetEmptyLeafProof(
+	ctx context.Context,
+	req *merkleproof.GetEmptyLeafProofRequest,
+	_ ...grpc.CallOption,
+) (*merkleproof.GetEmptyLeafProofResponse, error) {
+	tr, err := t.sync(ctx, req.Registry)
+	if err != nil {
+		return nil, err
+	}
+
+	var proof *merkleproof.Proof
+	err = t.db.View(func(txn *badger.Txn) error {
+		// a random leaf spreads the guardians issuing at the same time over different leaves
+		for range emptyLeafDraws {
+			index := uint32(rand.Uint64N(1 << tr.depth))
+			used, err := get(txn, tr.keys.node(0, index))
+			if err != nil {
+				return err
+			}
+			if used == nil {
+				proof, err = tr.proof(txn, index)
+				return err
+			}
+		}
+		return status.Errorf(codes.Internal, "could not find an empty leaf of %s", req.Registry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &merkleproof.GetEmptyLeafProofResponse{Proof: proof}, nil
+}
+
+// sync returns the tree of the registry, up to date with the head of the chain.
+func (t *Trees) sync(ctx context.Context, registry string) (*tree, error) {
+	if !common.IsHexAddress(registry) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid registry address, must be a hex string: %s", registry)
+	}
+	address := common.HexToAddress(registry)
+
+	caller, err := contracts.NewZkCertificateRegistryCaller(address, t.backend)
+	if err != nil {
+		return nil, fmt.Errorf("bind registry: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		tr := t.trees[address]
+		if tr == nil {
+			if tr, err = t.open(ctx, caller, address); err != nil {
+				return nil, err
+			}
+			t.trees[address] = tr
+		}
+
+		head, err := t.update(ctx, tr)
+		if err == nil {
+			err = t.checkRoot(ctx, tr, caller, head)
+		}
+		if err == nil {
+			return tr, nil
+		}
+
+		if attempt > 0 || !(errors.Is(err, ErrRootMismatch) || errors.Is(err, errReorgTooDeep)) {
+			return nil, err
+		}
+
+		log.WithError(err).WithField("registry", address).Warn("rebuilding the registry tree")
+		if err := t.db.DropPrefix(tr.keys.prefix); err != nil {
+			return nil, fmt.Errorf("drop registry tree: %w", err)
+		}
+		t.trees[address] = nil
+	}
+}
+
+// tree is the sparse Merkle tree of a registry, only its non empty nodes are stored.
+type tree struct {
+	address common.Address
+	keys    keys
+	depth   int
+	// emptyNodes are the values of the empty nodes of every level, the leaves being level 0
+	emptyNodes []*uint256.Int
+	initBlock  uint64
+}
+
+// open loads the description of the tree from the store, or from the registry the first time.
+func (t *Trees) open(ctx context.Context, caller *contracts.ZkCertificateRegistryCaller, address common.Address) (*tree, error) {
+	k := registryKeys(address)
+
+	var m meta
+	var found bool
+	err := t.db.View(func(txn *badger.Txn) error {
+		var err error
+		found, err = getJSON(txn, k.meta(), &m)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read registry tree: %w", err)
+	}
+
+	if !found {
+		if m, err = readMeta(ctx, caller); err != nil {
+			return nil, err
+		}
+		if err := t.db.Update(func(txn *badger.Txn) error { return setJSON(txn, k.meta(), m) }); err != nil {
+			return nil, fmt.Errorf("store registry tree: %w", err)
+		}
+	}
+
+	zeroValue, err := uint256.FromDecimal(m.ZeroValue)
+	if err != nil {
+		return nil, fmt.Errorf("decode empty leaf value: %w", err)
+	}
+
+	tr := &tree{
+		address:    address,
+		keys:       k,
+		depth:      m.Depth,
+		emptyNodes: make([]*uint256.Int, m.Depth+1),
+		initBlock:  m.InitBlock,
+	}
+	tr.emptyNodes[0] = zeroValue
+	for level := 1; level <= m.Depth; level++ {
+		tr.emptyNodes[level] = hash(tr.emptyNodes[level-1], tr.emptyNodes[level-1])
+	}
+	return tr, nil
+}
+
+func readMeta(ctx context.Context, caller *contracts.ZkCertificateRegistryCaller) (meta, error) {
+	opts := &bind.CallOpts{Context: ctx}
+
+	depth, err := caller.TreeDepth(opts)
+	if err != nil {
+		return meta{}, fmt.Errorf("retrieve tree depth: %w", err)
+	}
+	if !depth.IsUint64() || depth.Uint64() < 1 || depth.Uint64() > 32 {
+		return meta{}, fmt.Errorf("unsupported tree depth %s", depth)
+	}
+
+	zeroValue, err := caller.ZEROVALUE(opts)
+	if err != nil {
+		return meta{}, fmt.Errorf("retrieve empty leaf value: %w", err)
+	}
+
+	initBlock, err := caller.InitBlockHeight(opts)
+	if err != nil {
+		return meta{}, fmt.Errorf("retrieve registry init block: %w", err)
+	}
+
+	return meta{
+		Depth:     int(depth.Uint64()),
+		ZeroValue: new(uint256.Int).SetBytes32(zeroValue[:]).Dec(),
+		InitBlock: initBlock.Uint64(),
+	}, nil
+}
+
+// update adds the events of the blocks up to the head of the chain to the tree, after undoing the blocks
+// that left the chain. It returns the head.
+func (t *Trees) update(ctx context.Context, tr *tree) (uint64, error) {
+	cp, err := t.checkpoint(tr)
+	if err != nil {
+		return 0, err
+	}
+
+	if cp, err = t.rollback(ctx, tr, cp); err != nil {
+		return 0, err
+	}
+
+	head, err := t.backend.BlockNumber(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("retrieve head block: %w", err)
+	}
+
+	filterer, err := contracts.NewZkCertificateRegistryFilterer(tr.address, nil)
+	if err != nil {
+		return 0, fmt.Errorf("bind registry: %w", err)
+	}
+
+	for from := cp.Next; from <= head; from += logsRange {
+		to := min(from+logsRange-1, head)
+		logs, err := t.backend.FilterLogs(ctx, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(from),
+			ToBlock:   new(big.Int).SetUint64(to),
+			Addresses: []common.Address{tr.address},
+			Topics:    [][]common.Hash{{additionTopic, revocationTopic}},
+		})
+		if err != nil {
+			return 0, fmt.Errorf("retrieve registry events of blocks %d to %d: %w", from, to, err)
+		}
+
+		indexed := false
+		for len(logs) > 0 {
+			n := 1
+			for n < len(logs) && logs[n].BlockNumber == logs[0].BlockNumber {
+				n++
+			}
+			if err := t.index(tr, filterer, logs[0].BlockNumber, logs[0].BlockHash, logs[:n]); err != nil {
+				return 0, err
+			}
+			indexed = logs[0].BlockNumber == to
+			logs = logs[n:]
+		}
+		if indexed {
+			continue
+		}
+
+		// the last block is checkpointed even without events, to detect its reorg
+		header, err := t.backend.HeaderByNumber(ctx, new(big.Int).SetUint64(to))
+		if err != nil {
+			return 0, fmt.Errorf("retrieve block %d: %w", to, err)
+		}
+		if err := t.index(tr, filterer, to, header.Hash(), nil); err != nil {
+			return 0, err
+		}
+	}
+
+	return head, t.prune(tr, head)
+}
+
+func (t *Trees) checkpoint(tr *tree) (checkpoint, error) {
+	cp := checkpoint{Next: tr.initBlock}
+	err := t.db.View(func(txn *badger.Txn) error {
+		_, err := getJSON(txn, tr.keys.checkpoint(), &cp)
+		return err
+	})
+	if err != nil {
+		return checkpoint{}, fmt.Errorf("read registry tree checkpoint: %w", err)
+	}
+	return cp, nil
+}
+
+// index applies the events of the block to the tree, journals its changes and moves the checkpoint past it,
+// all at once.
+func (t *Trees) index(tr *tree, filterer *contracts.ZkCertificateRegistryFilterer, block uint64, hash common.Hash, logs []types.Log) error {
+	err := t.db.Update(func(txn *badger.Txn) error {
+		j := &journal{Block: block, Hash: hash}
+		w := newWriter(txn, j)
+		for _, l := range logs {
+			if err := tr.apply(w, filterer, l); err != nil {
+				return err
+			}
+		}
+
+		if err := setJSON(txn, tr.keys.journal(block), j); err != nil {
+			return err
+		}
+		return setJSON(txn, tr.keys.checkpoint(), checkpoint{Next: block + 1, Hash: hash})
+	})
+	if err != nil {
+		return fmt.Errorf("index block %d: %w", block, err)
+	}
+	return nil
+}
+
+// rollback undoes the journaled blocks that are no longer on the chain and returns the checkpoint after
+// the last block still on it.
+func (t *Trees) rollback(ctx context.Context, tr *tree, cp checkpoint) (checkpoint, error) {
+	if cp.Hash == (common.Hash{}) {
+		return cp, nil
+	}
+
+	onChain, err := t.onChain(ctx, cp.Next-1, cp.Hash)
+	if err != nil || onChain {
+		return cp, err
+	}
+
+	var journals []journal
+	err = t.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: tr.keys.journals(), Reverse: true, PrefetchValues: true})
+		defer it.Close()
+
+		for it.Seek(append(tr.keys.journals(), 0xff)); it.Valid(); it.Next() {
+			var j journal
+			if _, err := getJSON(txn, it.Item().KeyCopy(nil), &j); err != nil {
+				return err
+			}
+			journals = append(journals, j)
+		}
+		return nil
+	})
+	if err != nil {
+		return checkpoint{}, fmt.Errorf("read registry tree journal: %w", err)
+	}
+
+	for i, j := range journals {
+		onChain, err := t.onChain(ctx, j.Block, j.Hash)
+		if err != nil {
+			return checkpoint{}, err
+		}
+		if !onChain {
+			continue
+		}
+
+		log.WithField("registry", tr.address).WithField("from", j.Block+1).WithField("to", cp.Next-1).
+			Warn("reorg, undoing the blocks of the registry tree")
+		cp = checkpoint{Next: j.Block + 1, Hash: j.Hash}
+		err = t.db.Update(func(txn *badger.Txn) error {
+			for _, undone := range journals[:i] {
+				for _, u := range undone.Undo {
+					var err error
+					if u.Value == nil {
+						err = txn.Delete(u.Key)
+					} else {
+						err = txn.Set(u.Key, u.Value)
+					}
+					if err != nil {
+						return err
+					}
+				}
+				if err := txn.Delete(tr.keys.journal(undone.Block)); err != nil {
+					return err
+				}
+			}
+			return setJSON(txn, tr.keys.checkpoint(), cp)
+		})
+		if err != nil {
+			return checkpoint{}, fmt.Errorf("undo registry tree blocks: %w", err)
+		}
+		return cp, nil
+	}
+
+	return checkpoint{}, fmt.Errorf("%w, block %d left the chain", errReorgTooDeep, cp.Next-1)
+}
+
+// onChain tells whether the block of the chain at number has hash.
+func (t *Trees) onChain(ctx context.Context, number uint64, hash common.Hash) (bool, error) {
+	header, err := t.backend.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+	if errors.Is(err, ethereum.NotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("retrieve block %d: %w", number, err)
+	}
+	return header.Hash() == hash, nil
+}
+
+// prune deletes the journal of the blocks more than reorgWindow blocks behind head.
+func (t *Trees) prune(tr *tree, head uint64) error {
+	if head < reorgWindow {
+		return nil
+	}
+
+	end := tr.keys.journal(head - reorgWindow)
+	var stale [][]byte
+	err := t.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: tr.keys.journals()})
+		defer it.Close()
+
+		for it.Rewind(); it.Valid() && string(it.Item().Key()) < string(end); it.Next() {
+			stale = append(stale, it.Item().KeyCopy(nil))
+		}
+		return nil
+	})
+	if err != nil || len(stale) == 0 {
+		return err
+	}
+
+	return t.db.Update(func(txn *badger.Txn) error {
+		for _, key := range stale {
+			if err := txn.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// apply adds or removes the leaf of the event.
+func (tr *tree) apply(w *writer, filterer *contracts.ZkCertificateRegistryFilterer, l types.Log) error {
+	if l.Removed || len(l.Topics) == 0 {
+		return nil
+	}
+
+	switch l.Topics[0] {
+	case additionTopic:
+		event, err := filterer.ParseZkCertificateAddition(l)
+		if err != nil {
+			return fmt.Errorf("parse addition: %w", err)
+		}
+		index, err := tr.leafIndex(event.Index)
+		if err != nil {
+			return err
+		}
+		leaf := new(uint256.Int).SetBytes32(event.ZkCertificateLeafHash[:])
+		if err := tr.set(w, index, leaf); err != nil {
+			return err
+		}
+		return w.set(tr.keys.leaf(leaf), binary.BigEndian.AppendUint32(nil, index))
+
+	case revocationTopic:
+		event, err := filterer.ParseZkCertificateRevocation(l)
+		if err != nil {
+			return fmt.Errorf("parse revocation: %w", err)
+		}
+		index, err := tr.leafIndex(event.Index)
+		if err != nil {
+			return err
+		}
+		// the revoked leaf is set to the empty value but is not reused
+		if err := tr.set(w, index, tr.emptyNodes[0]); err != nil {
+			return err
+		}
+		return w.delete(tr.keys.leaf(new(uint256.Int).SetBytes32(event.ZkCertificateLeafHash[:])))
+	}
+	return nil
+}
+
+func (tr *tree) leafIndex(index *big.Int) (uint32, error) {
+	if !index.IsUint64() || index.Uint64() >= 1<<tr.depth {
+		return 0, fmt.Errorf("leaf index %s out of a tree of depth %d", index, tr.depth)
+	}
+	return uint32(index.Uint64()), nil
+}
+
+// set sets the leaf and updates its ancestors.
+func (tr *tree) set(w *writer, index uint32, leaf *uint256.Int) error {
+	node := leaf
+	for level := 0; ; level++ {
+		value := node.Bytes32()
+		if err := w.set(tr.keys.node(level, index), value[:]); err != nil {
+			return err
+		}
+		if level == tr.depth {
+			return nil
+		}
+
+		sibling, err := tr.node(w, level, index^1)
+		if err != nil {
+			return err
+		}
+		if index%2 == 0 {
+			node = hash(node, sibling)
+		} else {
+			node = hash(sibling, node)
+		}
+		index /= 2
+	}
+}
+
+func (tr *tree) node(r reader, level int, index uint32) (*uint256.Int, error) {
+	value, err := get(r, tr.keys.node(level, index))
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return tr.emptyNodes[level], nil
+	}
+	return new(uint256.Int).SetBytes(value), nil
+}
+
+func (tr *tree) proof(r reader, index uint32) (*merkleproof.Proof, error) {
+	leaf, err := tr.node(r, 0, index)
+	if err != nil {
+		return nil, err
+	}
+
+	path := make([]string, tr.depth)
+	for level := range tr.depth {
+		sibling, err := tr.node(r, level, (index>>level)^1)
+		if err != nil {
+			return nil, err
+		}
+		path[level] = sibling.Dec()
+	}
+
+	root, err := tr.node(r, tr.depth, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &merkleproof.Proof{Leaf: leaf.Dec(), Path: path, Index: index, Root: root.Dec()}, nil
+}
+
+// checkRoot compares the root of the tree to the one of the registry at the head block.
+func (t *Trees) checkRoot(ctx context.Context, tr *tree, caller *contracts.ZkCertificateRegistryCaller, head uint64) error {
+	registryRoot, err := caller.MerkleRoot(&bind.CallOpts{Context: ctx, BlockNumber: new(big.Int).SetUint64(head)})
+	if err != nil {
+		return fmt.Errorf("retrieve registry merkle root: %w", err)
+	}
+
+	var root *uint256.Int
+	err = t.db.View(func(txn *badger.Txn) error {
+		root, err = tr.node(txn, tr.depth, 0)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("read registry tree root: %w", err)
+	}
+
+	if expected := new(uint256.Int).SetBytes32(registryRoot[:]); !expected.Eq(root) {
+		return fmt.Errorf("%w at block %d: %s instead of %s", ErrRootMismatch, head, root.Dec(), expected.Dec())
+	}
+	return nil
+}
+
+func hash(left, right *uint256.Int) *uint256.Int {
+	h, err := poseidon.Hash([]*big.Int{left.ToBig(), right.ToBig()})
+	if err != nil {
+		// only happens for inputs out of the field, which the hashes and the empty value are not
+		panic(fmt.Sprintf("poseidon hash: %v", err))
+	}
+	return uint256.MustFromBig(h)
+}
+
+var additionTopic, revocationTopic = registryEventTopics()
+
+func registryEventTopics() (common.Hash, common.Hash) {
+	registryABI, err := contracts.ZkCertificateRegistryMetaData.GetAbi()
+	if err != nil {
+		panic(fmt.Sprintf("parse registry abi: %v", err))
+	}
+	return registryABI.Events["zkCertificateAddition"].ID, registryABI.Events["zkCertificateRevocation"].ID
+}
@@ -0,0 +1,359 @@
+package registrytree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	merkleproof "github.com/Galactica-corp/merkle-proof-service/gen/galactica/merkle"
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/galactica-corp/guardians-sdk/pkg/contracts"
+	"github.com/holiman/uint256"
+	"github.com/iden3/go-iden3-crypto/ff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var testRegistry = common.HexToAddress("0x68272A56A0e9b095E5606fDD8b6c297702C0dfe5")
+
+// fakeChain answers the registry calls and events of a registry of depth 4 from its leaves.
+type fakeChain struct {
+	head uint64
+	logs []types.Log
+	// forks changes the hash of the blocks from the key on, to simulate reorgs
+	forks map[uint64]byte
+	// from are the first blocks of the log queries
+	from []uint64
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (c *fakeChain) count(method string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls[method]
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{head: 100, forks: make(map[uint64]byte), calls: make(map[string]int)}
+}
+
+func newTrees(t *testing.T, chain *fakeChain) (*Trees, *badger.DB) {
+	t.Helper()
+
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return New(db, chain), db
+}
+
+func (c *fakeChain) header(number uint64) *types.Header {
+	var fork byte
+	for block, f := range c.forks {
+		if number >= block && f > fork {
+			fork = f
+		}
+	}
+	return &types.Header{Number: new(big.Int).SetUint64(number), Extra: []byte{fork}}
+}
+
+func (c *fakeChain) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	if number.Uint64() > c.head {
+		return nil, ethereum.NotFound
+	}
+	return c.header(number.Uint64()), nil
+}
+
+func zeroValue() *uint256.Int {
+	v := new(big.Int).SetBytes(crypto.Keccak256([]byte("Galactica")))
+	return uint256.MustFromBig(v.Mod(v, ff.Modulus()))
+}
+
+func (c *fakeChain) event(topic common.Hash, block uint64, index uint64, leaf *uint256.Int) {
+	c.logs = append(c.logs, types.Log{
+		Address:     testRegistry,
+		Topics:      []common.Hash{topic, leaf.Bytes32(), common.BytesToHash(testRegistry.Bytes())},
+		Data:        common.BigToHash(new(big.Int).SetUint64(index)).Bytes(),
+		BlockNumber: block,
+	})
+}
+
+// root computes the root of the registry from scratch, with every node of the tree.
+func (c *fakeChain) root() *uint256.Int {
+	level := make([]*uint256.Int, 16)
+	for i := range level {
+		level[i] = zeroValue()
+	}
+	for _, l := range c.logs {
+		index := new(big.Int).SetBytes(l.Data).Uint64()
+		if l.Topics[0] == additionTopic {
+			level[index] = new(uint256.Int).SetBytes32(l.Topics[1][:])
+		} else {
+			level[index] = zeroValue()
+		}
+	}
+
+	for len(level) > 1 {
+		parents := make([]*uint256.Int, len(level)/2)
+		for i := range parents {
+			parents[i] = hash(level[2*i], level[2*i+1])
+		}
+		level = parents
+	}
+	return level[0]
+}
+
+func (c *fakeChain) BlockNumber(context.Context) (uint64, error) {
+	return c.head, nil
+}
+
+func (c *fakeChain) FilterLogs(_ context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	c.from = append(c.from, q.FromBlock.Uint64())
+
+	var logs []types.Log
+	for _, l := range c.logs {
+		if l.BlockNumber >= q.FromBlock.Uint64() && l.BlockNumber <= q.ToBlock.Uint64() {
+			l.BlockHash = c.header(l.BlockNumber).Hash()
+			logs = append(logs, l)
+		}
+	}
+	return logs, nil
+}
+
+func (c *fakeChain) CodeAt(context.Context, common.Address, *big.Int) ([]byte, error) {
+	return []byte{1}, nil
+}
+
+func (c *fakeChain) CallContract(_ context.Context, msg ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	registryABI, err := contracts.ZkCertificateRegistryMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+
+	method, err := registryABI.MethodById(msg.Data[:4])
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.calls[method.Name]++
+	c.mu.Unlock()
+
+	switch method.Name {
+	case "treeDepth":
+		return method.Outputs.Pack(big.NewInt(4))
+	case "ZERO_VALUE":
+		return method.Outputs.Pack(zeroValue().Bytes32())
+	case "initBlockHeight":
+		return method.Outputs.Pack(big.NewInt(10))
+	case "merkleRoot":
+		return method.Outputs.Pack(c.root().Bytes32())
+	default:
+		return nil, fmt.Errorf("unexpected call of %s", method.Name)
+	}
+}
+
+// verify recomputes the root from the proof.
+func verify(t *testing.T, proof *merkleproof.Proof) {
+	t.Helper()
+
+	node := uint256.MustFromDecimal(proof.Leaf)
+	index := proof.Index
+	for _, sibling := range proof.Path {
+		if index%2 == 0 {
+			node = hash(node, uint256.MustFromDecimal(sibling))
+		} else {
+			node = hash(uint256.MustFromDecimal(sibling), node)
+		}
+		index /= 2
+	}
+
+	if node.Dec() != proof.Root {
+		t.Errorf("proof of leaf %d leads to %s instead of the root %s", proof.Index, node.Dec(), proof.Root)
+	}
+}
+
+func TestProofs(t *testing.T) {
+	chain := newFakeChain()
+	first, second := uint256.NewInt(1001), uint256.NewInt(1002)
+	chain.event(additionTopic, 20, 3, first)
+	chain.event(additionTopic, 30, 12, second)
+	chain.event(additionTopic, 40, 7, uint256.NewInt(1003))
+	chain.event(revocationTopic, 50, 7, uint256.NewInt(1003))
+
+	trees, _ := newTrees(t, chain)
+	ctx := context.Background()
+
+	resp, err := trees.Proof(ctx, &merkleproof.QueryProofRequest{Registry: testRegistry.Hex(), Leaf: second.Dec()})
+	if err != nil {
+		t.Fatalf("proof: %v", err)
+	}
+	if resp.Proof.Index != 12 || resp.Proof.Root != chain.root().Dec() {
+		t.Errorf("unexpected proof %+v", resp.Proof)
+	}
+	verify(t, resp.Proof)
+
+	_, err = trees.Proof(ctx, &merkleproof.QueryProofRequest{Registry: testRegistry.Hex(), Leaf: "1003"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected the revoked leaf not to be found, got %v", err)
+	}
+
+	// the tree is brought up to date with the new events only
+	chain.head = 200
+	chain.event(additionTopic, 150, 0, uint256.NewInt(1004))
+	resp, err = trees.Proof(ctx, &merkleproof.QueryProofRequest{Registry: testRegistry.Hex(), Leaf: first.Dec()})
+	if err != nil {
+		t.Fatalf("proof: %v", err)
+	}
+	verify(t, resp.Proof)
+	if resp.Proof.Root != chain.root().Dec() {
+		t.Errorf("expected root %s, got %s", chain.root().Dec(), resp.Proof.Root)
+	}
+	if chain.from[len(chain.from)-1] != 101 {
+		t.Errorf("expected the indexing to resume at block 101, got %d", chain.from[len(chain.from)-1])
+	}
+
+	for range 20 {
+		resp, err := trees.GetEmptyLeafProof(ctx, &merkleproof.GetEmptyLeafProofRequest{Registry: testRegistry.Hex()})
+		if err != nil {
+			t.Fatalf("empty leaf proof: %v", err)
+		}
+		switch resp.Proof.Index {
+		case 0, 3, 7, 12:
+			t.Fatalf("expected an unused leaf, got %d", resp.Proof.Index)
+		}
+		if resp.Proof.Leaf != zeroValue().Dec() {
+			t.Errorf("expected an empty leaf, got %s", resp.Proof.Leaf)
+		}
+		verify(t, resp.Proof)
+	}
+}
+
+func TestResumeFromCheckpoint(t *testing.T) {
+	chain := newFakeChain()
+	chain.event(additionTopic, 20, 3, uint256.NewInt(1001))
+
+	trees, db := newTrees(t, chain)
+	trees.Track(testRegistry)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		trees.Watch(ctx, time.Hour)
+		close(done)
+	}()
+	for chain.count("merkleRoot") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	// a restart resumes from the checkpoint, with the description of the tree from the store
+	chain.head = 150
+	chain.event(additionTopic, 120, 4, uint256.NewInt(1002))
+	restarted := New(db, chain)
+
+	resp, err := restarted.Proof(context.Background(), &merkleproof.QueryProofRequest{Registry: testRegistry.Hex(), Leaf: "1002"})
+	if err != nil {
+		t.Fatalf("proof: %v", err)
+	}
+	verify(t, resp.Proof)
+	if resp.Proof.Root != chain.root().Dec() {
+		t.Errorf("expected root %s, got %s", chain.root().Dec(), resp.Proof.Root)
+	}
+	if len(chain.from) != 2 || chain.from[0] != 10 || chain.from[1] != 101 {
+		t.Errorf("expected the blocks to be indexed once from the init block, got queries from %v", chain.from)
+	}
+	if chain.count("treeDepth") != 1 {
+		t.Errorf("expected the registry to be described once, got %d", chain.count("treeDepth"))
+	}
+}
+
+func TestReorg(t *testing.T) {
+	chain := newFakeChain()
+	chain.event(additionTopic, 20, 3, uint256.NewInt(1001))
+	chain.event(additionTopic, 95, 5, uint256.NewInt(1002))
+
+	trees, _ := newTrees(t, chain)
+	ctx := context.Background()
+
+	if _, err := trees.Proof(ctx, &merkleproof.QueryProofRequest{Registry: testRegistry.Hex(), Leaf: "1002"}); err != nil {
+		t.Fatalf("proof: %v", err)
+	}
+
+	// the blocks from 90 are replaced, the leaf of block 95 is now added at block 98 to another index
+	chain.forks[90] = 1
+	chain.logs = chain.logs[:1]
+	chain.event(additionTopic, 98, 9, uint256.NewInt(1002))
+	chain.head = 110
+
+	resp, err := trees.Proof(ctx, &merkleproof.QueryProofRequest{Registry: testRegistry.Hex(), Leaf: "1002"})
+	if err != nil {
+		t.Fatalf("proof after reorg: %v", err)
+	}
+	verify(t, resp.Proof)
+	if resp.Proof.Index != 9 || resp.Proof.Root != chain.root().Dec() {
+		t.Errorf("unexpected proof after reorg %+v", resp.Proof)
+	}
+	if from := chain.from[len(chain.from)-1]; from != 21 {
+		t.Errorf("expected the blocks after the last one still on the chain to be indexed again, got %d", from)
+	}
+	if chain.count("treeDepth") != 1 {
+		t.Errorf("expected the reorg to be undone without a rebuild")
+	}
+
+	// a reorg deeper than the journal rebuilds the tree
+	chain.head = 400
+	if _, err := trees.Proof(ctx, &merkleproof.QueryProofRequest{Registry: testRegistry.Hex(), Leaf: "1002"}); err != nil {
+		t.Fatalf("proof: %v", err)
+	}
+	chain.forks[15] = 2
+	if _, err := trees.Proof(ctx, &merkleproof.QueryProofRequest{Registry: testRegistry.Hex(), Leaf: "1002"}); err != nil {
+		t.Fatalf("proof after a deep reorg: %v", err)
+	}
+	if chain.count("treeDepth") != 2 {
+		t.Errorf("expected the tree to be rebuilt")
+	}
+}
+
+func TestRebuildOnRootMismatch(t *testing.T) {
+	chain := newFakeChain()
+	chain.event(additionTopic, 20, 3, uint256.NewInt(1001))
+
+	trees, _ := newTrees(t, chain)
+	ctx := context.Background()
+
+	if _, err := trees.Proof(ctx, &merkleproof.QueryProofRequest{Registry: testRegistry.Hex(), Leaf: "1001"}); err != nil {
+		t.Fatalf("proof: %v", err)
+	}
+
+	// the events changed without a change of the block hashes
+	chain.logs = nil
+	chain.event(additionTopic, 20, 5, uint256.NewInt(2001))
+
+	resp, err := trees.Proof(ctx, &merkleproof.QueryProofRequest{Registry: testRegistry.Hex(), Leaf: "2001"})
+	if err != nil {
+		t.Fatalf("proof after rebuild: %v", err)
+	}
+	verify(t, resp.Proof)
+	if resp.Proof.Index != 5 || resp.Proof.Root != chain.root().Dec() {
+		t.Errorf("unexpected proof after rebuild %+v", resp.Proof)
+	}
+
+	// a root that can't be reached from the events is reported, here a leaf before the init block
+	chain.event(additionTopic, 5, 1, uint256.NewInt(3001))
+	_, err = trees.Proof(ctx, &merkleproof.QueryProofRequest{Registry: testRegistry.Hex(), Leaf: "2001"})
+	if !errors.Is(err, ErrRootMismatch) {
+		t.Errorf("expected %v, got %v", ErrRootMismatch, err)
+	}
+}
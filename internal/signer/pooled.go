@@ -0,0 +1,89 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/keys"
+)
+
+// ErrDuplicateProvider is returned when a provider key of the pool has the address of another one
+var ErrDuplicateProvider = errors.New("provider address already in the pool")
+
+// Pooled signs the transactions with its own provider key and the certificates with the keys of the guardian
+// signer, so that the transactions of the guardian are sent from several provider addresses in parallel.
+type Pooled struct {
+	Signer
+	providerKey *ecdsa.PrivateKey
+	address     common.Address
+}
+
+var _ Signer = (*Pooled)(nil)
+
+// NewPooled returns the signer of the transactions of providerKey and of the certificates of guardian.
+func NewPooled(guardian Signer, providerKey *ecdsa.PrivateKey) *Pooled {
+	return &Pooled{
+		Signer:      guardian,
+		providerKey: providerKey,
+		address:     crypto.PubkeyToAddress(providerKey.PublicKey),
+	}
+}
+
+// Destroy zeroes the provider key of the signer, the keys of the guardian signer are left untouched.
+func (p *Pooled) Destroy() {
+	keys.ZeroECDSA(p.providerKey)
+}
+
+func (p *Pooled) Address() common.Address {
+	return p.address
+}
+
+func (p *Pooled) SignTx(_ context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), p.providerKey)
+}
+
+// LoadPool loads the additional provider keys of names, in order, each one signing the certificates with the keys
+// of guardian. A key is read like PRIVATE_KEY, from its keystore or raw hex, refused in production unless
+// explicitly allowed for that key.
+func LoadPool(secrets *keys.Secrets, names []string, guardian Signer, production bool) ([]*Pooled, error) {
+	pool := make([]*Pooled, 0, len(names))
+	destroy := func() {
+		for _, p := range pool {
+			p.Destroy()
+		}
+	}
+
+	addresses := map[common.Address]bool{guardian.Address(): true}
+	for _, name := range names {
+		src, err := secrets.PoolKeySource(name)
+		if err != nil {
+			destroy()
+			return nil, fmt.Errorf("read provider key %s: %w", name, err)
+		}
+
+		providerKey, err := keys.LoadProviderKey(src, production)
+		src.Zero()
+		if err != nil {
+			destroy()
+			return nil, fmt.Errorf("prepare provider key %s: %w", name, err)
+		}
+
+		p := NewPooled(guardian, providerKey)
+		if addresses[p.Address()] {
+			p.Destroy()
+			destroy()
+			return nil, fmt.Errorf("provider key %s: %w: %s", name, ErrDuplicateProvider, p.Address())
+		}
+		addresses[p.Address()] = true
+		pool = append(pool, p)
+	}
+
+	return pool, nil
+}
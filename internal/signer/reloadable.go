@@ -0,0 +1,77 @@
+package signer
+
+import (
+	"context"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+)
+
+// destroyer is implemented by the signers holding key material in memory
+type destroyer interface {
+	Destroy()
+}
+
+// Reloadable is a Signer whose keys can be replaced while in use, e.g. when rotated secrets are reloaded.
+type Reloadable struct {
+	mu      sync.RWMutex
+	current Signer
+}
+
+var _ Signer = (*Reloadable)(nil)
+
+func NewReloadable(s Signer) *Reloadable {
+	return &Reloadable{current: s}
+}
+
+// Reload signs with s from now on. The key material of the previous signer is zeroed
+// once the signatures in progress are done.
+func (r *Reloadable) Reload(s Signer) {
+	r.mu.Lock()
+	previous := r.current
+	r.current = s
+	r.mu.Unlock()
+
+	if d, ok := previous.(destroyer); ok && previous != s {
+		d.Destroy()
+	}
+}
+
+func (r *Reloadable) Address() common.Address {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current.Address()
+}
+
+func (r *Reloadable) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current.SignTx(ctx, tx, chainID)
+}
+
+func (r *Reloadable) PublicKey() *babyjub.PublicKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current.PublicKey()
+}
+
+// SignCertificate signs with the current signer, and returns the public key of that signer even when the keys are
+// reloaded meanwhile.
+func (r *Reloadable) SignCertificate(
+	ctx context.Context,
+	contentHash, commitmentHash zkcertificate.Hash,
+) (*babyjub.Signature, *babyjub.PublicKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current.SignCertificate(ctx, contentHash, commitmentHash)
+}
+
+func (r *Reloadable) RetiredPublicKeys() []*babyjub.PublicKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current.RetiredPublicKeys()
+}
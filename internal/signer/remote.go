@@ -0,0 +1,259 @@
+package signer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"math/big"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	signerv1 "github.com/swissborg/galactica-kyc-guardian/gen/signer/v1"
+	"github.com/swissborg/galactica-kyc-guardian/internal/keys"
+)
+
+// Remote signs through a signer process serving signerv1.SignerService. The public keys of the signer are fetched
+// again when it signs with keys rotated since they were last fetched, and on Refresh.
+type Remote struct {
+	client signerv1.SignerServiceClient
+
+	mu        sync.RWMutex
+	address   common.Address
+	publicKey *babyjub.PublicKey
+	retired   []*babyjub.PublicKey
+}
+
+var _ Signer = (*Remote)(nil)
+
+// NewRemote fetches the public keys of the signer behind conn.
+func NewRemote(ctx context.Context, conn grpc.ClientConnInterface) (*Remote, error) {
+	r := &Remote{client: signerv1.NewSignerServiceClient(conn)}
+	if err := r.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Refresh fetches the public keys of the signer again, e.g. once its keys were rotated.
+func (r *Remote) Refresh(ctx context.Context) error {
+	resp, err := r.client.GetPublicKeys(ctx, &signerv1.GetPublicKeysRequest{})
+	if err != nil {
+		return fmt.Errorf("get signer public keys: %w", err)
+	}
+
+	if len(resp.GetProviderAddress()) != common.AddressLength {
+		return fmt.Errorf("invalid provider address length %d", len(resp.GetProviderAddress()))
+	}
+
+	publicKey, err := decompressPublicKey(resp.GetSigningPublicKey())
+	if err != nil {
+		return fmt.Errorf("signing public key: %w", err)
+	}
+
+	retired := make([]*babyjub.PublicKey, len(resp.GetRetiredSigningPublicKeys()))
+	for i, b := range resp.GetRetiredSigningPublicKeys() {
+		if retired[i], err = decompressPublicKey(b); err != nil {
+			return fmt.Errorf("retired signing public key: %w", err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.address = common.BytesToAddress(resp.GetProviderAddress())
+	r.publicKey = publicKey
+	r.retired = retired
+	return nil
+}
+
+func decompressPublicKey(b []byte) (*babyjub.PublicKey, error) {
+	var compressed babyjub.PublicKeyComp
+	if len(b) != len(compressed) {
+		return nil, fmt.Errorf("invalid public key length %d", len(b))
+	}
+	copy(compressed[:], b)
+
+	publicKey, err := compressed.Decompress()
+	if err != nil {
+		return nil, fmt.Errorf("decompress public key: %w", err)
+	}
+	return publicKey, nil
+}
+
+func (r *Remote) Address() common.Address {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.address
+}
+
+func (r *Remote) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	unsigned, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("encode transaction: %w", err)
+	}
+
+	resp, err := r.client.SignTransaction(ctx, &signerv1.SignTransactionRequest{
+		Transaction: unsigned,
+		ChainId:     chainID.Bytes(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sign transaction: %w", err)
+	}
+
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(resp.GetTransaction()); err != nil {
+		return nil, fmt.Errorf("decode signed transaction: %w", err)
+	}
+
+	txSigner := types.LatestSignerForChainID(chainID)
+
+	// the signer must not be able to send anything else than what it was asked to sign
+	if txSigner.Hash(signed) != txSigner.Hash(tx) {
+		return nil, fmt.Errorf("signer returned another transaction")
+	}
+
+	sender, err := types.Sender(txSigner, signed)
+	if err != nil {
+		return nil, fmt.Errorf("recover transaction sender: %w", err)
+	}
+	if address := r.Address(); sender != address {
+		// the transaction was prepared for the previous provider address, e.g. its nonce, it is refused even when
+		// the provider key was rotated, the next ones are prepared for the new address
+		if err := r.Refresh(ctx); err != nil {
+			log.WithError(err).Warn("refresh signer public keys")
+		}
+		return nil, fmt.Errorf("transaction signed by %s instead of %s", sender, address)
+	}
+
+	return signed, nil
+}
+
+func (r *Remote) PublicKey() *babyjub.PublicKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.publicKey
+}
+
+func (r *Remote) RetiredPublicKeys() []*babyjub.PublicKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.retired
+}
+
+func (r *Remote) SignCertificate(
+	ctx context.Context,
+	contentHash, commitmentHash zkcertificate.Hash,
+) (*babyjub.Signature, *babyjub.PublicKey, error) {
+	content := contentHash.Bytes32()
+	commitment := commitmentHash.Bytes32()
+
+	resp, err := r.client.SignCertificate(ctx, &signerv1.SignCertificateRequest{
+		ContentHash:    content[:],
+		CommitmentHash: commitment[:],
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("sign certificate: %w", err)
+	}
+
+	var compressed babyjub.SignatureComp
+	if len(resp.GetSignature()) != len(compressed) {
+		return nil, nil, fmt.Errorf("invalid signature length %d", len(resp.GetSignature()))
+	}
+	copy(compressed[:], resp.GetSignature())
+
+	signature, err := compressed.Decompress()
+	if err != nil {
+		return nil, nil, fmt.Errorf("decompress signature: %w", err)
+	}
+
+	publicKey, err := r.signatureKey(ctx, resp.GetSigningPublicKey())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ok, err := zkcertificate.VerifySignature(publicKey, contentHash, commitmentHash, signature)
+	if err != nil {
+		return nil, nil, fmt.Errorf("verify signature: %w", err)
+	}
+	if !ok {
+		return nil, nil, fmt.Errorf("signer returned an invalid certificate signature")
+	}
+
+	return signature, publicKey, nil
+}
+
+// signatureKey returns the public key of a certificate signature, the compressed key returned with it, or the known
+// key when the signer returns none. A key other than the known one is only accepted once the public keys fetched
+// again confirm that it is the active key of the signer.
+func (r *Remote) signatureKey(ctx context.Context, compressed []byte) (*babyjub.PublicKey, error) {
+	if len(compressed) == 0 {
+		return r.PublicKey(), nil
+	}
+
+	publicKey, err := decompressPublicKey(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("signature public key: %w", err)
+	}
+	if publicKey.Compress() == r.PublicKey().Compress() {
+		return publicKey, nil
+	}
+
+	if err := r.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	if publicKey.Compress() != r.PublicKey().Compress() {
+		return nil, fmt.Errorf("certificate signed with %s instead of the active key %s",
+			keys.SigningKeyID(publicKey), keys.SigningKeyID(r.PublicKey()))
+	}
+
+	log.WithField("signingKeyID", keys.SigningKeyID(publicKey)).Info("signer keys rotated")
+	return publicKey, nil
+}
+
+// RefreshOnSignal fetches the public keys of the signer again every time the process receives sig,
+// e.g. when the keys of the signer are rotated with the same signal.
+func RefreshOnSignal(ctx context.Context, r *Remote, sig ...os.Signal) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, sig...)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-signals:
+			if err := r.Refresh(ctx); err != nil {
+				log.WithError(err).Error("refresh signer public keys, keeping the current ones")
+				continue
+			}
+			log.WithField("address", r.Address()).
+				WithField("signingKeyID", keys.SigningKeyID(r.PublicKey())).
+				Info("signer public keys refreshed")
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Dial connects to the signer at url, with TLS when useTLS is set.
+func Dial(url string, useTLS bool) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if useTLS {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+
+	conn, err := grpc.NewClient(url, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("connect to signer: %w", err)
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,109 @@
+package signer
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	signerv1 "github.com/swissborg/galactica-kyc-guardian/gen/signer/v1"
+)
+
+// hashLength is the length of the hashes signed in certificates
+const hashLength = 32
+
+// Server serves signerv1.SignerService with the keys of a local signer.
+type Server struct {
+	signerv1.UnimplementedSignerServiceServer
+	signer Signer
+}
+
+func NewServer(signer Signer) *Server {
+	return &Server{signer: signer}
+}
+
+// Register registers the signer service on the gRPC server.
+func (s *Server) Register(server *grpc.Server) {
+	signerv1.RegisterSignerServiceServer(server, s)
+}
+
+func (s *Server) GetPublicKeys(
+	context.Context,
+	*signerv1.GetPublicKeysRequest,
+) (*signerv1.GetPublicKeysResponse, error) {
+	compressed := s.signer.PublicKey().Compress()
+
+	retired := s.signer.RetiredPublicKeys()
+	retiredCompressed := make([][]byte, len(retired))
+	for i, publicKey := range retired {
+		c := publicKey.Compress()
+		retiredCompressed[i] = c[:]
+	}
+
+	return &signerv1.GetPublicKeysResponse{
+		ProviderAddress:          s.signer.Address().Bytes(),
+		SigningPublicKey:         compressed[:],
+		RetiredSigningPublicKeys: retiredCompressed,
+	}, nil
+}
+
+func (s *Server) SignTransaction(
+	ctx context.Context,
+	req *signerv1.SignTransactionRequest,
+) (*signerv1.SignTransactionResponse, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(req.GetTransaction()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "decode transaction: %v", err)
+	}
+
+	if len(req.GetChainId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "missing chain id")
+	}
+	chainID := new(big.Int).SetBytes(req.GetChainId())
+
+	signed, err := s.signer.SignTx(ctx, tx, chainID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "sign transaction: %v", err)
+	}
+
+	b, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "encode signed transaction: %v", err)
+	}
+
+	log.WithField("to", tx.To()).
+		WithField("nonce", tx.Nonce()).
+		WithField("chainID", chainID).
+		Info("transaction signed")
+
+	return &signerv1.SignTransactionResponse{Transaction: b}, nil
+}
+
+func (s *Server) SignCertificate(
+	ctx context.Context,
+	req *signerv1.SignCertificateRequest,
+) (*signerv1.SignCertificateResponse, error) {
+	if len(req.GetContentHash()) != hashLength || len(req.GetCommitmentHash()) != hashLength {
+		return nil, status.Errorf(codes.InvalidArgument, "hashes must be %d bytes long", hashLength)
+	}
+
+	contentHash := zkcertificate.HashFromBigInt(new(big.Int).SetBytes(req.GetContentHash()))
+	commitmentHash := zkcertificate.HashFromBigInt(new(big.Int).SetBytes(req.GetCommitmentHash()))
+
+	signature, publicKey, err := s.signer.SignCertificate(ctx, contentHash, commitmentHash)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "sign certificate: %v", err)
+	}
+
+	compressed := signature.Compress()
+	compressedKey := publicKey.Compress()
+
+	log.WithField("contentHash", contentHash).Info("certificate signed")
+
+	return &signerv1.SignCertificateResponse{Signature: compressed[:], SigningPublicKey: compressedKey[:]}, nil
+}
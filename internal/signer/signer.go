@@ -0,0 +1,196 @@
+// Package signer signs the transactions and certificates of the guardian,
+// either in process or through a remote signer holding the keys.
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"os/signal"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/keys"
+)
+
+// ErrUnknownAccount is returned when asked to sign a transaction for another address than the provider's
+var ErrUnknownAccount = errors.New("signer does not hold the key of the account")
+
+// Signer holds the ECDSA provider key and the EdDSA certificate signing key of the guardian.
+type Signer interface {
+	// Address is the Ethereum address of the provider key
+	Address() common.Address
+	// SignTx signs the transaction for chainID with the provider key
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+	// PublicKey is the EdDSA key the certificates are signed with
+	PublicKey() *babyjub.PublicKey
+	// SignCertificate signs the certificate of the content and holder commitment hashes, and returns the public key
+	// of the signature, which is PublicKey unless the keys were rotated meanwhile
+	SignCertificate(
+		ctx context.Context,
+		contentHash, commitmentHash zkcertificate.Hash,
+	) (*babyjub.Signature, *babyjub.PublicKey, error)
+	// RetiredPublicKeys are the keys the certificates were signed with before the last rotations
+	RetiredPublicKeys() []*babyjub.PublicKey
+}
+
+// Transactor returns the options sending transactions from the provider address, signed by s.
+func Transactor(ctx context.Context, s Signer, chainID *big.Int) *bind.TransactOpts {
+	return &bind.TransactOpts{
+		From: s.Address(),
+		Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != s.Address() {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownAccount, address)
+			}
+			return s.SignTx(ctx, tx, chainID)
+		},
+		Context: ctx,
+	}
+}
+
+// Local signs with keys held in process memory.
+type Local struct {
+	providerKey *ecdsa.PrivateKey
+	signingKey  babyjub.PrivateKey
+	address     common.Address
+	retired     []*babyjub.PublicKey
+}
+
+var _ Signer = (*Local)(nil)
+
+// NewLocal returns the signer of the keys, the public keys of the retired signing keys are only advertised.
+func NewLocal(providerKey *ecdsa.PrivateKey, signingKey babyjub.PrivateKey, retired ...*babyjub.PublicKey) *Local {
+	return &Local{
+		providerKey: providerKey,
+		signingKey:  signingKey,
+		address:     crypto.PubkeyToAddress(providerKey.PublicKey),
+		retired:     retired,
+	}
+}
+
+// Destroy zeroes the keys of the signer, it must not be used afterwards.
+func (l *Local) Destroy() {
+	keys.ZeroECDSA(l.providerKey)
+	keys.Zero(l.signingKey[:])
+}
+
+func (l *Local) Address() common.Address {
+	return l.address
+}
+
+func (l *Local) SignTx(_ context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), l.providerKey)
+}
+
+func (l *Local) PublicKey() *babyjub.PublicKey {
+	return l.signingKey.Public()
+}
+
+func (l *Local) SignCertificate(
+	_ context.Context,
+	contentHash, commitmentHash zkcertificate.Hash,
+) (*babyjub.Signature, *babyjub.PublicKey, error) {
+	signature, err := zkcertificate.SignCertificate(l.signingKey, contentHash, commitmentHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	return signature, l.signingKey.Public(), nil
+}
+
+func (l *Local) RetiredPublicKeys() []*babyjub.PublicKey {
+	return l.retired
+}
+
+// LoadLocal loads the provider key and the active signing key of the ring from the secrets. In production
+// a raw hex provider key is refused unless explicitly allowed.
+func LoadLocal(secrets *keys.Secrets, ring keys.KeyRing, production bool) (*Local, error) {
+	src, err := secrets.ProviderKeySource()
+	if err != nil {
+		return nil, fmt.Errorf("read provider key: %w", err)
+	}
+	defer src.Zero()
+
+	providerKey, err := keys.LoadProviderKey(src, production)
+	if err != nil {
+		return nil, fmt.Errorf("prepare provider key: %w", err)
+	}
+
+	retired, err := ring.RetiredPublicKeys()
+	if err != nil {
+		keys.ZeroECDSA(providerKey)
+		return nil, err
+	}
+
+	certSigningKey, err := secrets.Get(ring.ActiveSecret())
+	if err != nil {
+		keys.ZeroECDSA(providerKey)
+		return nil, fmt.Errorf("read signing key: %w", err)
+	}
+	defer keys.Zero(certSigningKey)
+
+	signingKey, err := keys.LoadSigningKey(certSigningKey, providerKey)
+	if err != nil {
+		keys.ZeroECDSA(providerKey)
+		return nil, fmt.Errorf("prepare signing key: %w", err)
+	}
+
+	activeID := keys.SigningKeyID(signingKey.Public())
+	for _, publicKey := range retired {
+		if keys.SigningKeyID(publicKey) == activeID {
+			keys.ZeroECDSA(providerKey)
+			keys.Zero(signingKey[:])
+			return nil, fmt.Errorf("%w: active key %s is also retired", keys.ErrInvalidKeyRing, activeID)
+		}
+	}
+
+	return NewLocal(providerKey, signingKey, retired...), nil
+}
+
+// ReloadOnSignal reloads the keys of r from the secrets every time the process receives sig,
+// so that rotated secret mounts take effect. The signer is kept when the new keys cannot be loaded.
+func ReloadOnSignal(
+	ctx context.Context,
+	r *Reloadable,
+	secrets *keys.Secrets,
+	ring keys.KeyRing,
+	production bool,
+	sig ...os.Signal,
+) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, sig...)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-signals:
+			local, err := LoadLocal(secrets, ring, production)
+			if err != nil {
+				log.WithError(err).Error("reload keys, keeping the current ones")
+				continue
+			}
+
+			if previous := r.Address(); previous != local.Address() {
+				log.WithField("previousAddress", previous).
+					WithField("address", local.Address()).
+					Warn("provider address changed on reload")
+			}
+
+			r.Reload(local)
+			log.WithField("address", local.Address()).
+				WithField("signingKeyID", keys.SigningKeyID(local.PublicKey())).
+				WithField("retiredKeys", len(local.RetiredPublicKeys())).
+				Info("keys reloaded")
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package signer
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/keys"
+)
+
+func newLocalSigner(t *testing.T) *Local {
+	t.Helper()
+
+	providerKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generate provider key: %v", err)
+	}
+
+	retired := babyjub.NewRandPrivKey()
+	return NewLocal(providerKey, babyjub.NewRandPrivKey(), retired.Public())
+}
+
+// newRemoteSigner serves local over bufconn and returns the remote signer connected to it.
+func newRemoteSigner(t *testing.T, local Signer) *Remote {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	NewServer(local).Register(server)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial bufconn: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	remote, err := NewRemote(context.Background(), conn)
+	if err != nil {
+		t.Fatalf("new remote signer: %v", err)
+	}
+
+	return remote
+}
+
+func TestSigners(t *testing.T) {
+	local := newLocalSigner(t)
+	remote := newRemoteSigner(t, local)
+
+	if remote.Address() != local.Address() {
+		t.Errorf("remote address %s does not match local %s", remote.Address(), local.Address())
+	}
+	if remote.PublicKey().Compress() != local.PublicKey().Compress() {
+		t.Errorf("remote signing public key does not match the local one")
+	}
+	if len(remote.RetiredPublicKeys()) != 1 ||
+		remote.RetiredPublicKeys()[0].Compress() != local.RetiredPublicKeys()[0].Compress() {
+		t.Errorf("remote retired signing public keys do not match the local ones")
+	}
+
+	for name, s := range map[string]Signer{"local": local, "remote": remote} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			chainID := big.NewInt(9302)
+			to := common.HexToAddress("0x68272A56A0e9b095E5606fDD8b6c297702C0dfe5")
+
+			tx := types.NewTx(&types.DynamicFeeTx{
+				ChainID:   chainID,
+				Nonce:     7,
+				GasTipCap: big.NewInt(1),
+				GasFeeCap: big.NewInt(2),
+				Gas:       21000,
+				To:        &to,
+			})
+
+			signed, err := Transactor(ctx, s, chainID).Signer(s.Address(), tx)
+			if err != nil {
+				t.Fatalf("sign transaction: %v", err)
+			}
+
+			sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+			if err != nil {
+				t.Fatalf("recover sender: %v", err)
+			}
+			if sender != local.Address() {
+				t.Errorf("expected sender %s, got %s", local.Address(), sender)
+			}
+
+			if _, err := Transactor(ctx, s, chainID).Signer(to, tx); err == nil {
+				t.Errorf("expected signing for another account to fail")
+			}
+
+			contentHash := zkcertificate.HashFromBigInt(big.NewInt(123456789))
+			commitmentHash := zkcertificate.HashFromBigInt(big.NewInt(987654321))
+
+			signature, publicKey, err := s.SignCertificate(ctx, contentHash, commitmentHash)
+			if err != nil {
+				t.Fatalf("sign certificate: %v", err)
+			}
+			if publicKey.Compress() != local.PublicKey().Compress() {
+				t.Errorf("expected the signing public key with the signature")
+			}
+
+			ok, err := zkcertificate.VerifySignature(local.PublicKey(), contentHash, commitmentHash, signature)
+			if err != nil {
+				t.Fatalf("verify signature: %v", err)
+			}
+			if !ok {
+				t.Errorf("certificate signature does not verify with the signing public key")
+			}
+		})
+	}
+}
+
+// tamperingSigner signs another transaction than the requested one.
+type tamperingSigner struct {
+	*Local
+}
+
+func (s tamperingSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	to := common.HexToAddress("0x20682CE367cE2cA50bD255b03fEc2bd08Cc1c8Bd")
+	return s.Local.SignTx(ctx, types.NewTx(&types.DynamicFeeTx{
+		ChainID: chainID,
+		Nonce:   tx.Nonce(),
+		Gas:     tx.Gas(),
+		To:      &to,
+		Value:   big.NewInt(1e18),
+	}), chainID)
+}
+
+func TestRemoteRejectsTamperedTransaction(t *testing.T) {
+	remote := newRemoteSigner(t, tamperingSigner{newLocalSigner(t)})
+
+	chainID := big.NewInt(9302)
+	to := common.HexToAddress("0x68272A56A0e9b095E5606fDD8b6c297702C0dfe5")
+	tx := types.NewTx(&types.DynamicFeeTx{ChainID: chainID, Gas: 21000, To: &to})
+
+	if _, err := remote.SignTx(context.Background(), tx, chainID); err == nil {
+		t.Errorf("expected a transaction different from the requested one to be rejected")
+	}
+}
+
+func TestRemoteFollowsRotation(t *testing.T) {
+	ctx := context.Background()
+	first := newLocalSigner(t)
+	second := newLocalSigner(t)
+	reloadable := NewReloadable(first)
+	remote := newRemoteSigner(t, reloadable)
+
+	// the keys of the signer process are rotated on SIGHUP
+	reloadable.Reload(second)
+
+	contentHash := zkcertificate.HashFromBigInt(big.NewInt(123456789))
+	commitmentHash := zkcertificate.HashFromBigInt(big.NewInt(987654321))
+	if _, publicKey, err := remote.SignCertificate(ctx, contentHash, commitmentHash); err != nil {
+		t.Fatalf("sign certificate with the rotated key: %v", err)
+	} else if publicKey.Compress() != second.PublicKey().Compress() {
+		t.Errorf("expected the rotated key with the signature")
+	}
+	if remote.PublicKey().Compress() != second.PublicKey().Compress() {
+		t.Errorf("expected the public keys fetched again")
+	}
+
+	chainID := big.NewInt(9302)
+	to := common.HexToAddress("0x68272A56A0e9b095E5606fDD8b6c297702C0dfe5")
+	tx := types.NewTx(&types.DynamicFeeTx{ChainID: chainID, Gas: 21000, To: &to})
+
+	reloadable.Reload(newLocalSigner(t))
+	if _, err := remote.SignTx(ctx, tx, chainID); err == nil {
+		t.Errorf("expected the transaction prepared for the previous address to be refused")
+	}
+	if remote.Address() != reloadable.Address() {
+		t.Errorf("expected the new provider address %s, got %s", reloadable.Address(), remote.Address())
+	}
+	if _, err := remote.SignTx(ctx, tx, chainID); err != nil {
+		t.Errorf("expected the next transaction signed from the new address: %v", err)
+	}
+}
+
+func TestReloadable(t *testing.T) {
+	first := newLocalSigner(t)
+	second := newLocalSigner(t)
+
+	reloadable := NewReloadable(first)
+	if reloadable.Address() != first.Address() {
+		t.Fatalf("expected address %s, got %s", first.Address(), reloadable.Address())
+	}
+
+	reloadable.Reload(second)
+	if reloadable.Address() != second.Address() {
+		t.Errorf("expected reloaded address %s, got %s", second.Address(), reloadable.Address())
+	}
+	if reloadable.PublicKey().Compress() != second.PublicKey().Compress() {
+		t.Errorf("expected the reloaded signing public key")
+	}
+
+	if first.providerKey.D.Sign() != 0 || first.signingKey != (babyjub.PrivateKey{}) {
+		t.Errorf("expected the keys of the previous signer to be zeroed")
+	}
+}
+
+func TestLoadPool(t *testing.T) {
+	guardian := newLocalSigner(t)
+
+	poolKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generate pool key: %v", err)
+	}
+	t.Setenv("PRIVATE_KEY_2", hex.EncodeToString(crypto.FromECDSA(poolKey)))
+	t.Setenv("PRIVATE_KEY_3", hex.EncodeToString(crypto.FromECDSA(poolKey)))
+	secrets := keys.NewSecrets("")
+
+	pool, err := LoadPool(secrets, []string{"PRIVATE_KEY_2"}, guardian, false)
+	if err != nil {
+		t.Fatalf("load pool: %v", err)
+	}
+	if len(pool) != 1 || pool[0].Address() != crypto.PubkeyToAddress(poolKey.PublicKey) {
+		t.Fatalf("expected the address of the pool key, got %v", pool)
+	}
+	if pool[0].PublicKey().Compress() != guardian.PublicKey().Compress() {
+		t.Errorf("expected the certificates signed with the key of the guardian")
+	}
+
+	chainID := big.NewInt(9302)
+	to := common.HexToAddress("0x68272A56A0e9b095E5606fDD8b6c297702C0dfe5")
+	signed, err := pool[0].SignTx(context.Background(), types.NewTx(&types.DynamicFeeTx{ChainID: chainID, Gas: 21000, To: &to}), chainID)
+	if err != nil {
+		t.Fatalf("sign transaction: %v", err)
+	}
+	if sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed); err != nil || sender != pool[0].Address() {
+		t.Errorf("expected sender %s, got %s (%v)", pool[0].Address(), sender, err)
+	}
+
+	if _, err := LoadPool(secrets, []string{"PRIVATE_KEY_2", "PRIVATE_KEY_3"}, guardian, false); !errors.Is(err, ErrDuplicateProvider) {
+		t.Errorf("expected %v, got %v", ErrDuplicateProvider, err)
+	}
+	if _, err := LoadPool(secrets, []string{"PRIVATE_KEY_2"}, guardian, true); !errors.Is(err, keys.ErrRawKeyInProduction) {
+		t.Errorf("expected %v, got %v", keys.ErrRawKeyInProduction, err)
+	}
+	if _, err := LoadPool(secrets, []string{"PRIVATE_KEY_4"}, guardian, false); !errors.Is(err, keys.ErrNoProviderKey) {
+		t.Errorf("expected %v, got %v", keys.ErrNoProviderKey, err)
+	}
+}
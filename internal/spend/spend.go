@@ -0,0 +1,203 @@
+// Package spend records the gas spent on the registry transactions of every certificate and reports it per day.
+package spend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+)
+
+// Operation is what the transactions of a record did to the certificate
+type Operation string
+
+const (
+	OperationIssuance   Operation = "issuance"
+	OperationRevocation Operation = "revocation"
+)
+
+// DB key prefix of the records, followed by the UTC day, the leaf hash and the operation
+const keyPrefix = "spend/"
+
+// Record is the gas spent on the transactions of the attempts of an operation on a certificate in a day.
+type Record struct {
+	Time         time.Time              `json:"time"`
+	Standard     zkcertificate.Standard `json:"standard"`
+	Operation    Operation              `json:"operation"`
+	LeafHash     zkcertificate.Hash     `json:"leafHash"`
+	Transactions []common.Hash          `json:"transactions"`
+	GasUsed      uint64                 `json:"gasUsed"`
+	// Cost is the amount paid in wei, as a decimal string
+	Cost string `json:"cost"`
+	// Attempts is the number of attempts of the operation recorded
+	Attempts int `json:"attempts"`
+	// Completed is whether an attempt completed the operation, the failed ones paid for their mined transactions too
+	Completed bool `json:"completed"`
+}
+
+// NewRecord sums the gas used and paid by the mined transactions of an attempt of the operation,
+// the failed transactions included.
+func NewRecord(
+	standard zkcertificate.Standard,
+	operation Operation,
+	leafHash zkcertificate.Hash,
+	receipts []*types.Receipt,
+	completed bool,
+) Record {
+	record := Record{
+		Time:      time.Now().UTC(),
+		Standard:  standard,
+		Operation: operation,
+		LeafHash:  leafHash,
+		Attempts:  1,
+		Completed: completed,
+	}
+
+	cost := new(big.Int)
+	for _, receipt := range receipts {
+		record.Transactions = append(record.Transactions, receipt.TxHash)
+		record.GasUsed += receipt.GasUsed
+		if receipt.EffectiveGasPrice != nil {
+			cost.Add(cost, new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), receipt.EffectiveGasPrice))
+		}
+	}
+	record.Cost = cost.String()
+
+	return record
+}
+
+// Day is the spend of a UTC day.
+type Day struct {
+	// Date is the day, formatted as time.DateOnly
+	Date string
+	// Issuances and Revocations are the operations completed in the day
+	Issuances   int
+	Revocations int
+	GasUsed     uint64
+	// Cost is the amount paid in wei
+	Cost *big.Int
+}
+
+func (d *Day) add(r Record) error {
+	cost, ok := new(big.Int).SetString(r.Cost, 10)
+	if !ok {
+		return fmt.Errorf("invalid cost %q of %s", r.Cost, r.LeafHash)
+	}
+
+	// the records stored before the attempts were counted are the ones of completed operations
+	switch {
+	case !r.Completed && r.Attempts > 0:
+	case r.Operation == OperationIssuance:
+		d.Issuances++
+	case r.Operation == OperationRevocation:
+		d.Revocations++
+	}
+	d.GasUsed += r.GasUsed
+	d.Cost.Add(d.Cost, cost)
+	return nil
+}
+
+// Ledger stores the records in the guardian store.
+type Ledger struct {
+	db *badger.DB
+}
+
+func NewLedger(db *badger.DB) *Ledger {
+	return &Ledger{db: db}
+}
+
+func recordKey(r Record) []byte {
+	return []byte(keyPrefix + r.Time.UTC().Format(time.DateOnly) + "/" + r.LeafHash.String() + "/" + string(r.Operation))
+}
+
+// Add stores the record, adding its transactions, gas and attempts to the record of the same day,
+// certificate and operation when there is one.
+func (l *Ledger) Add(r Record) error {
+	return l.db.Update(func(txn *badger.Txn) error {
+		key := recordKey(r)
+
+		item, err := txn.Get(key)
+		switch {
+		case errors.Is(err, badger.ErrKeyNotFound):
+		case err != nil:
+			return fmt.Errorf("read spend record %s: %w", key, err)
+		default:
+			var stored Record
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &stored)
+			}); err != nil {
+				return fmt.Errorf("decode spend record %s: %w", key, err)
+			}
+			if r, err = stored.merge(r); err != nil {
+				return err
+			}
+		}
+
+		b, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("marshal spend record: %w", err)
+		}
+		return txn.Set(key, b)
+	})
+}
+
+// merge returns r with the transactions, gas and attempts of the later record added.
+func (r Record) merge(later Record) (Record, error) {
+	cost, ok := new(big.Int).SetString(r.Cost, 10)
+	if !ok {
+		return Record{}, fmt.Errorf("invalid cost %q of %s", r.Cost, r.LeafHash)
+	}
+	laterCost, ok := new(big.Int).SetString(later.Cost, 10)
+	if !ok {
+		return Record{}, fmt.Errorf("invalid cost %q of %s", later.Cost, later.LeafHash)
+	}
+
+	r.Transactions = append(r.Transactions, later.Transactions...)
+	r.GasUsed += later.GasUsed
+	r.Cost = cost.Add(cost, laterCost).String()
+	r.Attempts += later.Attempts
+	r.Completed = r.Completed || later.Completed
+	return r, nil
+}
+
+// Report sums the records of every UTC day from the day of from to the day of to, both included.
+// The days without records are left out.
+func (l *Ledger) Report(from, to time.Time) ([]Day, error) {
+	first, last := from.UTC().Format(time.DateOnly), to.UTC().Format(time.DateOnly)
+
+	var days []Day
+	err := l.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: []byte(keyPrefix)})
+		defer it.Close()
+
+		for it.Seek([]byte(keyPrefix + first)); it.Valid(); it.Next() {
+			key := it.Item().Key()
+			date := string(key[len(keyPrefix) : len(keyPrefix)+len(time.DateOnly)])
+			if date > last {
+				break
+			}
+
+			var r Record
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &r)
+			}); err != nil {
+				return fmt.Errorf("decode spend record %s: %w", key, err)
+			}
+
+			if len(days) == 0 || days[len(days)-1].Date != date {
+				days = append(days, Day{Date: date, Cost: new(big.Int)})
+			}
+			if err := days[len(days)-1].add(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return days, err
+}
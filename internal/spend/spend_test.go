@@ -0,0 +1,100 @@
+package spend
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+)
+
+func TestReport(t *testing.T) {
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("open badger: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	ledger := NewLedger(db)
+	add := func(day int, operation Operation, leaf int64, receipts ...*types.Receipt) {
+		r := NewRecord(zkcertificate.StandardKYC, operation, zkcertificate.Hash(*big.NewInt(leaf)), receipts, true)
+		r.Time = time.Date(2024, 3, day, 12, 0, 0, 0, time.UTC)
+		if err := ledger.Add(r); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+	}
+	receipt := func(gas, price int64) *types.Receipt {
+		return &types.Receipt{TxHash: common.BigToHash(big.NewInt(gas)), GasUsed: uint64(gas), EffectiveGasPrice: big.NewInt(price)}
+	}
+
+	add(1, OperationIssuance, 1, receipt(100, 10))
+	add(2, OperationIssuance, 2, receipt(100, 10), receipt(50, 20))
+	add(2, OperationRevocation, 1, receipt(30, 10))
+	add(4, OperationIssuance, 3, receipt(100, 10))
+	add(5, OperationIssuance, 4, receipt(100, 10))
+
+	days, err := ledger.Report(time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 4, 23, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+
+	if len(days) != 2 {
+		t.Fatalf("expected the days 2 and 4, got %+v", days)
+	}
+	if d := days[0]; d.Date != "2024-03-02" || d.Issuances != 1 || d.Revocations != 1 || d.GasUsed != 180 ||
+		d.Cost.Int64() != 2_300 {
+		t.Errorf("unexpected spend of the 2nd: %+v", d)
+	}
+	if d := days[1]; d.Date != "2024-03-04" || d.Issuances != 1 || d.Cost.Int64() != 1_000 {
+		t.Errorf("unexpected spend of the 4th: %+v", d)
+	}
+}
+
+func TestAddAttempts(t *testing.T) {
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("open badger: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	ledger := NewLedger(db)
+	day := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	add := func(completed bool, gas int64) {
+		receipt := &types.Receipt{TxHash: common.BigToHash(big.NewInt(gas)), GasUsed: uint64(gas), EffectiveGasPrice: big.NewInt(10)}
+		r := NewRecord(zkcertificate.StandardKYC, OperationIssuance, zkcertificate.Hash(*big.NewInt(1)),
+			[]*types.Receipt{receipt}, completed)
+		r.Time = day
+		if err := ledger.Add(r); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+	}
+
+	// a failed attempt, then the retry completing the issuance in the same day
+	add(false, 100)
+	add(true, 50)
+
+	days, err := ledger.Report(day, day)
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if len(days) != 1 {
+		t.Fatalf("expected one day, got %+v", days)
+	}
+	if d := days[0]; d.Issuances != 1 || d.GasUsed != 150 || d.Cost.Int64() != 1_500 {
+		t.Errorf("expected the gas of both attempts and one issuance, got %+v", d)
+	}
+
+	// a failed attempt alone paid its gas but issued nothing
+	day = day.AddDate(0, 0, 1)
+	add(false, 100)
+
+	if days, err = ledger.Report(day, day); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if len(days) != 1 || days[0].Issuances != 0 || days[0].GasUsed != 100 {
+		t.Errorf("expected the gas of the failed attempt only, got %+v", days)
+	}
+}
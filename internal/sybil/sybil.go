@@ -0,0 +1,271 @@
+package sybil
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dgraph-io/badger/v4"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Mode is the action taken when a duplicate identity is detected
+type Mode string
+
+const (
+	// ModeWarn logs and records the duplicate but lets the issuance proceed
+	ModeWarn Mode = "warn"
+	// ModeBlock rejects the issuance
+	ModeBlock Mode = "block"
+)
+
+// Outcome is the decision taken on an issuance request
+type Outcome string
+
+const (
+	OutcomeAllow Outcome = "allow"
+	OutcomeWarn  Outcome = "warn"
+	OutcomeBlock Outcome = "block"
+)
+
+// MatchKind tells which index matched
+type MatchKind string
+
+const (
+	// MatchIdentity means the identity is already registered by another user
+	MatchIdentity MatchKind = "identity"
+	// MatchHolderCommitment means the holder commitment is already registered by another user
+	MatchHolderCommitment MatchKind = "holder_commitment"
+	// MatchAdditionalCommitment means the user already registered the identity with another holder commitment
+	MatchAdditionalCommitment MatchKind = "additional_commitment"
+)
+
+// ErrDuplicateIdentity is returned by Check in block mode when a duplicate is detected
+var ErrDuplicateIdentity = errors.New("duplicate identity")
+
+// DB key prefixes of the indexes and of the decision log
+const (
+	identityKeyPrefix   = "sybil/identity/"
+	commitmentKeyPrefix = "sybil/commitment/"
+	decisionKeyPrefix   = "sybil/decision/"
+)
+
+// Identity holds the identity attributes of a KYC profile
+type Identity struct {
+	Firstname   string
+	Lastname    string
+	DateOfBirth time.Time
+	Nationality string
+}
+
+// Match is an index entry owned by another user or commitment
+type Match struct {
+	Kind   MatchKind `json:"kind"`
+	UserID string    `json:"userID"`
+}
+
+// Decision is the recorded result of a Check
+type Decision struct {
+	UserID  string    `json:"userID"`
+	Outcome Outcome   `json:"outcome"`
+	Matches []Match   `json:"matches,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+type identityEntry struct {
+	UserID     string `json:"userID"`
+	Commitment string `json:"commitment"`
+}
+
+type commitmentEntry struct {
+	UserID string `json:"userID"`
+}
+
+// Detector keeps salted hash indexes of identities and holder commitments
+// to detect the same identity or commitment being used by several users.
+// Only HMACs are stored, the salt must be kept secret.
+type Detector struct {
+	db   *badger.DB
+	salt []byte
+	mode Mode
+}
+
+func NewDetector(db *badger.DB, salt []byte, mode Mode) (*Detector, error) {
+	if len(salt) < 16 {
+		return nil, fmt.Errorf("salt must be at least 16 bytes")
+	}
+	if mode != ModeWarn && mode != ModeBlock {
+		return nil, fmt.Errorf("unknown mode %q", mode)
+	}
+
+	return &Detector{db: db, salt: salt, mode: mode}, nil
+}
+
+// Check looks up the identity and holder commitment of the user, records the decision
+// and registers them in the indexes unless the issuance is blocked.
+// In block mode a detected duplicate is returned as ErrDuplicateIdentity.
+func (d *Detector) Check(userID string, identity Identity, holderCommitment string) (Decision, error) {
+	identityHash := d.hash("identity", normalizeIdentity(identity))
+	commitmentHash := d.hash("commitment", holderCommitment)
+
+	decision := Decision{
+		UserID:  userID,
+		Outcome: OutcomeAllow,
+		Time:    time.Now().UTC(),
+	}
+
+	err := d.db.Update(func(txn *badger.Txn) error {
+		var ident identityEntry
+		identFound, err := get(txn, identityKeyPrefix+identityHash, &ident)
+		if err != nil {
+			return err
+		}
+
+		var commitment commitmentEntry
+		commitmentFound, err := get(txn, commitmentKeyPrefix+commitmentHash, &commitment)
+		if err != nil {
+			return err
+		}
+
+		if identFound {
+			switch {
+			case ident.UserID != userID:
+				decision.Matches = append(decision.Matches, Match{Kind: MatchIdentity, UserID: ident.UserID})
+			case ident.Commitment != commitmentHash:
+				decision.Matches = append(decision.Matches, Match{Kind: MatchAdditionalCommitment, UserID: ident.UserID})
+			}
+		}
+
+		if commitmentFound && commitment.UserID != userID {
+			decision.Matches = append(decision.Matches, Match{Kind: MatchHolderCommitment, UserID: commitment.UserID})
+		}
+
+		if len(decision.Matches) > 0 {
+			decision.Outcome = Outcome(d.mode)
+		}
+
+		if decision.Outcome != OutcomeBlock {
+			// the first user of an identity or commitment keeps owning it
+			if !identFound {
+				if err := set(txn, identityKeyPrefix+identityHash, identityEntry{UserID: userID, Commitment: commitmentHash}); err != nil {
+					return err
+				}
+			}
+			if !commitmentFound {
+				if err := set(txn, commitmentKeyPrefix+commitmentHash, commitmentEntry{UserID: userID}); err != nil {
+					return err
+				}
+			}
+		}
+
+		key := fmt.Sprintf("%s%020d/%s", decisionKeyPrefix, decision.Time.UnixNano(), userID)
+		return set(txn, key, decision)
+	})
+	if err != nil {
+		return Decision{}, fmt.Errorf("check duplicate identity: %w", err)
+	}
+
+	if decision.Outcome == OutcomeBlock {
+		return decision, ErrDuplicateIdentity
+	}
+
+	return decision, nil
+}
+
+// Decisions returns the recorded decisions, oldest first.
+func (d *Detector) Decisions() ([]Decision, error) {
+	var decisions []Decision
+
+	err := d.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: []byte(decisionKeyPrefix), PrefetchValues: true})
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			var decision Decision
+			if err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &decision)
+			}); err != nil {
+				return err
+			}
+			decisions = append(decisions, decision)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read decisions: %w", err)
+	}
+
+	return decisions, nil
+}
+
+func (d *Detector) hash(domain, value string) string {
+	mac := hmac.New(sha256.New, d.salt)
+	mac.Write([]byte(domain))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// normalizeIdentity makes the identity attributes insensitive to case, accents,
+// punctuation and the order of first and last names.
+func normalizeIdentity(identity Identity) string {
+	names := []string{normalizeName(identity.Firstname), normalizeName(identity.Lastname)}
+	if names[0] > names[1] {
+		names[0], names[1] = names[1], names[0]
+	}
+
+	return strings.Join([]string{
+		names[0],
+		names[1],
+		identity.DateOfBirth.Format(time.DateOnly),
+		strings.ToUpper(strings.TrimSpace(identity.Nationality)),
+	}, "|")
+}
+
+func normalizeName(name string) string {
+	t := transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	stripped, _, err := transform.String(t, name)
+	if err != nil {
+		stripped = name
+	}
+
+	var b strings.Builder
+	for _, field := range strings.FieldsFunc(strings.ToLower(stripped), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(field)
+	}
+	return b.String()
+}
+
+func get(txn *badger.Txn, key string, v any) (bool, error) {
+	item, err := txn.Get([]byte(key))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, item.Value(func(val []byte) error {
+		return json.Unmarshal(val, v)
+	})
+}
+
+func set(txn *badger.Txn, key string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return txn.Set([]byte(key), b)
+}
@@ -0,0 +1,123 @@
+package sybil
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+func newDetector(t *testing.T, mode Mode) *Detector {
+	t.Helper()
+
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatalf("open badger: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	d, err := NewDetector(db, []byte("0123456789abcdef"), mode)
+	if err != nil {
+		t.Fatalf("new detector: %v", err)
+	}
+	return d
+}
+
+var bob = Identity{
+	Firstname:   "Bob",
+	Lastname:    "Norman",
+	DateOfBirth: time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC),
+	Nationality: "CHE",
+}
+
+func TestCheckBlock(t *testing.T) {
+	d := newDetector(t, ModeBlock)
+
+	decision, err := d.Check("1", bob, "100")
+	if err != nil || decision.Outcome != OutcomeAllow {
+		t.Fatalf("first check: expected allow, got %v, %v", decision.Outcome, err)
+	}
+
+	// same user, same commitment, retrying is fine
+	decision, err = d.Check("1", bob, "100")
+	if err != nil || decision.Outcome != OutcomeAllow {
+		t.Fatalf("retry: expected allow, got %v, %v", decision.Outcome, err)
+	}
+
+	spelledDifferently := Identity{
+		Firstname:   " NORMAN ",
+		Lastname:    "Böb",
+		DateOfBirth: bob.DateOfBirth,
+		Nationality: "che",
+	}
+	decision, err = d.Check("2", spelledDifferently, "200")
+	if !errors.Is(err, ErrDuplicateIdentity) {
+		t.Fatalf("same identity: expected %v, got %v", ErrDuplicateIdentity, err)
+	}
+	if len(decision.Matches) != 1 || decision.Matches[0] != (Match{Kind: MatchIdentity, UserID: "1"}) {
+		t.Errorf("same identity: unexpected matches %+v", decision.Matches)
+	}
+
+	alice := Identity{Firstname: "Alice", Lastname: "Smith", DateOfBirth: bob.DateOfBirth, Nationality: "CHE"}
+	decision, err = d.Check("3", alice, "100")
+	if !errors.Is(err, ErrDuplicateIdentity) {
+		t.Fatalf("same commitment: expected %v, got %v", ErrDuplicateIdentity, err)
+	}
+	if len(decision.Matches) != 1 || decision.Matches[0] != (Match{Kind: MatchHolderCommitment, UserID: "1"}) {
+		t.Errorf("same commitment: unexpected matches %+v", decision.Matches)
+	}
+
+	decision, err = d.Check("1", bob, "300")
+	if !errors.Is(err, ErrDuplicateIdentity) {
+		t.Fatalf("additional commitment: expected %v, got %v", ErrDuplicateIdentity, err)
+	}
+	if len(decision.Matches) != 1 || decision.Matches[0].Kind != MatchAdditionalCommitment {
+		t.Errorf("additional commitment: unexpected matches %+v", decision.Matches)
+	}
+
+	// a blocked user is not registered
+	decision, err = d.Check("3", alice, "400")
+	if err != nil || decision.Outcome != OutcomeAllow {
+		t.Errorf("blocked user with a new commitment: expected allow, got %v, %v", decision.Outcome, err)
+	}
+
+	decisions, err := d.Decisions()
+	if err != nil {
+		t.Fatalf("decisions: %v", err)
+	}
+	outcomes := []Outcome{OutcomeAllow, OutcomeAllow, OutcomeBlock, OutcomeBlock, OutcomeBlock, OutcomeAllow}
+	if len(decisions) != len(outcomes) {
+		t.Fatalf("expected %d decisions, got %d", len(outcomes), len(decisions))
+	}
+	for i, decision := range decisions {
+		if decision.Outcome != outcomes[i] {
+			t.Errorf("decision %d: expected %s, got %s", i, outcomes[i], decision.Outcome)
+		}
+	}
+}
+
+func TestCheckWarn(t *testing.T) {
+	d := newDetector(t, ModeWarn)
+
+	if _, err := d.Check("1", bob, "100"); err != nil {
+		t.Fatalf("first check: %v", err)
+	}
+
+	decision, err := d.Check("2", bob, "200")
+	if err != nil {
+		t.Fatalf("warn mode must not return an error, got %v", err)
+	}
+	if decision.Outcome != OutcomeWarn || len(decision.Matches) != 1 {
+		t.Errorf("expected a warning with one match, got %+v", decision)
+	}
+}
+
+func TestNewDetectorValidation(t *testing.T) {
+	if _, err := NewDetector(nil, []byte("short"), ModeWarn); err == nil {
+		t.Error("expected an error for a short salt")
+	}
+	if _, err := NewDetector(nil, []byte("0123456789abcdef"), "log"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
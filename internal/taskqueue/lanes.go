@@ -0,0 +1,77 @@
+package taskqueue
+
+import "sync"
+
+// Lanes are queues running in parallel, each one executing its tasks sequentially.
+// The tasks of a lane start in the order they were added to it, a retried task is added again at its end.
+type Lanes struct {
+	queues []*Queue
+
+	mu sync.Mutex
+	// next is the lane picked first when several lanes are as busy
+	next int
+}
+
+// NewLanes creates n lanes, at least one
+func NewLanes(n int) *Lanes {
+	l := &Lanes{queues: make([]*Queue, max(n, 1))}
+	for i := range l.queues {
+		l.queues[i] = NewQueue()
+	}
+	return l
+}
+
+// Len returns the number of lanes
+func (l *Lanes) Len() int {
+	return len(l.queues)
+}
+
+// Add adds the task made by newTask for the lane with the fewest pending tasks and returns the lane.
+// The lanes as busy are picked in turn, so that the idle lanes share the tasks.
+func (l *Lanes) Add(newTask func(lane int) AnyTask) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lane := l.next
+	for i := range l.queues {
+		candidate := (l.next + i) % len(l.queues)
+		if l.queues[candidate].Pending() < l.queues[lane].Pending() {
+			lane = candidate
+		}
+	}
+	l.next = (lane + 1) % len(l.queues)
+
+	l.queues[lane].Add(newTask(lane))
+	return lane
+}
+
+// AddTo adds the task to the given lane, after the tasks already added to it
+func (l *Lanes) AddTo(lane int, task AnyTask) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.queues[lane].Add(task)
+}
+
+// Pending returns the number of tasks queued or running in every lane
+func (l *Lanes) Pending() []int {
+	pending := make([]int, len(l.queues))
+	for i, q := range l.queues {
+		pending[i] = q.Pending()
+	}
+	return pending
+}
+
+// Wait waits for the tasks of all the lanes to complete
+func (l *Lanes) Wait() {
+	for _, q := range l.queues {
+		q.Wait()
+	}
+}
+
+// Close stops the lanes and waits for all tasks to complete
+func (l *Lanes) Close() {
+	for _, q := range l.queues {
+		q.Close()
+	}
+}
@@ -0,0 +1,98 @@
+package taskqueue
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLanes(t *testing.T) {
+	lanes := NewLanes(3)
+
+	var mu sync.Mutex
+	executed := make(map[int][]int)
+	release := make(chan struct{})
+
+	// every lane is blocked by its first task, so that the following ones are spread by the pending counts
+	for i := range 9 {
+		lanes.Add(func(lane int) AnyTask {
+			return NewTask(
+				func() (int, error) {
+					if i < 3 {
+						<-release
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					executed[lane] = append(executed[lane], i)
+					return i, nil
+				},
+				func(int, error) {},
+				nil,
+			)
+		})
+	}
+
+	if pending := lanes.Pending(); !slices.Equal(pending, []int{3, 3, 3}) {
+		t.Errorf("expected the tasks shared between the lanes, got %v pending", pending)
+	}
+
+	lanes.AddTo(1, NewTask(
+		func() (int, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			executed[1] = append(executed[1], 9)
+			return 9, nil
+		},
+		func(int, error) {},
+		nil,
+	))
+	close(release)
+
+	done := make(chan bool)
+	go func() {
+		lanes.Wait()
+		done <- true
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Test timed out")
+	}
+
+	expected := map[int][]int{
+		0: {0, 3, 6},
+		1: {1, 4, 7, 9},
+		2: {2, 5, 8},
+	}
+	for lane, tasks := range expected {
+		if !slices.Equal(executed[lane], tasks) {
+			t.Errorf("lane %d: expected the tasks %v in order, got %v", lane, tasks, executed[lane])
+		}
+	}
+}
+
+func TestLanesPickTheLeastBusy(t *testing.T) {
+	lanes := NewLanes(2)
+	release := make(chan struct{})
+	blocking := func(int) AnyTask {
+		return NewTask(func() (bool, error) { <-release; return true, nil }, func(bool, error) {}, nil)
+	}
+
+	lanes.AddTo(0, blocking(0))
+	lanes.AddTo(0, blocking(0))
+
+	if lane := lanes.Add(blocking); lane != 1 {
+		t.Errorf("expected the idle lane 1, got %d", lane)
+	}
+	if lane := lanes.Add(blocking); lane != 1 {
+		t.Errorf("expected lane 1 with fewer pending tasks, got %d", lane)
+	}
+	if lane := lanes.Add(blocking); lane != 0 {
+		t.Errorf("expected lane 0 after lane 1 when as busy, got %d", lane)
+	}
+
+	close(release)
+	lanes.Wait()
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gammazero/workerpool"
@@ -20,6 +21,8 @@ type AnyTask interface {
 	Execute() error
 	ShouldRetry(error) bool
 	IsExpired() bool
+	// Expire is called when the queue drops the expired task without executing it
+	Expire()
 }
 
 // Task is a generic implementation of AnyTask
@@ -28,6 +31,8 @@ type Task[T any] struct {
 	Callback    func(T, error)
 	RetryError  error
 	CreatedAt   time.Time
+	// ExpiredFunc is called instead of the callback when the task expired before it was executed, it may be nil
+	ExpiredFunc func()
 }
 
 // NewTask creates a new task with the given execute function, callback, and retry error
@@ -72,10 +77,19 @@ func (t Task[T]) IsExpired() bool {
 	return time.Since(t.CreatedAt) > TaskExpirationTime
 }
 
+// Expire calls the ExpiredFunc of the task, if any
+func (t Task[T]) Expire() {
+	if t.ExpiredFunc != nil {
+		t.ExpiredFunc()
+	}
+}
+
 // Queue is a task queue that executes tasks sequentially
 type Queue struct {
 	pool *workerpool.WorkerPool
 	wg   sync.WaitGroup
+	// pending counts the tasks added and not done yet, retries included
+	pending atomic.Int64
 }
 
 // NewQueue creates a new task queue
@@ -90,6 +104,7 @@ func NewQueue() *Queue {
 // Add adds a task to the queue
 func (q *Queue) Add(task AnyTask) {
 	q.wg.Add(1)
+	q.pending.Add(1)
 	q.pool.Submit(func() {
 		q.processTask(task)
 	})
@@ -98,9 +113,11 @@ func (q *Queue) Add(task AnyTask) {
 // processTask processes a task and retries it if necessary
 func (q *Queue) processTask(task AnyTask) {
 	defer q.wg.Done()
+	defer q.pending.Add(-1)
 
 	// Skip expired tasks
 	if task.IsExpired() {
+		task.Expire()
 		return
 	}
 
@@ -108,6 +125,7 @@ func (q *Queue) processTask(task AnyTask) {
 	if err != nil {
 		if errors.Is(err, ErrTaskExpired) {
 			// Task expired, don't retry
+			task.Expire()
 			return
 		}
 		if task.ShouldRetry(err) {
@@ -117,6 +135,11 @@ func (q *Queue) processTask(task AnyTask) {
 	}
 }
 
+// Pending returns the number of tasks queued or running
+func (q *Queue) Pending() int {
+	return int(q.pending.Load())
+}
+
 // Wait waits for all tasks to complete
 func (q *Queue) Wait() {
 	q.wg.Wait()
@@ -157,6 +157,11 @@ func TestTaskExpiration(t *testing.T) {
 	)
 	// Manually set the creation time to simulate an expired task
 	expiredTask.CreatedAt = time.Now().Add(-TaskExpirationTime * 2)
+	expiredTask.ExpiredFunc = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		results = append(results, "expired task dropped")
+	}
 
 	queue.Add(immediateTask)
 	queue.Add(expiredTask)
@@ -179,6 +184,7 @@ func TestTaskExpiration(t *testing.T) {
 	expectedResults := []string{
 		"executed immediate task",
 		"immediate callback with result: success",
+		"expired task dropped",
 	}
 
 	if len(results) != len(expectedResults) {
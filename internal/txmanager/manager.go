@@ -0,0 +1,511 @@
+// Package txmanager sends the transactions of the provider with nonces it tracks, and replaces the transactions
+// that are not mined in time by the same transactions paying higher fees.
+package txmanager
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/signer"
+)
+
+var (
+	// ErrNonceTaken is returned when the nonce of a transaction was used by a transaction the manager did not send
+	ErrNonceTaken = errors.New("nonce used by another transaction")
+	// ErrFeesTooHigh is returned when the fees a transaction must pay to be included exceed the ceiling
+	ErrFeesTooHigh = errors.New("fees above the ceiling")
+)
+
+// feeBumpPercent is the fee increase of a replacement, nodes require at least 10%
+const feeBumpPercent = 20
+
+var (
+	// pollInterval is how often a pending transaction is checked
+	pollInterval = 2 * time.Second
+	// feePollInterval is how often the fees are checked while they exceed the ceiling
+	feePollInterval = 30 * time.Second
+	// droppedAfter is how long the nodes must not know a pending transaction before its nonce is reused, so that a
+	// node lagging behind the others or slow to get the transaction does not make it reuse the nonce
+	droppedAfter = 2 * time.Minute
+)
+
+// FeePolicy prices the transactions of the provider.
+type FeePolicy struct {
+	// TipCap is the priority fee per gas, the suggestion of the node when nil
+	TipCap *big.Int
+	// BaseFeeMultiplier sizes the fee cap to the base fee times the multiplier plus the tip,
+	// so that the transaction stays includable while the base fee rises
+	BaseFeeMultiplier int64
+	// MaxFeeCap is the ceiling of the fee per gas, there is no ceiling when nil
+	MaxFeeCap *big.Int
+}
+
+// Backend is the node connection the transactions are sent through.
+type Backend interface {
+	bind.ContractBackend
+	bind.DeployBackend
+	ChainID(ctx context.Context) (*big.Int, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error)
+}
+
+// Manager is the Backend of the transactions of the provider signed by a signer.Signer. It assigns their nonces,
+// tracks them until they are mined and replaces them when they are pending for too long.
+// The calls about other accounts are passed to the backend as is.
+type Manager struct {
+	Backend
+	signer       signer.Signer
+	fees         FeePolicy
+	replaceAfter time.Duration
+	stuckAfter   time.Duration
+
+	mu sync.Mutex
+	// address is the provider address of next and pending, they are reset when the signer address changes, e.g.
+	// when the provider key is rotated
+	address common.Address
+	// next is the nonce after the last one sent, 0 before the first transaction
+	next    uint64
+	pending map[uint64]*pendingTx
+}
+
+// pendingTx is a nonce of the provider waiting for one of its transactions to be mined.
+type pendingTx struct {
+	// from is the sender of the transaction, it is never signed again by another key
+	from common.Address
+	// sent are the hashes of the transaction and of its replacements sent to the nodes, refused ones included
+	sent []common.Hash
+	// last is the last version signed, the fees of the next replacement are bumped from it
+	last      *types.Transaction
+	firstSent time.Time
+	lastSent  time.Time
+	stuck     bool
+	// capped is set once the fees reached the ceiling, the transaction can't be replaced anymore
+	capped bool
+	// orphaned is set once the signer address changed, the transaction can't be replaced anymore
+	orphaned bool
+	// missingSince is when the nodes were first found not to know any version, zero while they know one
+	missingSince time.Time
+}
+
+// Stuck is a transaction pending for longer than the stuck delay.
+type Stuck struct {
+	Nonce uint64
+	// Hash is the last version of the transaction sent
+	Hash  common.Hash
+	Since time.Time
+}
+
+// New returns the manager of the transactions of s, priced by fees. A transaction is replaced when it is not mined
+// replaceAfter its last version was sent, and reported stuck when it is not mined stuckAfter it was first sent.
+func New(backend Backend, s signer.Signer, fees FeePolicy, replaceAfter, stuckAfter time.Duration) *Manager {
+	return &Manager{
+		Backend:      backend,
+		signer:       s,
+		fees:         fees,
+		replaceAfter: replaceAfter,
+		stuckAfter:   stuckAfter,
+		address:      s.Address(),
+		pending:      make(map[uint64]*pendingTx),
+	}
+}
+
+// follow resets the nonces tracked when address, the current address of the signer, is not theirs. The
+// transactions of the previous address are still waited for, but they are not replaced anymore. m.mu must be held.
+func (m *Manager) follow(address common.Address) {
+	if address == m.address {
+		return
+	}
+
+	log.WithField("previousAddress", m.address).
+		WithField("address", address).
+		WithField("pending", len(m.pending)).
+		Warn("provider address changed, tracking the nonces of the new address")
+	m.address = address
+	m.next = 0
+	m.pending = make(map[uint64]*pendingTx)
+}
+
+// PendingNonceAt returns the next nonce of the provider: the nonce of a pending transaction the nodes forgot for
+// droppedAfter, or the nonce after the last one sent unless the nodes know of a later one.
+func (m *Manager) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	if account != m.signer.Address() {
+		return m.Backend.PendingNonceAt(ctx, account)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.follow(account)
+
+	nonce, err := m.Backend.PendingNonceAt(ctx, account)
+	if err != nil {
+		return 0, err
+	}
+
+	mined, err := m.Backend.NonceAt(ctx, account, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	nonces := make([]uint64, 0, len(m.pending))
+	for n := range m.pending {
+		nonces = append(nonces, n)
+	}
+	slices.Sort(nonces)
+
+	for _, n := range nonces {
+		if n < mined {
+			// mined while nobody was waiting for it
+			delete(m.pending, n)
+			continue
+		}
+		p := m.pending[n]
+		if m.known(ctx, p) {
+			p.missingSince = time.Time{}
+			continue
+		}
+		if p.missingSince.IsZero() {
+			p.missingSince = time.Now()
+		}
+		if time.Since(p.missingSince) >= droppedAfter {
+			// the nonce is free again, reusing it unblocks the later transactions
+			log.WithField("nonce", n).Warn("pending transaction dropped by the nodes, reusing its nonce")
+			delete(m.pending, n)
+			return n, nil
+		}
+	}
+
+	return max(nonce, m.next), nil
+}
+
+// Price sets the fees of the next transaction of the provider on auth. The chains without base fee are priced
+// with the gas price suggested by the node. It returns ErrFeesTooHigh when the fees the transaction must pay to be
+// included exceed the ceiling, the fee cap is lowered to the ceiling otherwise.
+func (m *Manager) Price(ctx context.Context, auth *bind.TransactOpts) error {
+	head, err := m.Backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("retrieve head: %w", err)
+	}
+
+	if head.BaseFee == nil {
+		gasPrice, err := m.Backend.SuggestGasPrice(ctx)
+		if err != nil {
+			return fmt.Errorf("suggest gas price: %w", err)
+		}
+		if m.fees.MaxFeeCap != nil && gasPrice.Cmp(m.fees.MaxFeeCap) > 0 {
+			return fmt.Errorf("%w: gas price %s above %s", ErrFeesTooHigh, gasPrice, m.fees.MaxFeeCap)
+		}
+		auth.GasPrice = gasPrice
+		return nil
+	}
+
+	tip := m.fees.TipCap
+	if tip == nil {
+		if tip, err = m.Backend.SuggestGasTipCap(ctx); err != nil {
+			return fmt.Errorf("suggest gas tip cap: %w", err)
+		}
+	}
+
+	required := new(big.Int).Add(head.BaseFee, tip)
+	if m.fees.MaxFeeCap != nil && required.Cmp(m.fees.MaxFeeCap) > 0 {
+		return fmt.Errorf("%w: base fee %s and tip %s above %s", ErrFeesTooHigh, head.BaseFee, tip, m.fees.MaxFeeCap)
+	}
+
+	feeCap := new(big.Int).Mul(head.BaseFee, big.NewInt(max(m.fees.BaseFeeMultiplier, 1)))
+	feeCap.Add(feeCap, tip)
+	if m.fees.MaxFeeCap != nil && feeCap.Cmp(m.fees.MaxFeeCap) > 0 {
+		feeCap.Set(m.fees.MaxFeeCap)
+	}
+
+	auth.GasTipCap, auth.GasFeeCap = tip, feeCap
+	return nil
+}
+
+// WaitPrice prices the next transaction like Price, waiting while the fees exceed the ceiling.
+func (m *Manager) WaitPrice(ctx context.Context, auth *bind.TransactOpts) error {
+	deferred := false
+	for {
+		err := m.Price(ctx, auth)
+		if !errors.Is(err, ErrFeesTooHigh) {
+			if err == nil && deferred {
+				log.Info("fees back below the ceiling, transaction resumed")
+			}
+			return err
+		}
+		if !deferred {
+			log.WithError(err).Warn("transaction deferred until the fees are below the ceiling")
+			deferred = true
+		}
+
+		select {
+		case <-time.After(feePollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// known tells whether a node has a version of the transaction, pending or mined.
+func (m *Manager) known(ctx context.Context, p *pendingTx) bool {
+	for _, hash := range p.sent {
+		if _, _, err := m.Backend.TransactionByHash(ctx, hash); !errors.Is(err, ethereum.NotFound) {
+			return true
+		}
+	}
+	return false
+}
+
+// SendTransaction sends the transaction and tracks it when it is one of the provider.
+func (m *Manager) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if err := m.Backend.SendTransaction(ctx, tx); err != nil {
+		return err
+	}
+
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil || from != m.signer.Address() {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.follow(from)
+
+	now := time.Now()
+	m.pending[tx.Nonce()] = &pendingTx{from: from, sent: []common.Hash{tx.Hash()}, last: tx, firstSent: now, lastSent: now}
+	m.next = max(m.next, tx.Nonce()+1)
+	return nil
+}
+
+// WaitMined waits until the transaction, or one of its replacements, is mined and returns its receipt.
+// The transaction is replaced every time it is pending for longer than the replacement delay.
+func (m *Manager) WaitMined(ctx context.Context, tx *types.Transaction) (*types.Receipt, error) {
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, fmt.Errorf("recover transaction sender: %w", err)
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		receipt, err := m.poll(ctx, from, tx)
+		if receipt != nil || err != nil {
+			return receipt, err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// poll returns the receipt of the version of tx, sent from from, that was mined, nil when none was.
+func (m *Manager) poll(ctx context.Context, from common.Address, tx *types.Transaction) (*types.Receipt, error) {
+	m.mu.Lock()
+	p := m.pending[tx.Nonce()]
+	if p != nil && p.from != from {
+		// the nonce is the one of a transaction of another address
+		p = nil
+	}
+	sent := []common.Hash{tx.Hash()}
+	if p != nil {
+		sent = slices.Clone(p.sent)
+	}
+	m.mu.Unlock()
+
+	// the nonce is read before the receipts, so that a version mined in between is not missed
+	mined, err := m.Backend.NonceAt(ctx, from, nil)
+	if err != nil {
+		log.WithError(err).Debug("retrieve provider nonce")
+		return nil, nil
+	}
+
+	for _, hash := range sent {
+		receipt, err := m.Backend.TransactionReceipt(ctx, hash)
+		if err == nil {
+			m.done(p)
+			return receipt, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			log.WithError(err).WithField("tx", hash).Debug("retrieve receipt")
+			return nil, nil
+		}
+	}
+
+	if mined > tx.Nonce() {
+		m.done(p)
+		return nil, fmt.Errorf("%w: %d", ErrNonceTaken, tx.Nonce())
+	}
+
+	if p != nil {
+		m.check(ctx, tx.Nonce(), p)
+	}
+	return nil, nil
+}
+
+// done stops tracking the pending transaction p, when it is still tracked.
+func (m *Manager) done(p *pendingTx) {
+	if p == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if nonce := p.last.Nonce(); m.pending[nonce] == p {
+		delete(m.pending, nonce)
+	}
+}
+
+// check reports the transaction once it is stuck, and replaces it once its last version is pending for too long.
+func (m *Manager) check(ctx context.Context, nonce uint64, p *pendingTx) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	logger := log.WithField("nonce", nonce).WithField("tx", p.sent[len(p.sent)-1])
+
+	if !p.stuck && now.Sub(p.firstSent) > m.stuckAfter {
+		p.stuck = true
+		logger.WithField("since", p.firstSent).Error("transaction stuck")
+	}
+
+	if now.Sub(p.lastSent) <= m.replaceAfter {
+		return
+	}
+
+	if address := m.signer.Address(); address != p.from {
+		// signing the replacement with the new key would send the call a second time, from another account
+		if !p.orphaned {
+			p.orphaned = true
+			logger.WithField("address", address).Warn("provider address changed, transaction not replaced")
+		}
+		return
+	}
+
+	bumped, ok := bumpFees(p.last, m.fees.MaxFeeCap)
+	if !ok {
+		if !p.capped {
+			p.capped = true
+			logger.Warn("transaction at the fee ceiling, it is not replaced anymore")
+		}
+		return
+	}
+
+	replacement, err := m.signer.SignTx(ctx, bumped, p.last.ChainId())
+	if err != nil {
+		logger.WithError(err).Error("sign replacement transaction")
+		return
+	}
+	p.last, p.lastSent = replacement, now
+	// tracked before it is sent: a node can accept it and the call still fail, e.g. on a timeout
+	p.sent = append(p.sent, replacement.Hash())
+
+	if err := m.Backend.SendTransaction(ctx, replacement); err != nil {
+		// the next replacement pays more, unless a version was mined meanwhile
+		logger.WithError(err).Warn("replacement transaction refused")
+		return
+	}
+
+	logger.WithField("replacement", replacement.Hash()).
+		WithField("gasFeeCap", replacement.GasFeeCap()).
+		WithField("gasTipCap", replacement.GasTipCap()).
+		Warn("transaction replaced with higher fees")
+}
+
+// Stuck returns the transactions pending for longer than the stuck delay, by nonce.
+func (m *Manager) Stuck() []Stuck {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var stuck []Stuck
+	for nonce, p := range m.pending {
+		if p.stuck {
+			stuck = append(stuck, Stuck{Nonce: nonce, Hash: p.sent[len(p.sent)-1], Since: p.firstSent})
+		}
+	}
+	slices.SortFunc(stuck, func(a, b Stuck) int { return cmp.Compare(a.Nonce, b.Nonce) })
+	return stuck
+}
+
+// bumpFees returns the unsigned transaction tx paying feeBumpPercent more, up to maxFeeCap when it is not nil.
+// It returns false when the fees can't be bumped enough for the nodes to accept the replacement.
+func bumpFees(tx *types.Transaction, maxFeeCap *big.Int) (*types.Transaction, bool) {
+	if tx.Type() == types.DynamicFeeTxType {
+		feeCap, ok := bumpBelow(tx.GasFeeCap(), maxFeeCap)
+		if !ok {
+			return nil, false
+		}
+		tip, ok := bumpBelow(tx.GasTipCap(), feeCap)
+		if !ok {
+			return nil, false
+		}
+
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:    tx.ChainId(),
+			Nonce:      tx.Nonce(),
+			GasTipCap:  tip,
+			GasFeeCap:  feeCap,
+			Gas:        tx.Gas(),
+			To:         tx.To(),
+			Value:      tx.Value(),
+			Data:       tx.Data(),
+			AccessList: tx.AccessList(),
+		}), true
+	}
+
+	gasPrice, ok := bumpBelow(tx.GasPrice(), maxFeeCap)
+	if !ok {
+		return nil, false
+	}
+
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    tx.Nonce(),
+		GasPrice: gasPrice,
+		Gas:      tx.Gas(),
+		To:       tx.To(),
+		Value:    tx.Value(),
+		Data:     tx.Data(),
+	}), true
+}
+
+// bumpBelow bumps the fee up to ceiling when it is not nil. It returns false when the bumped fee is less than
+// the 10% increase the nodes require.
+func bumpBelow(fee, ceiling *big.Int) (*big.Int, bool) {
+	bumped := bump(fee)
+	if ceiling == nil || bumped.Cmp(ceiling) <= 0 {
+		return bumped, true
+	}
+
+	minimum := new(big.Int).Mul(fee, big.NewInt(110))
+	minimum.Add(minimum, big.NewInt(99))
+	minimum.Div(minimum, big.NewInt(100))
+	return new(big.Int).Set(ceiling), ceiling.Cmp(minimum) >= 0
+}
+
+// bump increases the fee by feeBumpPercent, rounded up, and by at least 1 wei.
+func bump(fee *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(fee, big.NewInt(100+feeBumpPercent))
+	bumped.Add(bumped, big.NewInt(99))
+	bumped.Div(bumped, big.NewInt(100))
+	if bumped.Cmp(fee) <= 0 {
+		bumped.Add(fee, big.NewInt(1))
+	}
+	return bumped
+}
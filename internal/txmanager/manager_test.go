@@ -0,0 +1,435 @@
+package txmanager
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/signer"
+)
+
+var chainID = big.NewInt(41238)
+
+// fakeNode keeps the transactions it is sent in its pool until mine is called.
+type fakeNode struct {
+	Backend
+
+	mu     sync.Mutex
+	nonce  uint64 // nonce of the provider on chain
+	pool   map[common.Hash]*types.Transaction
+	mined  map[common.Hash]bool
+	sent   []*types.Transaction
+	refuse error
+	// timeout is returned once the transaction was accepted, like a call timing out after the node got it
+	timeout error
+}
+
+func newFakeNode() *fakeNode {
+	return &fakeNode{pool: make(map[common.Hash]*types.Transaction), mined: make(map[common.Hash]bool)}
+}
+
+func (n *fakeNode) SendTransaction(_ context.Context, tx *types.Transaction) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.refuse != nil {
+		return n.refuse
+	}
+	n.pool[tx.Hash()] = tx
+	n.sent = append(n.sent, tx)
+	return n.timeout
+}
+
+func (n *fakeNode) PendingNonceAt(context.Context, common.Address) (uint64, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	next := n.nonce
+	for _, tx := range n.pool {
+		next = max(next, tx.Nonce()+1)
+	}
+	return next, nil
+}
+
+func (n *fakeNode) NonceAt(context.Context, common.Address, *big.Int) (uint64, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.nonce, nil
+}
+
+func (n *fakeNode) TransactionReceipt(_ context.Context, hash common.Hash) (*types.Receipt, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if !n.mined[hash] {
+		return nil, ethereum.NotFound
+	}
+	return &types.Receipt{TxHash: hash, Status: types.ReceiptStatusSuccessful}, nil
+}
+
+func (n *fakeNode) TransactionByHash(_ context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if tx, ok := n.pool[hash]; ok {
+		return tx, true, nil
+	}
+	return nil, false, ethereum.NotFound
+}
+
+// mine includes the transaction of hash, the other transactions of its nonce are dropped.
+func (n *fakeNode) mine(hash common.Hash) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	tx := n.pool[hash]
+	for h, other := range n.pool {
+		if other.Nonce() == tx.Nonce() {
+			delete(n.pool, h)
+		}
+	}
+	n.mined[hash] = true
+	n.nonce = tx.Nonce() + 1
+}
+
+// drop removes every transaction from the pool.
+func (n *fakeNode) drop() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	clear(n.pool)
+}
+
+func (n *fakeNode) last() *types.Transaction {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.sent[len(n.sent)-1]
+}
+
+func newManager(t *testing.T, node *fakeNode, replaceAfter, stuckAfter time.Duration) (*Manager, signer.Signer) {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	s := signer.NewLocal(key, babyjub.NewRandPrivKey())
+	return New(node, s, FeePolicy{}, replaceAfter, stuckAfter), s
+}
+
+// send signs and sends a transaction of the provider with the nonce given by the manager.
+func send(t *testing.T, m *Manager, s signer.Signer) *types.Transaction {
+	t.Helper()
+	ctx := context.Background()
+
+	nonce, err := m.PendingNonceAt(ctx, s.Address())
+	if err != nil {
+		t.Fatalf("pending nonce: %v", err)
+	}
+
+	tx, err := s.SignTx(ctx, types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(1_000),
+		GasFeeCap: big.NewInt(10_000),
+		Gas:       21_000,
+		To:        &common.Address{},
+	}), chainID)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	if err := m.SendTransaction(ctx, tx); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	return tx
+}
+
+func TestNonces(t *testing.T) {
+	originalDroppedAfter := droppedAfter
+	droppedAfter = 0
+	defer func() {
+		droppedAfter = originalDroppedAfter
+	}()
+
+	node := newFakeNode()
+	node.nonce = 7
+	m, s := newManager(t, node, time.Hour, time.Hour)
+
+	first := send(t, m, s)
+	if first.Nonce() != 7 {
+		t.Fatalf("expected the nonce of the chain, got %d", first.Nonce())
+	}
+	node.drop()
+
+	// the first transaction was dropped, its nonce is reused
+	second := send(t, m, s)
+	if second.Nonce() != 7 {
+		t.Errorf("expected the nonce of the dropped transaction, got %d", second.Nonce())
+	}
+
+	third := send(t, m, s)
+	if third.Nonce() != 8 {
+		t.Errorf("expected the nonce after the pending transaction, got %d", third.Nonce())
+	}
+
+	node.mine(second.Hash())
+	node.mine(third.Hash())
+	if fourth := send(t, m, s); fourth.Nonce() != 9 {
+		t.Errorf("expected the nonce after the mined transactions, got %d", fourth.Nonce())
+	}
+}
+
+func TestDroppedAfterGracePeriod(t *testing.T) {
+	originalDroppedAfter := droppedAfter
+	droppedAfter = 50 * time.Millisecond
+	defer func() {
+		droppedAfter = originalDroppedAfter
+	}()
+
+	node := newFakeNode()
+	m, s := newManager(t, node, time.Hour, time.Hour)
+
+	first := send(t, m, s)
+	// a lagging node does not know the transaction yet, its nonce is not reused
+	node.drop()
+	if second := send(t, m, s); second.Nonce() != first.Nonce()+1 {
+		t.Fatalf("expected the nonce after the missing transaction, got %d", second.Nonce())
+	}
+
+	time.Sleep(droppedAfter)
+	node.drop()
+	if third := send(t, m, s); third.Nonce() != first.Nonce() {
+		t.Errorf("expected the nonce of the transaction missing for the grace period, got %d", third.Nonce())
+	}
+}
+
+func TestReplaceStuckTransaction(t *testing.T) {
+	originalInterval := pollInterval
+	pollInterval = time.Millisecond
+	defer func() {
+		pollInterval = originalInterval
+	}()
+
+	node := newFakeNode()
+	m, s := newManager(t, node, 10*time.Millisecond, 30*time.Millisecond)
+	tx := send(t, m, s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the replacements are refused until the transaction is reported stuck
+	node.refuse = errors.New("replacement transaction underpriced")
+	go func() {
+		for {
+			time.Sleep(time.Millisecond)
+			if len(m.Stuck()) > 0 {
+				break
+			}
+		}
+		node.mu.Lock()
+		node.refuse = nil
+		node.mu.Unlock()
+
+		for {
+			time.Sleep(time.Millisecond)
+			if last := node.last(); last.Hash() != tx.Hash() {
+				node.mine(last.Hash())
+				return
+			}
+		}
+	}()
+
+	receipt, err := m.WaitMined(ctx, tx)
+	if err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+
+	replacement := node.last()
+	if receipt.TxHash != replacement.Hash() || replacement.Nonce() != tx.Nonce() {
+		t.Fatalf("expected the receipt of the replacement, got %s", receipt.TxHash)
+	}
+	if replacement.GasTipCap().Cmp(bump(bump(tx.GasTipCap()))) < 0 || replacement.GasFeeCap().Cmp(bump(tx.GasFeeCap())) <= 0 {
+		t.Errorf("expected the fees to be bumped from the refused replacement, got %s/%s",
+			replacement.GasTipCap(), replacement.GasFeeCap())
+	}
+	if stuck := m.Stuck(); len(stuck) != 0 {
+		t.Errorf("expected no stuck transaction once mined, got %+v", stuck)
+	}
+}
+
+func TestReplacementTrackedWhenSendFails(t *testing.T) {
+	originalInterval := pollInterval
+	pollInterval = time.Millisecond
+	defer func() {
+		pollInterval = originalInterval
+	}()
+
+	node := newFakeNode()
+	m, s := newManager(t, node, 10*time.Millisecond, time.Hour)
+	tx := send(t, m, s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the node accepts the replacement but the call times out, the replacement is mined
+	node.mu.Lock()
+	node.timeout = context.DeadlineExceeded
+	node.mu.Unlock()
+	go func() {
+		for {
+			time.Sleep(time.Millisecond)
+			if last := node.last(); last.Hash() != tx.Hash() {
+				node.mine(last.Hash())
+				return
+			}
+		}
+	}()
+
+	receipt, err := m.WaitMined(ctx, tx)
+	if err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if receipt.TxHash == tx.Hash() {
+		t.Errorf("expected the receipt of the replacement, got the one of the original")
+	}
+}
+
+func TestProviderAddressChanged(t *testing.T) {
+	originalInterval := pollInterval
+	pollInterval = time.Millisecond
+	defer func() {
+		pollInterval = originalInterval
+	}()
+
+	node := newFakeNode()
+	m, s := newManager(t, node, 0, time.Hour)
+	reloadable := signer.NewReloadable(s)
+	m.signer = reloadable
+	tx := send(t, m, reloadable)
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	reloadable.Reload(signer.NewLocal(key, babyjub.NewRandPrivKey()))
+
+	// the transaction of the previous address is not signed again by the new key
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := m.WaitMined(ctx, tx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected to wait for the transaction, got %v", err)
+	}
+	if last := node.last(); last.Hash() != tx.Hash() {
+		t.Errorf("expected the transaction of the previous address not to be replaced")
+	}
+
+	// the new address does not continue the nonces of the previous one
+	node.drop()
+	if next := send(t, m, reloadable); next.Nonce() != 0 {
+		t.Errorf("expected the nonce of the new address, got %d", next.Nonce())
+	}
+}
+
+func TestNonceTaken(t *testing.T) {
+	node := newFakeNode()
+	m, s := newManager(t, node, time.Hour, time.Hour)
+	tx := send(t, m, s)
+
+	// another transaction of the provider, sent by hand, was mined
+	node.mu.Lock()
+	node.nonce = tx.Nonce() + 1
+	node.mu.Unlock()
+
+	if _, err := m.WaitMined(context.Background(), tx); !errors.Is(err, ErrNonceTaken) {
+		t.Errorf("expected %v, got %v", ErrNonceTaken, err)
+	}
+}
+
+func TestBump(t *testing.T) {
+	for fee, want := range map[int64]int64{0: 1, 1: 2, 10: 12, 1_000_000_000: 1_200_000_000} {
+		if got := bump(big.NewInt(fee)); got.Int64() != want {
+			t.Errorf("bump %d: expected %d, got %s", fee, want, got)
+		}
+	}
+}
+
+// feeNode is a chain with a base fee, or without one when baseFee is nil.
+type feeNode struct {
+	*fakeNode
+
+	baseFee *big.Int
+	tip     *big.Int
+}
+
+func (n *feeNode) HeaderByNumber(context.Context, *big.Int) (*types.Header, error) {
+	return &types.Header{BaseFee: n.baseFee}, nil
+}
+
+func (n *feeNode) SuggestGasTipCap(context.Context) (*big.Int, error) {
+	return n.tip, nil
+}
+
+func (n *feeNode) SuggestGasPrice(context.Context) (*big.Int, error) {
+	return n.tip, nil
+}
+
+func TestPrice(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		baseFee *big.Int
+		fees    FeePolicy
+		tip     int64
+		feeCap  int64
+		err     error
+	}{
+		{name: "suggested tip", baseFee: big.NewInt(100), fees: FeePolicy{BaseFeeMultiplier: 2}, tip: 5, feeCap: 205},
+		{name: "fixed tip", baseFee: big.NewInt(100), fees: FeePolicy{TipCap: big.NewInt(1), BaseFeeMultiplier: 3}, tip: 1, feeCap: 301},
+		{name: "capped", baseFee: big.NewInt(100), fees: FeePolicy{BaseFeeMultiplier: 2, MaxFeeCap: big.NewInt(150)}, tip: 5, feeCap: 150},
+		{name: "above the ceiling", baseFee: big.NewInt(100), fees: FeePolicy{BaseFeeMultiplier: 2, MaxFeeCap: big.NewInt(104)}, err: ErrFeesTooHigh},
+		{name: "legacy above the ceiling", fees: FeePolicy{MaxFeeCap: big.NewInt(4)}, err: ErrFeesTooHigh},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &feeNode{fakeNode: newFakeNode(), baseFee: tt.baseFee, tip: big.NewInt(5)}
+			m := &Manager{Backend: node, fees: tt.fees}
+
+			auth := &bind.TransactOpts{}
+			err := m.Price(ctx, auth)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if auth.GasTipCap.Int64() != tt.tip || auth.GasFeeCap.Int64() != tt.feeCap {
+				t.Errorf("expected %d/%d, got %s/%s", tt.tip, tt.feeCap, auth.GasTipCap, auth.GasFeeCap)
+			}
+		})
+	}
+}
+
+func TestBumpFeesCeiling(t *testing.T) {
+	tx := types.NewTx(&types.DynamicFeeTx{ChainID: chainID, GasTipCap: big.NewInt(100), GasFeeCap: big.NewInt(1_000)})
+
+	bumped, ok := bumpFees(tx, big.NewInt(1_150))
+	if !ok || bumped.GasFeeCap().Int64() != 1_150 || bumped.GasTipCap().Int64() != 120 {
+		t.Errorf("expected the fee cap lowered to the ceiling, got %v %s/%s", ok, bumped.GasTipCap(), bumped.GasFeeCap())
+	}
+
+	if _, ok := bumpFees(tx, big.NewInt(1_050)); ok {
+		t.Errorf("expected no replacement below the minimum increase")
+	}
+}
@@ -0,0 +1,11 @@
+// Package version holds the build information set at link time, e.g.
+//
+//	go build -ldflags "-X github.com/swissborg/galactica-kyc-guardian/internal/version.Version=v1.0.0"
+package version
+
+var (
+	// Version is the release of the service
+	Version = "dev"
+	// Commit is the git commit the service was built from
+	Commit = "unknown"
+)
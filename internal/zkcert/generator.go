@@ -2,123 +2,206 @@ package zkcert
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"time"
 
-	merkleproof "github.com/Galactica-corp/merkle-proof-service/gen/galactica/merkle"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/galactica-corp/guardians-sdk/cmd"
-	"github.com/galactica-corp/guardians-sdk/pkg/contracts"
-	"github.com/galactica-corp/guardians-sdk/pkg/merkle"
 	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
-	"github.com/iden3/go-iden3-crypto/babyjub"
 	log "github.com/sirupsen/logrus"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/spend"
 	"github.com/swissborg/galactica-kyc-guardian/internal/taskqueue"
 )
 
 var errRequiresRetry = errors.New("requires a retry")
 
-type Service struct {
-	EthClient         *ethclient.Client
-	merkleProofClient merkleproof.QueryClient
-	providerKey       *ecdsa.PrivateKey
-	registry          *contracts.ZkCertificateRegistry
-	registryAddress   common.Address
-	rpcURL            string
-	signingKey        babyjub.PrivateKey
-	taskQueue         *taskqueue.Queue
+// ErrTxVanished is passed to the issuance callback when the transaction adding the certificate left the chain
+// before it was confirmed. The issuance is queued again and the callback called once more with its outcome.
+var ErrTxVanished = fmt.Errorf("issuance transaction vanished from the chain, %w", errRequiresRetry)
+
+// Inputs are the raw data of a certificate, encoded to the content T of its standard.
+type Inputs[T zkcertificate.Content] interface {
+	zkcertificate.FFEncoder[T]
+	Validate() error
 }
 
-func NewService(
-	providerKey *ecdsa.PrivateKey,
-	signingKey babyjub.PrivateKey,
-	registryAddress common.Address,
-	rpcURL string,
-	merkleProofURL string,
-	merkleProofTLS bool,
-) (*Service, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-	defer cancel()
-
-	ethClient, err := ethclient.DialContext(ctx, rpcURL)
-	if err != nil {
-		return nil, fmt.Errorf("connect to ethereum node: %v", err)
-	}
+// Service creates the certificates of the standard of T and issues them to its registry.
+type Service[T zkcertificate.Content] struct {
+	*Issuer
+	registryAddress common.Address
+}
 
-	merkleProofClient, err := merkle.ConnectToMerkleProofService(merkleProofURL, merkleProofTLS)
-	if err != nil {
-		return nil, fmt.Errorf("connect to merkle proof service: %v", err)
+// NewService returns the service of the standard of T issuing to registryAddress through issuer.
+func NewService[T zkcertificate.Content](issuer *Issuer, registryAddress common.Address) *Service[T] {
+	var content T
+	issuer.register(content.Standard(), registryAddress)
+
+	return &Service[T]{
+		Issuer:          issuer,
+		registryAddress: registryAddress,
 	}
+}
+
+// Standard returns the standard of the certificates created by the service.
+func (s *Service[T]) Standard() zkcertificate.Standard {
+	var content T
+	return content.Standard()
+}
 
-	registry, err := contracts.NewZkCertificateRegistry(registryAddress, ethClient)
+func (s *Service[T]) CreateZKCert(
+	ctx context.Context,
+	holderCommitment zkcertificate.HolderCommitment,
+	inputs Inputs[T],
+) (*zkcertificate.Certificate[T], error) {
+	content, err := encodeInputs(inputs)
 	if err != nil {
-		return nil, fmt.Errorf("load record registry: %w", err)
+		return nil, err
 	}
 
-	taskQueue := taskqueue.NewQueue()
-
-	return &Service{
-		rpcURL:            rpcURL,
-		EthClient:         ethClient,
-		merkleProofClient: merkleProofClient,
-		providerKey:       providerKey,
-		registry:          registry,
-		registryAddress:   registryAddress,
-		signingKey:        signingKey,
-		taskQueue:         taskQueue,
-	}, nil
-}
+	/* one year expiration */
+	expirationDate := time.Now().AddDate(1, 0, 0)
 
-func (s *Service) Close() {
-	s.taskQueue.Wait()
-	s.EthClient.Close()
+	return createZKCert(ctx, s.signer, content, holderCommitment, expirationDate)
 }
 
-func (s *Service) CreateZKCert(
-	holderCommitment zkcertificate.HolderCommitment,
-	inputs zkcertificate.KYCInputs,
-) (*zkcertificate.Certificate[zkcertificate.KYCContent], error) {
+// encodeInputs validates the inputs and encodes them to the finite field content of the certificate.
+func encodeInputs[T zkcertificate.Content](inputs Inputs[T]) (T, error) {
 	if err := inputs.Validate(); err != nil {
-		return nil, fmt.Errorf("validate inputs: %w", err)
+		var content T
+		return content, fmt.Errorf("validate inputs: %w", err)
 	}
 
 	content, err := inputs.FFEncode()
 	if err != nil {
-		return nil, fmt.Errorf("encode inputs to finite field: %w", err)
+		return content, fmt.Errorf("encode inputs to finite field: %w", err)
 	}
+	return content, nil
+}
 
-	/* one year expiration */
-	expirationDate := time.Now().AddDate(1, 0, 0)
+func (s *Service[T]) AddZKCertToQueue(
+	ctx context.Context,
+	certificate zkcertificate.Certificate[T],
+	callback func(zkcertificate.IssuedCertificate[T], error),
+) {
+	if s.closing.Load() {
+		callback(zkcertificate.IssuedCertificate[T]{}, ErrShuttingDown)
+		return
+	}
 
-	return cmd.CreateZKCert(content, holderCommitment, s.signingKey, expirationDate)
+	s.lanes.Add(func(lane int) taskqueue.AnyTask {
+		p := s.providers[lane]
+		id := s.progress.queue(lane, &certificate.LeafHash)
+
+		task := taskqueue.NewTask(
+			func() (_ zkcertificate.IssuedCertificate[T], err error) {
+				s.progress.start(id)
+				defer func() { s.progress.done(id, err) }()
+
+				ctx, cancel := s.taskContext(ctx)
+				defer cancel()
+
+				receipts, issuedCert, err := issueZKCert(
+					ctx, certificate, p.txs, s.merkleProofClient, s.registryAddress, p.signer, s.confirmations,
+				)
+				s.recordSpend(s.Standard(), spend.OperationIssuance, certificate.LeafHash, receipts, err == nil)
+				if err != nil {
+					log.WithError(err).WithField("provider", p.signer.Address()).Error("issue zk certificate")
+					return zkcertificate.IssuedCertificate[T]{}, err
+				}
+
+				return issuedCert, err
+			},
+			callback,
+			errRequiresRetry,
+		)
+		// the caller learns that the issuance expired in its lane, without running
+		task.ExpiredFunc = func() {
+			s.progress.expire(id)
+			callback(zkcertificate.IssuedCertificate[T]{}, taskqueue.ErrTaskExpired)
+		}
+		return task
+	})
 }
 
-func (s *Service) AddZKCertToQueue(
+// AddRevocationToQueue queues the on-chain revocation of the certificate registered
+// under leafHash at leafIndex in the registry, in the lane of the provider that issued it.
+func (s *Service[T]) AddRevocationToQueue(
 	ctx context.Context,
-	certificate zkcertificate.Certificate[zkcertificate.KYCContent],
-	callback func(zkcertificate.IssuedCertificate[zkcertificate.KYCContent], error),
+	leafHash zkcertificate.Hash,
+	leafIndex int,
+	callback func(*types.Transaction, error),
 ) {
-	s.taskQueue.Add(taskqueue.NewTask(
-		func() (zkcertificate.IssuedCertificate[zkcertificate.KYCContent], error) {
-			_, issuedCert, err := cmd.IssueZKCert(ctx, certificate, s.EthClient, s.merkleProofClient, s.registryAddress, s.providerKey)
+	if s.closing.Load() {
+		callback(nil, ErrShuttingDown)
+		return
+	}
+
+	issuedCert := zkcertificate.IssuedCertificate[T]{
+		Certificate: zkcertificate.Certificate[T]{LeafHash: leafHash},
+		Registration: zkcertificate.RegistrationDetails{
+			Address:   s.registryAddress,
+			LeafIndex: leafIndex,
+		},
+	}
+
+	lane := s.issuerLane(ctx, leafHash)
+	p := s.providers[lane]
+	id := s.progress.queue(lane, nil)
+
+	task := taskqueue.NewTask(
+		func() (_ *types.Transaction, err error) {
+			s.progress.start(id)
+			defer func() { s.progress.done(id, err) }()
+
+			ctx, cancel := s.taskContext(ctx)
+			defer cancel()
+
+			tx, receipts, err := revokeZKCert(ctx, issuedCert, p.txs, s.merkleProofClient, p.signer)
+			s.recordSpend(s.Standard(), spend.OperationRevocation, leafHash, receipts, err == nil)
 			if err != nil {
-				log.WithError(err).Error("issue zk certificate")
-				return zkcertificate.IssuedCertificate[zkcertificate.KYCContent]{}, err
+				log.WithError(err).Error("revoke zk certificate")
+				return nil, err
 			}
 
-			return issuedCert, err
+			return tx, nil
 		},
 		callback,
 		errRequiresRetry,
-	))
+	)
+	task.ExpiredFunc = func() {
+		s.progress.expire(id)
+		callback(nil, taskqueue.ErrTaskExpired)
+	}
+	s.lanes.AddTo(lane, task)
+}
+
+// issuerLane returns the lane of the provider the registry records as the guardian of the certificate of leafHash,
+// the only one allowed to revoke it. It is the first lane when the record can't be read or is not a provider.
+func (s *Service[T]) issuerLane(ctx context.Context, leafHash zkcertificate.Hash) int {
+	if len(s.providers) == 1 {
+		return 0
+	}
+
+	guardian, err := s.CertificateGuardian(ctx, s.registryAddress, leafHash)
+	if err != nil {
+		log.WithError(err).WithField("leafHash", leafHash).Warn("find the provider of the certificate, revoking from the first lane")
+		return 0
+	}
+
+	for lane, p := range s.providers {
+		if p.signer.Address() == guardian {
+			return lane
+		}
+	}
+	return 0
 }
 
-func (s *Service) EncryptZKCert(
+func (s *Service[T]) EncryptZKCert(
 	holderCommitment zkcertificate.HolderCommitment,
-	issuedCert zkcertificate.IssuedCertificate[zkcertificate.KYCContent],
+	issuedCert zkcertificate.IssuedCertificate[T],
 ) (zkcertificate.EncryptedCertificate, error) {
 	return cmd.EncryptZKCert(issuedCert, holderCommitment)
 }
@@ -0,0 +1,500 @@
+package zkcert
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/galactica-corp/guardians-sdk/cmd"
+	"github.com/galactica-corp/guardians-sdk/pkg/contracts"
+	"github.com/galactica-corp/guardians-sdk/pkg/merkle"
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+	"github.com/holiman/uint256"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/signer"
+)
+
+// The functions below follow the issuance and revocation flows of the guardians SDK commands,
+// which only accept keys held in memory, with the transactions signed by a signer.Signer.
+
+// queueTurnPollInterval is how often the registry queue is checked for the turn of a certificate
+var queueTurnPollInterval = 5 * time.Second
+
+// confirmationPollInterval is how often the depth of the transaction adding a certificate is checked
+var confirmationPollInterval = 5 * time.Second
+
+// providerTxs prices the transactions of the provider and waits until they, or their replacements, are mined.
+type providerTxs interface {
+	WaitPrice(ctx context.Context, auth *bind.TransactOpts) error
+	WaitMined(ctx context.Context, tx *types.Transaction) (*types.Receipt, error)
+}
+
+// issueClient is the node connection the certificates are issued through.
+type issueClient interface {
+	cmd.EthereumIssueClient
+	providerTxs
+	BlockNumber(ctx context.Context) (uint64, error)
+	TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error)
+}
+
+// revokeClient is the node connection the certificates are revoked through.
+type revokeClient interface {
+	cmd.EthereumRevokeClient
+	providerTxs
+}
+
+// createZKCert signs the certificate of the content for the holder.
+func createZKCert[T zkcertificate.Content](
+	ctx context.Context,
+	s signer.Signer,
+	content T,
+	holderCommitment zkcertificate.HolderCommitment,
+	expirationDate time.Time,
+) (*zkcertificate.Certificate[T], error) {
+	contentHash, err := content.Hash()
+	if err != nil {
+		return nil, fmt.Errorf("hash certificate content: %w", err)
+	}
+
+	// the key is the one of the signature, the keys may be rotated since
+	signature, publicKey, err := s.SignCertificate(ctx, contentHash, holderCommitment.CommitmentHash)
+	if err != nil {
+		return nil, fmt.Errorf("sign certificate: %w", err)
+	}
+
+	salt, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64)) // [0, MaxInt64)
+	if err != nil {
+		return nil, fmt.Errorf("generate random salt: %w", err)
+	}
+
+	return zkcertificate.New(
+		holderCommitment.CommitmentHash,
+		content,
+		publicKey,
+		signature,
+		salt.Int64()+1, // [1, MaxInt64]
+		expirationDate,
+	)
+}
+
+// issueZKCert registers the certificate in the registry queue, waits for its turn
+// and adds it to the first empty leaf of the registry. It returns once the transaction adding the leaf
+// is confirmations blocks deep and the registry records the leaf, with the receipts of the transactions mined,
+// the ones of a failed issuance included.
+func issueZKCert[T zkcertificate.Content](
+	ctx context.Context,
+	certificate zkcertificate.Certificate[T],
+	client issueClient,
+	merkleProofClient merkle.EmptyLeafProver,
+	registryAddress common.Address,
+	s signer.Signer,
+	confirmations uint64,
+) ([]*types.Receipt, zkcertificate.IssuedCertificate[T], error) {
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, zkcertificate.IssuedCertificate[T]{}, fmt.Errorf("retrieve chain-id: %w", err)
+	}
+
+	registry, err := contracts.NewZkCertificateRegistry(registryAddress, client)
+	if err != nil {
+		return nil, zkcertificate.IssuedCertificate[T]{}, fmt.Errorf("load record registry: %w", err)
+	}
+
+	if err := ensureProviderIsGuardian(ctx, client, registry, s.Address()); err != nil {
+		return nil, zkcertificate.IssuedCertificate[T]{}, fmt.Errorf("ensure provider is guardian: %w", err)
+	}
+
+	if certificate.Standard == zkcertificate.StandardKYC {
+		content := any(certificate.Content).(zkcertificate.KYCContent)
+		if err := ensureKYCSaltIsCompatible(ctx, content, certificate.HolderCommitment, client, registryAddress, s.Address()); err != nil {
+			return nil, zkcertificate.IssuedCertificate[T]{}, fmt.Errorf("ensure KYC salt is compatible: %w", err)
+		}
+	}
+
+	var receipts []*types.Receipt
+
+	auth, err := transactor(ctx, client, s, chainID)
+	if err != nil {
+		return receipts, zkcertificate.IssuedCertificate[T]{}, err
+	}
+	registration, err := registerAndWaitForTurn(ctx, client, auth, registry, certificate.LeafHash)
+	if registration != nil {
+		receipts = append(receipts, registration)
+	}
+	if err != nil {
+		return receipts, zkcertificate.IssuedCertificate[T]{}, fmt.Errorf("register and wait for issue turn: %w", err)
+	}
+
+	emptyLeafIndex, proof, err := merkle.GetEmptyLeafProof(ctx, merkleProofClient, registryAddress.Hex())
+	if err != nil {
+		return receipts, zkcertificate.IssuedCertificate[T]{}, fmt.Errorf("find empty tree leaf: %w", err)
+	}
+	leafIndex := int(emptyLeafIndex)
+
+	auth, err = transactor(ctx, client, s, chainID)
+	if err != nil {
+		return receipts, zkcertificate.IssuedCertificate[T]{}, err
+	}
+	tx, err := addZKCert(ctx, client, auth, registryAddress, leafIndex, certificate, proof)
+	if err != nil {
+		return receipts, zkcertificate.IssuedCertificate[T]{}, fmt.Errorf("construct add record tx: %w", err)
+	}
+
+	receipt, err := waitSuccess(ctx, client, tx)
+	if receipt != nil {
+		receipts = append(receipts, receipt)
+	}
+	if err != nil {
+		return receipts, zkcertificate.IssuedCertificate[T]{}, err
+	}
+
+	if err := waitConfirmations(ctx, client, receipt.TxHash, confirmations); err != nil {
+		return receipts, zkcertificate.IssuedCertificate[T]{}, err
+	}
+
+	if err := ensureLeafAdded(ctx, registry, certificate.LeafHash, s.Address()); err != nil {
+		return receipts, zkcertificate.IssuedCertificate[T]{}, err
+	}
+
+	proof.Leaf = merkle.TreeNode{Value: uint256.MustFromBig(certificate.LeafHash.BigInt())}
+
+	return receipts, zkcertificate.IssuedCertificate[T]{
+		Certificate: certificate,
+		Registration: zkcertificate.RegistrationDetails{
+			Address:   registryAddress,
+			ChainID:   chainID,
+			Revocable: true,
+			LeafIndex: leafIndex,
+		},
+		MerkleProof: proof,
+	}, nil
+}
+
+// revokeZKCert removes the leaf of the issued certificate from its registry. It returns the revocation transaction
+// and the receipts of the transactions mined, the ones of a failed revocation included.
+func revokeZKCert[T zkcertificate.Content](
+	ctx context.Context,
+	certificate zkcertificate.IssuedCertificate[T],
+	client revokeClient,
+	merkleProofClient merkle.Prover,
+	s signer.Signer,
+) (*types.Transaction, []*types.Receipt, error) {
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get chain id from blockchain rpc: %w", err)
+	}
+
+	registryAddress := certificate.Registration.Address
+
+	registry, err := contracts.NewZkCertificateRegistry(registryAddress, client)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load record registry: %w", err)
+	}
+
+	if err := ensureProviderIsGuardian(ctx, client, registry, s.Address()); err != nil {
+		return nil, nil, fmt.Errorf("ensure provider is guardian: %w", err)
+	}
+
+	leafHash := certificate.LeafHash
+
+	var receipts []*types.Receipt
+
+	auth, err := transactor(ctx, client, s, chainID)
+	if err != nil {
+		return nil, receipts, err
+	}
+	registration, err := registerAndWaitForTurn(ctx, client, auth, registry, leafHash)
+	if registration != nil {
+		receipts = append(receipts, registration)
+	}
+	if err != nil {
+		return nil, receipts, fmt.Errorf("register and wait for zkCertificate turn: %w", err)
+	}
+
+	proof, err := merkle.GetProof(ctx, merkleProofClient, registryAddress.Hex(), leafHash.String())
+	if err != nil {
+		return nil, receipts, fmt.Errorf("get merkle proof: %w", err)
+	}
+
+	auth, err = transactor(ctx, client, s, chainID)
+	if err != nil {
+		return nil, receipts, err
+	}
+	tx, err := registry.RevokeZkCertificate(
+		auth,
+		big.NewInt(int64(certificate.Registration.LeafIndex)),
+		leafHash.Bytes32(),
+		encodeMerkleProof(proof),
+	)
+	if err != nil {
+		return nil, receipts, fmt.Errorf("construct transaction to revoke record from registry: %w", err)
+	}
+
+	receipt, err := waitSuccess(ctx, client, tx)
+	if receipt != nil {
+		receipts = append(receipts, receipt)
+	}
+	if err != nil {
+		return nil, receipts, err
+	}
+
+	return tx, receipts, nil
+}
+
+func ensureProviderIsGuardian(
+	ctx context.Context,
+	client bind.ContractBackend,
+	registry *contracts.ZkCertificateRegistry,
+	providerAddress common.Address,
+) error {
+	guardianRegistryAddress, err := registry.GuardianRegistry(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return fmt.Errorf("retrieve guardian registry address: %w", err)
+	}
+
+	guardianRegistry, err := contracts.NewGuardianRegistry(guardianRegistryAddress, client)
+	if err != nil {
+		return fmt.Errorf("bind guardian registry contract: %w", err)
+	}
+
+	whitelisted, err := guardianRegistry.IsWhitelisted(&bind.CallOpts{Context: ctx}, providerAddress)
+	if err != nil {
+		return fmt.Errorf("retrieve guardian whitelist status: %w", err)
+	}
+
+	if !whitelisted {
+		return fmt.Errorf("provider %s is not a guardian yet", providerAddress)
+	}
+
+	return nil
+}
+
+func ensureKYCSaltIsCompatible(
+	ctx context.Context,
+	content zkcertificate.KYCContent,
+	salt zkcertificate.Hash,
+	client bind.ContractBackend,
+	registryAddress common.Address,
+	providerAddress common.Address,
+) error {
+	recordRegistry, err := contracts.NewZkKYCRegistry(registryAddress, client)
+	if err != nil {
+		return fmt.Errorf("load kyc registry: %w", err)
+	}
+
+	saltRegistryAddress, err := recordRegistry.HumanIDSaltRegistry(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return fmt.Errorf("get salt registry: %w", err)
+	}
+
+	saltRegistry, err := contracts.NewHumanIDSaltRegistry(saltRegistryAddress, client)
+	if err != nil {
+		return fmt.Errorf("load salt registry: %w", err)
+	}
+
+	idHash, err := content.IDHash()
+	if err != nil {
+		return fmt.Errorf("get id hash: %w", err)
+	}
+
+	registeredHash, err := saltRegistry.GetSaltHash(&bind.CallOpts{
+		Context: ctx,
+		From:    providerAddress,
+	}, idHash.BigInt())
+	if err != nil {
+		return fmt.Errorf("get registered salt: %w", err)
+	}
+
+	if registeredHash.Sign() != 0 && registeredHash.Cmp(salt.BigInt()) != 0 {
+		return cmd.ErrSaltIncompatible
+	}
+
+	return nil
+}
+
+// registerAndWaitForTurn registers the leaf hash in the registry queue, unless it is already queued,
+// and waits until it is its turn to be added or revoked. It returns the receipt of the registration,
+// nil when the leaf hash was already queued, on error too once the registration is mined.
+func registerAndWaitForTurn(
+	ctx context.Context,
+	client providerTxs,
+	auth *bind.TransactOpts,
+	registry *contracts.ZkCertificateRegistry,
+	leafHash zkcertificate.Hash,
+) (*types.Receipt, error) {
+	tx, err := registry.RegisterToQueue(auth, leafHash.Bytes32())
+	if err != nil {
+		queued, checkErr := registry.CheckZkCertificateHashInQueue(&bind.CallOpts{Context: ctx}, leafHash.Bytes32())
+		if checkErr != nil {
+			return nil, fmt.Errorf("register to queue failed: %w, also failed to check if zkCertificateHash is in queue: %w", err, checkErr)
+		}
+		if !queued {
+			return nil, fmt.Errorf("register to queue failed: %w", err)
+		}
+		tx = nil
+	}
+
+	var receipt *types.Receipt
+	if tx != nil {
+		if receipt, err = waitSuccess(ctx, client, tx); err != nil {
+			return receipt, fmt.Errorf("queue registration: %w", err)
+		}
+	}
+
+	for {
+		myTurn, err := registry.CheckZkCertificateHashInQueue(&bind.CallOpts{Context: ctx}, leafHash.Bytes32())
+		if err != nil {
+			return receipt, fmt.Errorf("retrieve zkCertificate hash to check: %w", err)
+		}
+
+		if myTurn {
+			return receipt, nil
+		}
+
+		select {
+		case <-time.After(queueTurnPollInterval):
+		case <-ctx.Done():
+			return receipt, ctx.Err()
+		}
+	}
+}
+
+func addZKCert[T zkcertificate.Content](
+	ctx context.Context,
+	client bind.ContractBackend,
+	auth *bind.TransactOpts,
+	registryAddress common.Address,
+	leafIndex int,
+	certificate zkcertificate.Certificate[T],
+	proof merkle.Proof,
+) (*types.Transaction, error) {
+	if certificate.Standard != zkcertificate.StandardKYC {
+		registry, err := contracts.NewZkCertificateRegistry(registryAddress, client)
+		if err != nil {
+			return nil, fmt.Errorf("load record registry: %w", err)
+		}
+
+		return registry.AddZkCertificate(
+			auth,
+			big.NewInt(int64(leafIndex)),
+			certificate.LeafHash.Bytes32(),
+			encodeMerkleProof(proof),
+		)
+	}
+
+	// the registry of zkKYC certificates expects the ID hash, holder commitment and expiration as well
+	registry, err := contracts.NewZkKYCRegistry(registryAddress, client)
+	if err != nil {
+		return nil, fmt.Errorf("load record registry: %w", err)
+	}
+
+	content := any(certificate.Content).(zkcertificate.KYCContent)
+	idHash, err := content.IDHash()
+	if err != nil {
+		return nil, fmt.Errorf("get id hash: %w", err)
+	}
+
+	return registry.AddZkKYC(
+		auth,
+		big.NewInt(int64(leafIndex)),
+		certificate.LeafHash.Bytes32(),
+		encodeMerkleProof(proof),
+		idHash.BigInt(),
+		certificate.HolderCommitment.BigInt(),
+		big.NewInt(certificate.ExpirationDate.Unix()),
+	)
+}
+
+// transactor returns the options of the next transaction of the provider, priced by client.
+// It waits while the fees exceed the ceiling, deferring the issuance rather than overpaying.
+func transactor(ctx context.Context, client providerTxs, s signer.Signer, chainID *big.Int) (*bind.TransactOpts, error) {
+	auth := signer.Transactor(ctx, s, chainID)
+	if err := client.WaitPrice(ctx, auth); err != nil {
+		return nil, fmt.Errorf("price transaction: %w", err)
+	}
+	return auth, nil
+}
+
+// waitSuccess waits until the transaction, or a replacement of it, is mined and returns its receipt,
+// with an error when it failed.
+func waitSuccess(ctx context.Context, client providerTxs, tx *types.Transaction) (*types.Receipt, error) {
+	receipt, err := client.WaitMined(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("wait until transaction is mined: %w", err)
+	}
+	if receipt.Status == types.ReceiptStatusFailed {
+		return receipt, fmt.Errorf("transaction %q failed", receipt.TxHash)
+	}
+	return receipt, nil
+}
+
+// waitConfirmations waits until the mined transaction is confirmations blocks deep, its own block included.
+// It returns ErrTxVanished when a reorg removed the transaction from the chain and from the pending pool of every
+// node, or when the transaction failed once mined again.
+func waitConfirmations(ctx context.Context, client issueClient, hash common.Hash, confirmations uint64) error {
+	for {
+		receipt, err := client.TransactionReceipt(ctx, hash)
+		switch {
+		case errors.Is(err, ethereum.NotFound):
+			// a reorg put the transaction back in the pending pool, or dropped it
+			_, _, err := client.TransactionByHash(ctx, hash)
+			if errors.Is(err, ethereum.NotFound) {
+				return fmt.Errorf("%w: %s", ErrTxVanished, hash)
+			}
+			if err != nil {
+				return fmt.Errorf("retrieve transaction %s: %w", hash, err)
+			}
+		case err != nil:
+			return fmt.Errorf("retrieve receipt of %s: %w", hash, err)
+		case receipt.Status == types.ReceiptStatusFailed:
+			return fmt.Errorf("%w: %s failed once mined again", ErrTxVanished, hash)
+		default:
+			head, err := client.BlockNumber(ctx)
+			if err != nil {
+				return fmt.Errorf("retrieve block number: %w", err)
+			}
+			if head+1 >= receipt.BlockNumber.Uint64()+confirmations {
+				return nil
+			}
+		}
+
+		select {
+		case <-time.After(confirmationPollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ensureLeafAdded checks that the registry records the certificate of leafHash as issued by the provider.
+func ensureLeafAdded(
+	ctx context.Context,
+	registry *contracts.ZkCertificateRegistry,
+	leafHash zkcertificate.Hash,
+	providerAddress common.Address,
+) error {
+	guardian, err := registry.ZkCertificateToGuardian(&bind.CallOpts{Context: ctx}, leafHash.Bytes32())
+	if err != nil {
+		return fmt.Errorf("retrieve certificate guardian: %w", err)
+	}
+	if guardian != providerAddress {
+		return fmt.Errorf("%w: leaf %s is not in the registry", ErrTxVanished, leafHash)
+	}
+	return nil
+}
+
+func encodeMerkleProof(proof merkle.Proof) [][32]byte {
+	res := make([][32]byte, len(proof.Path))
+	for i, node := range proof.Path {
+		res[i] = node.Value.Bytes32()
+	}
+	return res
+}
@@ -0,0 +1,106 @@
+package zkcert
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// fakeChain mines a transaction at block, or keeps it pending. Every call advances the head by one block.
+type fakeChain struct {
+	issueClient
+
+	mu      sync.Mutex
+	head    uint64
+	block   uint64 // 0 when the transaction is not mined
+	pending bool
+	status  uint64
+}
+
+func (c *fakeChain) TransactionReceipt(context.Context, common.Hash) (*types.Receipt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.block == 0 {
+		return nil, ethereum.NotFound
+	}
+	return &types.Receipt{Status: c.status, BlockNumber: new(big.Int).SetUint64(c.block)}, nil
+}
+
+func (c *fakeChain) TransactionByHash(context.Context, common.Hash) (*types.Transaction, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.pending {
+		return nil, false, ethereum.NotFound
+	}
+	return &types.Transaction{}, true, nil
+}
+
+func (c *fakeChain) BlockNumber(context.Context) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.head++
+	return c.head, nil
+}
+
+func TestWaitConfirmations(t *testing.T) {
+	originalInterval := confirmationPollInterval
+	confirmationPollInterval = time.Millisecond
+	defer func() {
+		confirmationPollInterval = originalInterval
+	}()
+
+	ctx := context.Background()
+	hash := common.HexToHash("0x01")
+
+	t.Run("confirmed", func(t *testing.T) {
+		chain := &fakeChain{head: 100, block: 100, status: types.ReceiptStatusSuccessful}
+		if err := waitConfirmations(ctx, chain, hash, 5); err != nil {
+			t.Fatalf("wait: %v", err)
+		}
+		if chain.head != 104 {
+			t.Errorf("expected to return at block 104, the fifth one, got %d", chain.head)
+		}
+	})
+
+	t.Run("vanished", func(t *testing.T) {
+		chain := &fakeChain{head: 100}
+		if err := waitConfirmations(ctx, chain, hash, 5); !errors.Is(err, ErrTxVanished) {
+			t.Errorf("expected %v, got %v", ErrTxVanished, err)
+		}
+	})
+
+	t.Run("failed once mined again", func(t *testing.T) {
+		chain := &fakeChain{head: 100, block: 100, status: types.ReceiptStatusFailed}
+		if err := waitConfirmations(ctx, chain, hash, 5); !errors.Is(err, ErrTxVanished) {
+			t.Errorf("expected %v, got %v", ErrTxVanished, err)
+		}
+	})
+
+	t.Run("back to pending", func(t *testing.T) {
+		chain := &fakeChain{head: 100, pending: true, status: types.ReceiptStatusSuccessful}
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			chain.mu.Lock()
+			defer chain.mu.Unlock()
+			chain.block, chain.head, chain.pending = 101, 101, false
+		}()
+
+		if err := waitConfirmations(ctx, chain, hash, 2); err != nil {
+			t.Fatalf("wait: %v", err)
+		}
+	})
+
+	if !errors.Is(ErrTxVanished, errRequiresRetry) {
+		t.Errorf("expected the issuance to be retried once its transaction vanished")
+	}
+}
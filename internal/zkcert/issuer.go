@@ -0,0 +1,466 @@
+package zkcert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/galactica-corp/guardians-sdk/pkg/contracts"
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/swissborg/galactica-kyc-guardian/config"
+	"github.com/swissborg/galactica-kyc-guardian/internal/nodepool"
+	"github.com/swissborg/galactica-kyc-guardian/internal/proofservice"
+	"github.com/swissborg/galactica-kyc-guardian/internal/registrytree"
+	"github.com/swissborg/galactica-kyc-guardian/internal/signer"
+	"github.com/swissborg/galactica-kyc-guardian/internal/spend"
+	"github.com/swissborg/galactica-kyc-guardian/internal/taskqueue"
+	"github.com/swissborg/galactica-kyc-guardian/internal/txmanager"
+)
+
+// Issuer holds the connections, the keys and the issuance lanes shared by the
+// certificate services of every standard. Every provider key has its own lane, the transactions
+// of all the standards going through it, so that they are sent one at a time by each provider.
+type Issuer struct {
+	EthClient         *nodepool.Pool
+	merkleProofClient *proofservice.Client
+	// localTrees mirrors the registry trees in the store, it is nil without a local fallback
+	localTrees      *registrytree.Trees
+	signer          signer.Signer
+	registryAddress common.Address
+	// providers are the guardian signer followed by the pool, the provider of every lane
+	providers []provider
+	lanes     *taskqueue.Lanes
+	// progress tracks the tasks of the lanes, to tell where the issuances stand
+	progress *progress
+	// confirmations is the depth a certificate must reach on-chain before it is issued
+	confirmations uint64
+	// ledger records the gas spent on every certificate, it is nil when the spend is not recorded
+	ledger *spend.Ledger
+
+	// stopBackground stops the node probes and the registry tree indexing
+	stopBackground context.CancelFunc
+	background     sync.WaitGroup
+
+	// tasksCtx is cancelled when the lanes are not drained in time on Close, stopping the tasks
+	tasksCtx    context.Context
+	cancelTasks context.CancelFunc
+	// closing is set by Close, the tasks queued after it fail with ErrShuttingDown
+	closing atomic.Bool
+
+	chainIDMu sync.Mutex
+	chainID   *big.Int
+
+	registriesMu sync.Mutex
+	registries   map[zkcertificate.Standard]common.Address
+}
+
+// Info describes the guardian identity and where it issues certificates
+type Info struct {
+	ProviderAddress common.Address
+	// PoolAddresses are the additional provider addresses the transactions are sent from
+	PoolAddresses    []common.Address
+	SigningPublicKey *babyjub.PublicKey
+	// RetiredSigningPublicKeys are the keys certificates were signed with before the last rotations
+	RetiredSigningPublicKeys []*babyjub.PublicKey
+	// RegistryAddress is the registry of the KYC certificates
+	RegistryAddress common.Address
+	ChainID         *big.Int
+	Standards       []zkcertificate.Standard
+	Registries      map[zkcertificate.Standard]common.Address
+}
+
+const (
+	// nodeProbeInterval is how often the health of the nodes is checked
+	nodeProbeInterval = 15 * time.Second
+	// treeIndexInterval is how often the events of the registries are indexed in the local trees
+	treeIndexInterval = 15 * time.Second
+	// drainTimeout is how long Close lets the queued tasks run before stopping them
+	drainTimeout = 20 * time.Second
+	// stopTimeout is how long Close waits for the stopped tasks to return
+	stopTimeout = 5 * time.Second
+)
+
+// ErrShuttingDown is passed to the callback of a task queued while the issuer is closing
+var ErrShuttingDown = errors.New("issuer shutting down")
+
+// provider sends the transactions of a lane.
+type provider struct {
+	signer signer.Signer
+	// txs sends the transactions of the signer through EthClient, replacing the ones pending for too long
+	txs *txmanager.Manager
+}
+
+// NewIssuer connects to the nodes of rpcURLs and to the merkle proof services of merkleProofURLs, both in
+// decreasing priority. When mirror is not nil, the registry trees are mirrored in it from the events of the
+// nodes, and queried when every merkle proof service is down or when there is none.
+// The transactions and certificates are signed by s, the transactions of the additional lanes by the signers
+// of pool. registryAddress is the registry of the KYC certificates.
+// The confirmation depth of the certificates, the fees and the replacement of the pending transactions are set by
+// issuance. The gas spent on every certificate is recorded in ledger when it is not nil.
+func NewIssuer(
+	s signer.Signer,
+	registryAddress common.Address,
+	rpcURLs []string,
+	merkleProofURLs []string,
+	merkleProofTLS bool,
+	mirror *badger.DB,
+	issuance config.Issuance,
+	ledger *spend.Ledger,
+	pool ...signer.Signer,
+) (*Issuer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	nodes, err := nodepool.Dial(ctx, rpcURLs)
+	if err != nil {
+		return nil, fmt.Errorf("connect to ethereum node: %v", err)
+	}
+
+	var localTrees *registrytree.Trees
+	if mirror != nil {
+		localTrees = registrytree.New(mirror, nodes)
+	}
+
+	merkleProofClient, err := proofservice.Dial(merkleProofURLs, merkleProofTLS, localTrees)
+	if err != nil {
+		nodes.Close()
+		return nil, fmt.Errorf("connect to merkle proof service: %v", err)
+	}
+
+	_ = nodes.Probe(ctx)
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	tasksCtx, cancelTasks := context.WithCancel(context.Background())
+
+	providers := make([]provider, 0, 1+len(pool))
+	for _, ps := range append([]signer.Signer{s}, pool...) {
+		providers = append(providers, provider{
+			signer: ps,
+			txs:    txmanager.New(nodes, ps, feePolicy(issuance), issuance.ReplaceAfter, issuance.StuckAfter),
+		})
+	}
+
+	i := &Issuer{
+		EthClient:         nodes,
+		merkleProofClient: merkleProofClient,
+		localTrees:        localTrees,
+		signer:            s,
+		registryAddress:   registryAddress,
+		providers:         providers,
+		lanes:             taskqueue.NewLanes(len(providers)),
+		progress:          newProgress(),
+		confirmations:     uint64(issuance.Confirmations),
+		ledger:            ledger,
+		stopBackground:    stopBackground,
+		tasksCtx:          tasksCtx,
+		cancelTasks:       cancelTasks,
+		registries:        make(map[zkcertificate.Standard]common.Address),
+	}
+
+	i.background.Add(1)
+	go func() {
+		defer i.background.Done()
+		nodes.Watch(backgroundCtx, nodeProbeInterval)
+	}()
+
+	if localTrees != nil {
+		i.background.Add(1)
+		go func() {
+			defer i.background.Done()
+			localTrees.Watch(backgroundCtx, treeIndexInterval)
+		}()
+	}
+
+	return i, nil
+}
+
+// feePolicy converts the fees of the issuance config from gwei to wei.
+func feePolicy(issuance config.Issuance) txmanager.FeePolicy {
+	return txmanager.FeePolicy{
+		TipCap:            gwei(issuance.TipCap),
+		BaseFeeMultiplier: int64(issuance.BaseFeeMultiplier),
+		MaxFeeCap:         gwei(issuance.MaxFeeCap),
+	}
+}
+
+// gwei returns the amount in wei, nil when it is zero.
+func gwei(amount float64) *big.Int {
+	if amount == 0 {
+		return nil
+	}
+	return big.NewInt(int64(math.Round(amount * params.GWei)))
+}
+
+// recordSpend records the gas spent on the transactions of an attempt of the operation on the certificate of leafHash,
+// completed or not. A failed attempt without mined transactions spent nothing.
+func (i *Issuer) recordSpend(
+	standard zkcertificate.Standard,
+	operation spend.Operation,
+	leafHash zkcertificate.Hash,
+	receipts []*types.Receipt,
+	completed bool,
+) {
+	if i.ledger == nil || !completed && len(receipts) == 0 {
+		return
+	}
+
+	record := spend.NewRecord(standard, operation, leafHash, receipts, completed)
+	if err := i.ledger.Add(record); err != nil {
+		log.WithError(err).WithField("leafHash", leafHash).Error("record spend")
+		return
+	}
+
+	log.WithField("leafHash", leafHash).
+		WithField("operation", operation).
+		WithField("gasUsed", record.GasUsed).
+		WithField("cost", record.Cost).
+		WithField("completed", completed).
+		Info("spend recorded")
+}
+
+// register records the registry the certificates of the standard are issued to.
+func (i *Issuer) register(standard zkcertificate.Standard, registryAddress common.Address) {
+	i.registriesMu.Lock()
+	defer i.registriesMu.Unlock()
+
+	if i.registries == nil {
+		i.registries = make(map[zkcertificate.Standard]common.Address)
+	}
+	i.registries[standard] = registryAddress
+
+	if i.localTrees != nil {
+		i.localTrees.Track(registryAddress)
+	}
+}
+
+// Info returns the public keys of the guardian, the registries it issues to and the supported standards.
+func (i *Issuer) Info(ctx context.Context) (Info, error) {
+	chainID, err := i.ChainID(ctx)
+	if err != nil {
+		return Info{}, err
+	}
+
+	i.registriesMu.Lock()
+	registries := make(map[zkcertificate.Standard]common.Address, len(i.registries))
+	standards := make([]zkcertificate.Standard, 0, len(i.registries))
+	for standard, address := range i.registries {
+		registries[standard] = address
+		standards = append(standards, standard)
+	}
+	i.registriesMu.Unlock()
+
+	slices.Sort(standards)
+
+	var pool []common.Address
+	for _, p := range i.providers[1:] {
+		pool = append(pool, p.signer.Address())
+	}
+
+	return Info{
+		ProviderAddress:          i.signer.Address(),
+		PoolAddresses:            pool,
+		SigningPublicKey:         i.signer.PublicKey(),
+		RetiredSigningPublicKeys: i.signer.RetiredPublicKeys(),
+		RegistryAddress:          i.registryAddress,
+		ChainID:                  chainID,
+		Standards:                standards,
+		Registries:               registries,
+	}, nil
+}
+
+// ChainID returns the chain ID of the node, it is fetched once and cached.
+func (i *Issuer) ChainID(ctx context.Context) (*big.Int, error) {
+	i.chainIDMu.Lock()
+	defer i.chainIDMu.Unlock()
+
+	if i.chainID != nil {
+		return i.chainID, nil
+	}
+
+	chainID, err := i.EthClient.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("retrieve chain-id: %w", err)
+	}
+
+	i.chainID = chainID
+	return chainID, nil
+}
+
+// Backlog is the work queued in the lanes of the issuer.
+type Backlog struct {
+	// Pending is the number of tasks queued or running in every lane
+	Pending int
+	// EstimatedWait is how long a new issuance should wait before it runs, zero until a task is done
+	EstimatedWait time.Duration
+	// MeanDuration is the mean duration of the last tasks, zero until a task is done
+	MeanDuration time.Duration
+	// Lanes is the number of lanes, one per provider key
+	Lanes int
+}
+
+// Backlog returns the work queued in the lanes, a new issuance going to the least busy one.
+func (i *Issuer) Backlog() Backlog {
+	backlog := Backlog{Lanes: i.lanes.Len()}
+	for _, pending := range i.lanes.Pending() {
+		backlog.Pending += pending
+	}
+	backlog.EstimatedWait, backlog.MeanDuration = i.progress.wait(backlog.Lanes)
+	return backlog
+}
+
+// taskContext returns ctx, also cancelled when the tasks are stopped on Close.
+func (i *Issuer) taskContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(i.tasksCtx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// drain refuses the new tasks and waits for the queued ones, for at most timeout. The remaining tasks are then
+// cancelled, and given stopTimeout to return.
+func (i *Issuer) drain(timeout time.Duration) {
+	i.closing.Store(true)
+
+	drained := make(chan struct{})
+	go func() {
+		i.lanes.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return
+	case <-time.After(timeout):
+	}
+
+	log.WithField("pending", i.lanes.Pending()).Warn("issuance lanes not drained in time, cancelling the tasks")
+	i.cancelTasks()
+
+	select {
+	case <-drained:
+	case <-time.After(stopTimeout):
+		log.WithField("pending", i.lanes.Pending()).Error("issuance tasks still running, abandoned")
+	}
+}
+
+// Close refuses the new tasks, lets the queued ones run for drainTimeout and cancels the remaining ones
+// before closing the connections.
+func (i *Issuer) Close() {
+	i.drain(drainTimeout)
+	i.stopBackground()
+	i.background.Wait()
+	i.merkleProofClient.Close()
+	i.EthClient.Close()
+}
+
+// CheckResult is the outcome of one of the checks of Check.
+type CheckResult struct {
+	Name string
+	Err  error
+}
+
+// Check verifies that the nodes answer, and for every registry that it is deployed, that the provider is
+// whitelisted as a guardian and that every merkle proof service knows its tree. Every check is run.
+func (i *Issuer) Check(ctx context.Context) []CheckResult {
+	var results []CheckResult
+	for _, health := range i.EthClient.Probe(ctx) {
+		results = append(results, CheckResult{Name: "node " + health.Node, Err: health.Err})
+	}
+
+	_, err := i.ChainID(ctx)
+	if err != nil {
+		return append(results, CheckResult{Name: "chain id", Err: err})
+	}
+
+	for lane, p := range i.providers {
+		results = append(results, CheckResult{Name: i.providerName(lane) + " transactions", Err: stuckError(p.txs.Stuck())})
+	}
+
+	info, err := i.Info(ctx)
+	if err != nil {
+		return append(results, CheckResult{Name: "info", Err: err})
+	}
+
+	for _, standard := range info.Standards {
+		registryAddress := info.Registries[standard]
+
+		code, err := i.EthClient.CodeAt(ctx, registryAddress, nil)
+		if err == nil && len(code) == 0 {
+			err = fmt.Errorf("no contract at %s", registryAddress)
+		}
+		results = append(results, CheckResult{Name: standard.String() + " registry", Err: err})
+		if err != nil {
+			continue
+		}
+
+		registry, err := contracts.NewZkCertificateRegistry(registryAddress, i.EthClient)
+		for lane, p := range i.providers {
+			whitelistErr := err
+			if whitelistErr == nil {
+				whitelistErr = ensureProviderIsGuardian(ctx, i.EthClient, registry, p.signer.Address())
+			}
+			name := standard.String() + " guardian whitelist"
+			if lane > 0 {
+				name = standard.String() + " " + i.providerName(lane) + " guardian whitelist"
+			}
+			results = append(results, CheckResult{Name: name, Err: whitelistErr})
+		}
+
+		for _, health := range i.merkleProofClient.Probe(ctx, registryAddress.Hex()) {
+			results = append(results, CheckResult{Name: standard.String() + " merkle proof " + health.Service, Err: health.Err})
+		}
+	}
+
+	return results
+}
+
+// CertificateGuardian returns the guardian the registry records for the certificate of leafHash,
+// the zero address when the registry has no record of it.
+func (i *Issuer) CertificateGuardian(
+	ctx context.Context,
+	registryAddress common.Address,
+	leafHash zkcertificate.Hash,
+) (common.Address, error) {
+	registry, err := contracts.NewZkCertificateRegistry(registryAddress, i.EthClient)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("load record registry: %w", err)
+	}
+
+	guardian, err := registry.ZkCertificateToGuardian(&bind.CallOpts{Context: ctx}, leafHash.Bytes32())
+	if err != nil {
+		return common.Address{}, fmt.Errorf("retrieve certificate guardian: %w", err)
+	}
+	return guardian, nil
+}
+
+// providerName names the provider of the lane in the checks, the provider keys of the pool by their address.
+func (i *Issuer) providerName(lane int) string {
+	if lane == 0 {
+		return "provider"
+	}
+	return "provider " + i.providers[lane].signer.Address().Hex()
+}
+
+// stuckError describes the stuck transactions, it is nil when there is none.
+func stuckError(stuck []txmanager.Stuck) error {
+	if len(stuck) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d transactions stuck, the first one %s with nonce %d since %s",
+		len(stuck), stuck[0].Hash, stuck[0].Nonce, stuck[0].Since.Format(time.RFC3339))
+}
@@ -0,0 +1,68 @@
+package zkcert
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/taskqueue"
+)
+
+func TestDrain(t *testing.T) {
+	tasksCtx, cancelTasks := context.WithCancel(context.Background())
+	i := &Issuer{lanes: taskqueue.NewLanes(1), tasksCtx: tasksCtx, cancelTasks: cancelTasks}
+
+	// a task waiting on the fees forever, until its context is cancelled
+	result := make(chan error, 1)
+	i.lanes.Add(func(int) taskqueue.AnyTask {
+		return taskqueue.NewTask(
+			func() (struct{}, error) {
+				ctx, cancel := i.taskContext(context.Background())
+				defer cancel()
+				<-ctx.Done()
+				return struct{}{}, ctx.Err()
+			},
+			func(_ struct{}, err error) { result <- err },
+			errRequiresRetry,
+		)
+	})
+
+	start := time.Now()
+	i.drain(50 * time.Millisecond)
+	if elapsed := time.Since(start); elapsed > stopTimeout {
+		t.Errorf("expected the drain to give up after its timeout, took %s", elapsed)
+	}
+	if err := <-result; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the task to be cancelled, got %v", err)
+	}
+
+	if !i.closing.Load() {
+		t.Error("expected the issuer to refuse the new tasks")
+	}
+}
+
+func TestExpiredIssuance(t *testing.T) {
+	originalExpiration := taskqueue.TaskExpirationTime
+	taskqueue.TaskExpirationTime = -time.Second
+	defer func() {
+		taskqueue.TaskExpirationTime = originalExpiration
+	}()
+
+	i := &Issuer{lanes: taskqueue.NewLanes(1), providers: []provider{{}}, progress: newProgress()}
+	s := &Service[zkcertificate.KYCContent]{Issuer: i}
+
+	result := make(chan error, 1)
+	s.AddZKCertToQueue(context.Background(), zkcertificate.Certificate[zkcertificate.KYCContent]{},
+		func(_ zkcertificate.IssuedCertificate[zkcertificate.KYCContent], err error) { result <- err })
+	i.lanes.Wait()
+
+	if err := <-result; !errors.Is(err, taskqueue.ErrTaskExpired) {
+		t.Errorf("expected the caller to learn that the issuance expired, got %v", err)
+	}
+	if len(i.progress.tasks) != 0 {
+		t.Errorf("expected the expired issuance to be forgotten")
+	}
+}
@@ -0,0 +1,194 @@
+package zkcert
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/keys"
+)
+
+// The functions below create and check certificates without any connection to the chain,
+// to reproduce and debug the certificates of support cases.
+
+var (
+	ErrContentHashMismatch = errors.New("content hash does not match the content")
+	ErrInvalidSignature    = errors.New("invalid certificate signature")
+	ErrLeafHashMismatch    = errors.New("leaf hash does not match the certificate")
+	ErrUnknownSigningKey   = errors.New("certificate not signed by a guardian key")
+)
+
+// CreateOffline creates the certificate of the inputs like Service.CreateZKCert, signed with signingKey.
+// The salt, in [1, MaxInt64], and the expiration date are given so that the certificate is reproducible:
+// the same inputs always give the same certificate.
+func CreateOffline[T zkcertificate.Content](
+	holderCommitment zkcertificate.HolderCommitment,
+	inputs Inputs[T],
+	signingKey babyjub.PrivateKey,
+	salt int64,
+	expirationDate time.Time,
+) (*zkcertificate.Certificate[T], error) {
+	if salt < 1 {
+		return nil, fmt.Errorf("salt must be positive, got %d", salt)
+	}
+
+	content, err := encodeInputs(inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	contentHash, err := content.Hash()
+	if err != nil {
+		return nil, fmt.Errorf("hash certificate content: %w", err)
+	}
+
+	signature, err := zkcertificate.SignCertificate(signingKey, contentHash, holderCommitment.CommitmentHash)
+	if err != nil {
+		return nil, fmt.Errorf("sign certificate: %w", err)
+	}
+
+	return zkcertificate.New(
+		holderCommitment.CommitmentHash,
+		content,
+		signingKey.Public(),
+		signature,
+		salt,
+		expirationDate,
+	)
+}
+
+// Inspection details how a certificate is encoded and signed.
+type Inspection struct {
+	Standard         zkcertificate.Standard `json:"standard"`
+	DID              string                 `json:"did"`
+	HolderCommitment zkcertificate.Hash     `json:"holderCommitment"`
+	// Fields are the finite field encoded content, hashed to ContentHash
+	Fields         zkcertificate.Content   `json:"fields"`
+	ContentHash    zkcertificate.Hash      `json:"contentHash"`
+	LeafHash       zkcertificate.Hash      `json:"leafHash"`
+	ExpirationDate zkcertificate.Timestamp `json:"expirationDate"`
+	RandomSalt     string                  `json:"randomSalt"`
+	SigningKey     InspectedKey            `json:"signingKey"`
+	Signature      InspectedSignature      `json:"signature"`
+}
+
+// InspectedKey is the EdDSA public key a certificate is signed with.
+type InspectedKey struct {
+	KeyID string `json:"keyId"`
+	Ax    string `json:"ax"`
+	Ay    string `json:"ay"`
+	// Compressed is the hex encoded compressed point
+	Compressed string `json:"compressed"`
+}
+
+// InspectedSignature is the EdDSA signature of the content and holder commitment hashes.
+type InspectedSignature struct {
+	R8x string `json:"r8x"`
+	R8y string `json:"r8y"`
+	S   string `json:"s"`
+}
+
+// Inspect returns the details of the certificate, the missing provider data are left empty.
+func Inspect[T zkcertificate.Content](cert zkcertificate.Certificate[T]) Inspection {
+	inspection := Inspection{
+		Standard:         cert.Standard,
+		DID:              cert.DID,
+		HolderCommitment: cert.HolderCommitment,
+		Fields:           cert.Content,
+		ContentHash:      cert.ContentHash,
+		LeafHash:         cert.LeafHash,
+		ExpirationDate:   cert.ExpirationDate,
+		RandomSalt:       cert.RandomSalt,
+	}
+
+	if publicKey := &cert.Provider.PublicKey; publicKey.X != nil && publicKey.Y != nil {
+		compressed := publicKey.Compress()
+		inspection.SigningKey = InspectedKey{
+			KeyID:      keys.SigningKeyID(publicKey),
+			Ax:         publicKey.X.String(),
+			Ay:         publicKey.Y.String(),
+			Compressed: hex.EncodeToString(compressed[:]),
+		}
+	}
+
+	if signature := &cert.Provider.Signature; signature.R8 != nil && signature.S != nil {
+		inspection.Signature = InspectedSignature{
+			R8x: signature.R8.X.String(),
+			R8y: signature.R8.Y.String(),
+			S:   signature.S.String(),
+		}
+	}
+
+	return inspection
+}
+
+// Verify recomputes the content and leaf hashes of the certificate and checks its signature.
+// When publicKeys are given, the certificate must also be signed by one of them.
+// Every mismatch is reported.
+func Verify[T zkcertificate.Content](cert zkcertificate.Certificate[T], publicKeys ...*babyjub.PublicKey) error {
+	publicKey := &cert.Provider.PublicKey
+	signature := &cert.Provider.Signature
+	if publicKey.X == nil || publicKey.Y == nil || signature.R8 == nil || signature.S == nil {
+		return fmt.Errorf("%w: missing provider data", ErrInvalidSignature)
+	}
+
+	salt, err := strconv.ParseInt(cert.RandomSalt, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid random salt %q: %w", cert.RandomSalt, err)
+	}
+
+	contentHash, err := cert.Content.Hash()
+	if err != nil {
+		return fmt.Errorf("hash certificate content: %w", err)
+	}
+
+	var errs []error
+	if contentHash.BigInt().Cmp(cert.ContentHash.BigInt()) != 0 {
+		errs = append(errs, fmt.Errorf("%w: the content hashes to %s", ErrContentHashMismatch, contentHash))
+	}
+
+	valid, err := zkcertificate.VerifySignature(publicKey, contentHash, cert.HolderCommitment, signature)
+	if err != nil {
+		return fmt.Errorf("verify signature: %w", err)
+	}
+	if !valid {
+		errs = append(errs, ErrInvalidSignature)
+	}
+
+	leafHash, err := zkcertificate.LeafHash(
+		contentHash,
+		publicKey,
+		signature,
+		cert.HolderCommitment,
+		salt,
+		cert.ExpirationDate.Time(),
+	)
+	if err != nil {
+		return err
+	}
+	if leafHash.BigInt().Cmp(cert.LeafHash.BigInt()) != 0 {
+		errs = append(errs, fmt.Errorf("%w: the certificate hashes to %s", ErrLeafHashMismatch, leafHash))
+	} else if did := zkcertificate.DID(cert.Standard, leafHash); did != cert.DID {
+		errs = append(errs, fmt.Errorf("%w: expected DID %s", ErrLeafHashMismatch, did))
+	}
+
+	if len(publicKeys) > 0 && !containsKey(publicKeys, publicKey) {
+		errs = append(errs, fmt.Errorf("%w: signed by key %s", ErrUnknownSigningKey, keys.SigningKeyID(publicKey)))
+	}
+
+	return errors.Join(errs...)
+}
+
+func containsKey(publicKeys []*babyjub.PublicKey, key *babyjub.PublicKey) bool {
+	for _, publicKey := range publicKeys {
+		if publicKey.X.Cmp(key.X) == 0 && publicKey.Y.Cmp(key.Y) == 0 {
+			return true
+		}
+	}
+	return false
+}
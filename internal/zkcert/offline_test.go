@@ -0,0 +1,95 @@
+package zkcert
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+)
+
+var testKYCInputs = zkcertificate.KYCInputs{
+	Surname:      "Bob",
+	Forename:     "Norman",
+	YearOfBirth:  2006,
+	MonthOfBirth: 1,
+	DayOfBirth:   2,
+	Citizenship:  "CHE",
+	Postcode:     "1006",
+	Country:      "CHE",
+}
+
+func TestCreateOfflineReproducible(t *testing.T) {
+	signingKey := babyjub.NewRandPrivKey()
+	expiration := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cert, err := CreateOffline[zkcertificate.KYCContent](testHolderCommitment(t), &testKYCInputs, signingKey, 42, expiration)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	again, err := CreateOffline[zkcertificate.KYCContent](testHolderCommitment(t), &testKYCInputs, signingKey, 42, expiration)
+	if err != nil {
+		t.Fatalf("create again: %v", err)
+	}
+	if cert.LeafHash.String() != again.LeafHash.String() {
+		t.Errorf("expected the same leaf hash, got %s and %s", cert.LeafHash, again.LeafHash)
+	}
+
+	if err := Verify(*cert, signingKey.Public()); err != nil {
+		t.Errorf("verify: %v", err)
+	}
+
+	inspection := Inspect(*cert)
+	if inspection.ContentHash.String() != cert.ContentHash.String() || inspection.Signature.S != cert.Provider.Signature.S.String() {
+		t.Errorf("unexpected inspection %+v", inspection)
+	}
+
+	if _, err := CreateOffline[zkcertificate.KYCContent](testHolderCommitment(t), &testKYCInputs, signingKey, 0, expiration); err == nil {
+		t.Errorf("expected a zero salt to be refused")
+	}
+}
+
+func TestVerifyCertificateJSON(t *testing.T) {
+	signingKey := babyjub.NewRandPrivKey()
+	cert, err := CreateOffline[zkcertificate.KYCContent](testHolderCommitment(t), &testKYCInputs, signingKey, 42, time.Now().AddDate(1, 0, 0))
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	data, err := json.Marshal(cert)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	decoded, err := zkcertificate.DeserializeCertificateJSON(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("deserialize: %v", err)
+	}
+	if err := Verify(decoded, signingKey.Public()); err != nil {
+		t.Errorf("verify decoded: %v", err)
+	}
+
+	otherKey := babyjub.NewRandPrivKey()
+	if err := Verify(decoded, otherKey.Public()); !errors.Is(err, ErrUnknownSigningKey) {
+		t.Errorf("expected %v, got %v", ErrUnknownSigningKey, err)
+	}
+
+	tampered := *cert
+	tampered.Content.YearOfBirth = 1990
+	err = Verify(tampered, signingKey.Public())
+	if !errors.Is(err, ErrContentHashMismatch) || !errors.Is(err, ErrInvalidSignature) || !errors.Is(err, ErrLeafHashMismatch) {
+		t.Errorf("expected every mismatch to be reported, got %v", err)
+	}
+
+	empty := zkcertificate.Certificate[zkcertificate.KYCContent]{}
+	if inspection := Inspect(empty); inspection.SigningKey.KeyID != "" {
+		t.Errorf("expected no signing key, got %+v", inspection.SigningKey)
+	}
+	if err := Verify(empty); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected a certificate without provider data to be invalid, got %v", err)
+	}
+}
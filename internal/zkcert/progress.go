@@ -0,0 +1,276 @@
+package zkcert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/galactica-corp/guardians-sdk/pkg/contracts"
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/taskqueue"
+)
+
+// ErrNotQueued is returned for the status of a certificate whose issuance is not queued
+var ErrNotQueued = errors.New("certificate issuance not queued")
+
+// durationWindow is the number of the last task durations the completion of the queued issuances is estimated from
+const durationWindow = 50
+
+// QueueStatus tells where the issuance of a certificate stands.
+type QueueStatus struct {
+	// Position is the number of tasks ahead of the issuance in its lane, 0 once it runs
+	Position int
+	// Running is true once the issuance left its lane
+	Running bool
+	// Registry is the state of the certificate in the queue of the registry, nil while it is not registered in it
+	Registry *RegistryQueue
+	// EstimatedCompletion is when the issuance should be done, computed from the durations of the last tasks.
+	// It is zero until a task is done
+	EstimatedCompletion time.Time
+}
+
+// RegistryQueue is the state of a certificate in the on-chain queue the registry orders the additions with.
+type RegistryQueue struct {
+	// Index is the index of the certificate in the queue
+	Index uint64
+	// Ahead is the number of certificates to add or revoke before its turn, 0 on its turn
+	Ahead uint64
+}
+
+// progress tracks the tasks queued in the lanes, to tell the position of the issuances in them and estimate
+// when they are done from the durations of the last tasks.
+type progress struct {
+	mu sync.Mutex
+	// next orders the tasks in their lanes, a retried task is ordered again at the end of its lane
+	next      uint64
+	tasks     map[uint64]*trackedTask
+	issuances map[[32]byte]uint64
+	// durations are the ones of the last successful tasks, from their start to their outcome
+	durations []time.Duration
+}
+
+type trackedTask struct {
+	lane  int
+	order uint64
+	// leafHash is the certificate of an issuance, nil for the other tasks
+	leafHash  *zkcertificate.Hash
+	createdAt time.Time
+	// startedAt is zero while the task waits in its lane
+	startedAt time.Time
+}
+
+func newProgress() *progress {
+	return &progress{
+		tasks:     make(map[uint64]*trackedTask),
+		issuances: make(map[[32]byte]uint64),
+	}
+}
+
+// queue tracks a task added at the end of the lane and returns its ID, leafHash is the certificate of an
+// issuance and nil for the other tasks.
+func (p *progress) queue(lane int, leafHash *zkcertificate.Hash) uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := p.next
+	p.next++
+	p.tasks[id] = &trackedTask{lane: lane, order: id, leafHash: leafHash, createdAt: time.Now()}
+	if leafHash != nil {
+		p.issuances[leafHash.Bytes32()] = id
+	}
+	return id
+}
+
+// start records that the task runs.
+func (p *progress) start(id uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if t, ok := p.tasks[id]; ok {
+		t.startedAt = time.Now()
+	}
+}
+
+// done records the outcome of the task. A task to retry goes back to the end of its lane, the duration of a
+// successful task is kept to estimate the completion of the next ones.
+func (p *progress) done(id uint64, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t, ok := p.tasks[id]
+	if !ok {
+		return
+	}
+
+	if errors.Is(err, errRequiresRetry) {
+		t.order = p.next
+		p.next++
+		t.startedAt = time.Time{}
+		return
+	}
+
+	if err == nil {
+		p.durations = append(p.durations, time.Since(t.startedAt))
+		if len(p.durations) > durationWindow {
+			p.durations = p.durations[1:]
+		}
+	}
+
+	p.remove(id, t)
+}
+
+// expire forgets the task its lane dropped without running it.
+func (p *progress) expire(id uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if t, ok := p.tasks[id]; ok {
+		p.remove(id, t)
+	}
+}
+
+func (p *progress) remove(id uint64, t *trackedTask) {
+	delete(p.tasks, id)
+	if t.leafHash != nil && p.issuances[t.leafHash.Bytes32()] == id {
+		delete(p.issuances, t.leafHash.Bytes32())
+	}
+}
+
+// status returns the position of the issuance of the certificate of leafHash in its lane and the estimation of
+// its completion. ok is false when the issuance is not queued, or expired in its lane.
+func (p *progress) status(leafHash zkcertificate.Hash) (status QueueStatus, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id, ok := p.issuances[leafHash.Bytes32()]
+	if !ok {
+		return QueueStatus{}, false
+	}
+	issuance := p.tasks[id]
+	if issuance.expired() {
+		return QueueStatus{}, false
+	}
+	mean := p.mean()
+
+	left := issuance.remaining(mean)
+	for _, t := range p.tasks {
+		if t == issuance || t.lane != issuance.lane || t.expired() || !ahead(t, issuance) {
+			continue
+		}
+		status.Position++
+		left += t.remaining(mean)
+	}
+
+	status.Running = !issuance.startedAt.IsZero()
+	if len(p.durations) > 0 {
+		status.EstimatedCompletion = time.Now().Add(left)
+	}
+	return status, true
+}
+
+// wait returns how long a new task should wait before it runs in the least busy of the lanes, and the mean
+// duration of the last tasks. Both are zero until a task is done.
+func (p *progress) wait(lanes int) (wait, mean time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	mean = p.mean()
+	left := make([]time.Duration, lanes)
+	for _, t := range p.tasks {
+		if t.lane < lanes && !t.expired() {
+			left[t.lane] += t.remaining(mean)
+		}
+	}
+	return slices.Min(left), mean
+}
+
+// mean returns the mean duration of the last tasks, zero until a task is done.
+func (p *progress) mean() time.Duration {
+	var mean time.Duration
+	for _, d := range p.durations {
+		mean += d / time.Duration(len(p.durations))
+	}
+	return mean
+}
+
+// remaining is the estimation of the time left to the task, at least a second once it runs.
+func (t *trackedTask) remaining(mean time.Duration) time.Duration {
+	if t.startedAt.IsZero() {
+		return mean
+	}
+	return max(mean-time.Since(t.startedAt), time.Second)
+}
+
+// expired reports whether the task expired while waiting, its lane drops it without running it.
+func (t *trackedTask) expired() bool {
+	return t.startedAt.IsZero() && time.Since(t.createdAt) > taskqueue.TaskExpirationTime
+}
+
+// ahead reports whether the task a runs before b in their lane.
+func ahead(a, b *trackedTask) bool {
+	if !a.startedAt.IsZero() {
+		return b.startedAt.IsZero()
+	}
+	return b.startedAt.IsZero() && a.order < b.order
+}
+
+// QueueStatus returns where the issuance of the certificate of leafHash stands, in its lane and in the queue of
+// the registry. It returns ErrNotQueued when the issuance is not queued.
+func (s *Service[T]) QueueStatus(ctx context.Context, leafHash zkcertificate.Hash) (QueueStatus, error) {
+	status, ok := s.progress.status(leafHash)
+	if !ok {
+		return QueueStatus{}, ErrNotQueued
+	}
+	if !status.Running {
+		// the certificate is registered in the queue of the registry once its issuance runs
+		return status, nil
+	}
+
+	registry, err := contracts.NewZkCertificateRegistry(s.registryAddress, s.EthClient)
+	if err != nil {
+		return status, fmt.Errorf("load record registry: %w", err)
+	}
+
+	status.Registry, err = registryQueue(ctx, registry, leafHash)
+	return status, err
+}
+
+// registryQueue returns the state of the certificate of leafHash in the queue of the registry,
+// nil when it is not queued.
+func registryQueue(
+	ctx context.Context,
+	registry *contracts.ZkCertificateRegistry,
+	leafHash zkcertificate.Hash,
+) (*RegistryQueue, error) {
+	opts := &bind.CallOpts{Context: ctx}
+
+	index, err := registry.ZkCertificateHashToIndexInQueue(opts, leafHash.Bytes32())
+	if err != nil {
+		return nil, fmt.Errorf("retrieve queue index: %w", err)
+	}
+
+	pointer, err := registry.CurrentQueuePointer(opts)
+	if err != nil {
+		return nil, fmt.Errorf("retrieve queue pointer: %w", err)
+	}
+	if index.Cmp(pointer) < 0 {
+		return nil, nil
+	}
+
+	// the index of a certificate that was never queued is zero, the call fails when the queue is empty
+	queued, err := registry.ZkCertificateQueue(opts, index)
+	if err != nil || queued != leafHash.Bytes32() {
+		return nil, nil
+	}
+
+	return &RegistryQueue{
+		Index: index.Uint64(),
+		Ahead: new(big.Int).Sub(index, pointer).Uint64(),
+	}, nil
+}
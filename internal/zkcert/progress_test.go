@@ -0,0 +1,81 @@
+package zkcert
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/taskqueue"
+)
+
+func TestProgress(t *testing.T) {
+	p := newProgress()
+	first := zkcertificate.HashFromBigInt(big.NewInt(1))
+	second := zkcertificate.HashFromBigInt(big.NewInt(2))
+	other := zkcertificate.HashFromBigInt(big.NewInt(3))
+
+	firstID := p.queue(0, &first)
+	p.queue(0, nil)
+	secondID := p.queue(0, &second)
+	p.queue(1, &other)
+
+	status, ok := p.status(second)
+	if !ok {
+		t.Fatal("expected the second issuance queued")
+	}
+	if status.Position != 2 || status.Running {
+		t.Errorf("expected the second issuance waiting behind 2 tasks, got %+v", status)
+	}
+	if !status.EstimatedCompletion.IsZero() {
+		t.Errorf("expected no estimation before a task is done, got %s", status.EstimatedCompletion)
+	}
+
+	// the first issuance is retried, it goes back to the end of the lane
+	p.start(firstID)
+	p.done(firstID, ErrTxVanished)
+	if status, _ := p.status(first); status.Position != 2 || status.Running {
+		t.Errorf("expected the retried issuance at the end of the lane, got %+v", status)
+	}
+	if status, _ := p.status(second); status.Position != 1 {
+		t.Errorf("expected the second issuance behind 1 task, got %+v", status)
+	}
+
+	p.start(secondID)
+	p.durations = []time.Duration{time.Hour}
+	status, _ = p.status(second)
+	if status.Position != 0 || !status.Running {
+		t.Errorf("expected the second issuance running, got %+v", status)
+	}
+	if estimate := time.Until(status.EstimatedCompletion); estimate < 59*time.Minute || estimate > time.Hour {
+		t.Errorf("expected the completion in an hour, got %s", estimate)
+	}
+	if status, _ := p.status(first); status.Position != 2 {
+		t.Errorf("expected the retried issuance behind the running one, got %+v", status)
+	}
+
+	p.done(secondID, nil)
+	if _, ok := p.status(second); ok {
+		t.Errorf("expected the issuance done to be forgotten")
+	}
+	if len(p.durations) != 2 {
+		t.Errorf("expected the duration of the issuance recorded, got %v", p.durations)
+	}
+}
+
+func TestProgressExpired(t *testing.T) {
+	p := newProgress()
+	leafHash := zkcertificate.HashFromBigInt(big.NewInt(1))
+
+	id := p.queue(0, &leafHash)
+	p.tasks[id].createdAt = time.Now().Add(-2 * taskqueue.TaskExpirationTime)
+	if _, ok := p.status(leafHash); ok {
+		t.Errorf("expected no status of an expired issuance")
+	}
+
+	p.expire(id)
+	if len(p.tasks) != 0 || len(p.issuances) != 0 {
+		t.Errorf("expected the issuance dropped by its lane to be forgotten, got %d tasks", len(p.tasks))
+	}
+}
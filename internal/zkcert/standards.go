@@ -0,0 +1,114 @@
+package zkcert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+)
+
+// ErrInvalidInputs is returned when the inputs of a certificate cannot be decoded or are not valid for its standard
+var ErrInvalidInputs = errors.New("invalid certificate inputs")
+
+// ErrUnsupportedStandard is returned for standards without JSON inputs support
+var ErrUnsupportedStandard = errors.New("unsupported certificate standard")
+
+// JSONService creates and issues certificates of a standard from inputs given as JSON,
+// so that callers do not depend on the content type of the standard.
+type JSONService interface {
+	Standard() zkcertificate.Standard
+	// GenerateZKCert decodes and validates the inputs, creates the certificate and queues its issuance.
+	// It returns the content hash of the certificate, callback receives it encrypted for the holder once issued.
+	GenerateZKCert(
+		ctx context.Context,
+		holderCommitment zkcertificate.HolderCommitment,
+		inputs json.RawMessage,
+		callback func(zkcertificate.EncryptedCertificate, error),
+	) (zkcertificate.Hash, error)
+}
+
+// NewJSONService returns the JSON service of the standard issuing to registryAddress through issuer.
+// KYC is not supported, its certificates are created from a profile checked against the issuance policy.
+func NewJSONService(
+	issuer *Issuer,
+	standard zkcertificate.Standard,
+	registryAddress common.Address,
+) (JSONService, error) {
+	switch standard {
+	case zkcertificate.StandardSimpleJSON:
+		return newJSONService[zkcertificate.SimpleJSONContent, zkcertificate.SimpleJSON](issuer, registryAddress), nil
+	case zkcertificate.StandardTwitter:
+		return newJSONService[zkcertificate.TwitterContent, zkcertificate.TwitterInputs](issuer, registryAddress), nil
+	case zkcertificate.StandardREY:
+		return newJSONService[zkcertificate.REYContent, zkcertificate.REYInputs](issuer, registryAddress), nil
+	case zkcertificate.StandardDEX:
+		return newJSONService[zkcertificate.DEXContent, zkcertificate.DEXInputs](issuer, registryAddress), nil
+	case zkcertificate.StandardCEX:
+		return newJSONService[zkcertificate.CEXContent, zkcertificate.CEXInputs](issuer, registryAddress), nil
+	case zkcertificate.StandardTelegram:
+		return newJSONService[zkcertificate.TelegramContent, zkcertificate.TelegramInputs](issuer, registryAddress), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedStandard, standard)
+	}
+}
+
+// inputsPtr constrains the pointer to the inputs type I of the content T,
+// most of the standards validate and decode their inputs on pointer receivers.
+type inputsPtr[T zkcertificate.Content, I any] interface {
+	*I
+	Inputs[T]
+}
+
+type jsonService[T zkcertificate.Content, I any, PI inputsPtr[T, I]] struct {
+	*Service[T]
+}
+
+func newJSONService[T zkcertificate.Content, I any, PI inputsPtr[T, I]](
+	issuer *Issuer,
+	registryAddress common.Address,
+) *jsonService[T, I, PI] {
+	return &jsonService[T, I, PI]{Service: NewService[T](issuer, registryAddress)}
+}
+
+func (s *jsonService[T, I, PI]) GenerateZKCert(
+	ctx context.Context,
+	holderCommitment zkcertificate.HolderCommitment,
+	inputs json.RawMessage,
+	callback func(zkcertificate.EncryptedCertificate, error),
+) (zkcertificate.Hash, error) {
+	cert, err := s.createZKCert(ctx, holderCommitment, inputs)
+	if err != nil {
+		return zkcertificate.Hash{}, err
+	}
+
+	s.AddZKCertToQueue(ctx, *cert, func(issuedCert zkcertificate.IssuedCertificate[T], err error) {
+		if err != nil {
+			callback(zkcertificate.EncryptedCertificate{}, err)
+			return
+		}
+
+		callback(s.EncryptZKCert(holderCommitment, issuedCert))
+	})
+
+	return cert.ContentHash, nil
+}
+
+func (s *jsonService[T, I, PI]) createZKCert(
+	ctx context.Context,
+	holderCommitment zkcertificate.HolderCommitment,
+	raw json.RawMessage,
+) (*zkcertificate.Certificate[T], error) {
+	inputs := PI(new(I))
+	if err := json.Unmarshal(raw, inputs); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInputs, err)
+	}
+
+	if err := inputs.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInputs, err)
+	}
+
+	return s.CreateZKCert(ctx, holderCommitment, inputs)
+}
@@ -0,0 +1,110 @@
+package zkcert
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/galactica-corp/guardians-sdk/pkg/zkcertificate"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+
+	"github.com/swissborg/galactica-kyc-guardian/internal/signer"
+)
+
+func newTestSigner(t *testing.T) signer.Signer {
+	t.Helper()
+
+	providerKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("generate provider key: %v", err)
+	}
+
+	return signer.NewLocal(providerKey, babyjub.NewRandPrivKey())
+}
+
+func testHolderCommitment(t *testing.T) zkcertificate.HolderCommitment {
+	t.Helper()
+
+	commitment, _ := new(big.Int).SetString("4586425042444163335895417167611444541749813513569901646582116352074512113476", 10)
+	encryptionKey, err := base64.StdEncoding.DecodeString("OEotdsfEuoiqM7ob2KJEQemhWodn87hZNFv890q4xGw=")
+	if err != nil {
+		t.Fatalf("decode encryption key: %v", err)
+	}
+
+	return zkcertificate.HolderCommitment{
+		CommitmentHash: zkcertificate.HashFromBigInt(commitment),
+		EncryptionKey:  encryptionKey,
+	}
+}
+
+func TestNewJSONService(t *testing.T) {
+	issuer := &Issuer{signer: newTestSigner(t)}
+	registry := common.HexToAddress("0x68272A56A0e9b095E5606fDD8b6c297702C0dfe5")
+
+	service, err := NewJSONService(issuer, zkcertificate.StandardCEX, registry)
+	if err != nil {
+		t.Fatalf("new cex service: %v", err)
+	}
+	if service.Standard() != zkcertificate.StandardCEX {
+		t.Errorf("expected standard %s, got %s", zkcertificate.StandardCEX, service.Standard())
+	}
+	if issuer.registries[zkcertificate.StandardCEX] != registry {
+		t.Errorf("expected the cex registry to be registered, got %v", issuer.registries)
+	}
+
+	if _, err := NewJSONService(issuer, zkcertificate.StandardKYC, registry); !errors.Is(err, ErrUnsupportedStandard) {
+		t.Errorf("kyc: expected %v, got %v", ErrUnsupportedStandard, err)
+	}
+}
+
+func TestJSONServiceCreateZKCert(t *testing.T) {
+	issuer := &Issuer{signer: newTestSigner(t)}
+	service := newJSONService[zkcertificate.CEXContent, zkcertificate.CEXInputs](issuer, common.Address{})
+
+	tests := []struct {
+		name    string
+		inputs  string
+		wantErr error
+	}{
+		{
+			name:   "valid",
+			inputs: `{"totalSwapVolume":"1000.5","swapVolumeYear":"200","swapVolumeHalfYear":"50"}`,
+		},
+		{
+			name:    "malformed",
+			inputs:  `{"totalSwapVolume":`,
+			wantErr: ErrInvalidInputs,
+		},
+		{
+			name:    "missing required field",
+			inputs:  `{"swapVolumeYear":"200"}`,
+			wantErr: ErrInvalidInputs,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, err := service.createZKCert(context.Background(), testHolderCommitment(t), json.RawMessage(tt.inputs))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("create certificate: %v", err)
+			}
+			if cert.Standard != zkcertificate.StandardCEX {
+				t.Errorf("expected standard %s, got %s", zkcertificate.StandardCEX, cert.Standard)
+			}
+			if cert.Content.TotalSwapVolume.String() != "100050" {
+				t.Errorf("expected the volume in cents, got %s", cert.Content.TotalSwapVolume)
+			}
+		})
+	}
+}